@@ -0,0 +1,475 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/cloud"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultWorkDir    = "k8ssandra/test/smoke"
+	defaultTestPrefix = "TestK8c"
+)
+
+type options struct {
+	configPath  string
+	simulate    bool
+	provisionId string
+	adopt       bool
+	workDir     string
+	timeout     time.Duration
+	record      string
+	replay      string
+}
+
+func parseOptions(name string, args []string) (options, error) {
+	return parseOptionsWith(name, args, nil)
+}
+
+// parseOptionsWith parses the common flags along with the flags registered by the command.
+func parseOptionsWith(name string, args []string, register func(flags *flag.FlagSet)) (options, error) {
+	var opts options
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	if register != nil {
+		register(flags)
+	}
+	flags.StringVar(&opts.configPath, "config", os.Getenv(util.DefaultReadinessConfigKey),
+		"readiness configuration file (YAML or JSON), defaults to $"+util.DefaultReadinessConfigKey)
+	flags.BoolVar(&opts.simulate, "simulate", false, "log the activities without applying them")
+	flags.StringVar(&opts.provisionId, "provision-id", "", "identifier of an existing provisioning run")
+	flags.BoolVar(&opts.adopt, "adopt", false, "record the unmet prerequisites of the phases as completed for the "+
+		"provision identifier, e.g. of a run provisioned before the run ledger existed")
+	flags.StringVar(&opts.workDir, "workdir", "",
+		"directory the relative Terraform and config paths are resolved from, defaults to "+defaultWorkDir)
+	flags.DurationVar(&opts.timeout, "timeout", 0, "overall timeout, zero for none")
+	flags.StringVar(&opts.record, "record", "", "cassette file recording the external commands")
+	flags.StringVar(&opts.replay, "replay", "", "cassette file replaying the external commands")
+
+	if err := flags.Parse(args); err != nil {
+		return opts, err
+	}
+	if opts.record != "" && opts.replay != "" {
+		return opts, errors.New("only one of --record or --replay may be used")
+	}
+	if opts.configPath == "" {
+		return opts, errors.New("a readiness configuration file is required, use --config or $" +
+			util.DefaultReadinessConfigKey)
+	}
+
+	absolutePath, err := filepath.Abs(opts.configPath)
+	if err != nil {
+		return opts, err
+	}
+	opts.configPath = absolutePath
+	return opts, nil
+}
+
+// loadConfig parses the configuration file, applying the command line overrides.
+func loadConfig(opts options) (model.ProvisionMeta, model.ReadinessConfig, error) {
+	meta, config, err := util.ParseReadinessConfig(opts.configPath)
+	if err != nil {
+		return meta, config, err
+	}
+	if opts.provisionId != "" {
+		meta.ProvisionId = opts.provisionId
+		meta.ArtifactsRootDir = util.DefaultArtifactsRootDir(opts.provisionId)
+	}
+	meta.Enable.Simulate = meta.Enable.Simulate || opts.simulate
+	meta.Enable.Adopt = meta.Enable.Adopt || opts.adopt
+	// The identifier is known before the run starts, so that a timed out run is recorded in its ledger.
+	if meta.ProvisionId == "" && !meta.Enable.Adopt {
+		meta.ProvisionId = strings.ToLower(random.UniqueId())
+	}
+	if meta.ProvisionId != "" && meta.ArtifactsRootDir == "" {
+		meta.ArtifactsRootDir = util.DefaultArtifactsRootDir(meta.ProvisionId)
+	}
+	if opts.record != "" {
+		meta.Cassette = &model.CassetteConfig{Mode: util.CassetteRecordMode, Path: absolutePath(opts.record)}
+	} else if opts.replay != "" {
+		meta.Cassette = &model.CassetteConfig{Mode: util.CassetteReplayMode, Path: absolutePath(opts.replay)}
+	}
+	return meta, config, nil
+}
+
+func runProvision(name string, args []string) int {
+	return runPhases(name, args, []model.Phase{model.PhaseProvision})
+}
+
+func runSetup(name string, args []string) int {
+	return runPhases(name, args, []model.Phase{model.PhasePreInstall})
+}
+
+func runInstall(name string, args []string) int {
+	return runPhases(name, args, []model.Phase{model.PhaseInstall})
+}
+
+func runVerify(name string, args []string) int {
+	return runPhases(name, args, []model.Phase{model.PhaseValidate})
+}
+
+func runDiagnose(name string, args []string) int {
+	return runPhases(name, args, []model.Phase{model.PhaseDiagnose})
+}
+
+func runCleanup(name string, args []string) int {
+	return runPhases(name, args, []model.Phase{model.PhaseCleanup})
+}
+
+func runPipeline(name string, args []string) int {
+	var phaseList string
+	opts, err := parseOptionsWith(name, args, func(flags *flag.FlagSet) {
+		flags.StringVar(&phaseList, "phases", "", fmt.Sprintf("comma separated phases to apply in order, of: %v",
+			util.PhaseOrder))
+	})
+	if err != nil {
+		return reportError(err)
+	}
+
+	phases, err := util.ParsePhases(phaseList)
+	if err != nil {
+		return reportError(err)
+	}
+	return applyPhases(name, opts, phases)
+}
+
+func runPhases(name string, args []string, phases []model.Phase) int {
+	opts, err := parseOptions(name, args)
+	if err != nil {
+		return reportError(err)
+	}
+	return applyPhases(name, opts, phases)
+}
+
+// applyPhases applies the phases through the util Apply, driven by a phase runner.
+func applyPhases(name string, opts options, phases []model.Phase) int {
+	meta, config, err := loadConfig(opts)
+	if err != nil {
+		return reportError(err)
+	}
+	meta.Enable = model.EnableConfig{Simulate: meta.Enable.Simulate, Adopt: meta.Enable.Adopt, Phases: phases}
+
+	if err := changeWorkDir(opts.workDir); err != nil {
+		return reportError(err)
+	}
+
+	return runActivity(defaultTestPrefix+capitalize(name), meta, opts.timeout, func(t testing.TestingT) {
+		util.Apply(t, meta, config)
+	})
+}
+
+func runUpgrade(name string, args []string) int {
+	var toVersion, order string
+	opts, err := parseOptionsWith(name, args, func(flags *flag.FlagSet) {
+		flags.StringVar(&toVersion, "to-version", "",
+			"k8ssandra-operator chart version to upgrade to, overriding the upgrade of the configuration")
+		flags.StringVar(&order, "order", "",
+			"control-plane-first or data-planes-first, overriding the upgrade of the configuration")
+	})
+	if err != nil {
+		return reportError(err)
+	}
+
+	meta, config, err := loadConfig(opts)
+	if err != nil {
+		return reportError(err)
+	}
+	meta.Enable = model.EnableConfig{Simulate: meta.Enable.Simulate, Adopt: meta.Enable.Adopt,
+		Phases: []model.Phase{model.PhaseUpgrade}}
+	if toVersion != "" {
+		config.ProvisionConfig.Upgrade.ToVersion = toVersion
+	}
+	if order != "" {
+		config.ProvisionConfig.Upgrade.Order = model.UpgradeOrder(order)
+	}
+
+	if err := changeWorkDir(opts.workDir); err != nil {
+		return reportError(err)
+	}
+
+	return runActivity(defaultTestPrefix+capitalize(name), meta, opts.timeout, func(t testing.TestingT) {
+		util.Apply(t, meta, config)
+	})
+}
+
+func runMatrix(name string, args []string) int {
+	var kubernetesVersions, operatorVersions, certManagerVersions string
+	opts, err := parseOptionsWith(name, args, func(flags *flag.FlagSet) {
+		flags.StringVar(&kubernetesVersions, "kubernetes-versions", "",
+			"comma separated Kubernetes versions, overriding the matrix of the configuration")
+		flags.StringVar(&operatorVersions, "operator-versions", "",
+			"comma separated k8ssandra-operator chart versions, overriding the matrix of the configuration")
+		flags.StringVar(&certManagerVersions, "cert-manager-versions", "",
+			"comma separated cert-manager versions, overriding the matrix of the configuration")
+	})
+	if err != nil {
+		return reportError(err)
+	}
+
+	meta, config, err := loadConfig(opts)
+	if err != nil {
+		return reportError(err)
+	}
+	overrideVersions(&config.Matrix.KubernetesVersions, kubernetesVersions)
+	overrideVersions(&config.Matrix.OperatorVersions, operatorVersions)
+	overrideVersions(&config.Matrix.CertManagerVersions, certManagerVersions)
+
+	if err := changeWorkDir(opts.workDir); err != nil {
+		return reportError(err)
+	}
+
+	return runActivity(defaultTestPrefix+capitalize(name), meta, opts.timeout, func(t testing.TestingT) {
+		util.ApplyMatrix(t, meta, config)
+	})
+}
+
+// overrideVersions replaces the versions by the comma separated list, when provided.
+func overrideVersions(versions *[]string, list string) {
+	if list == "" {
+		return
+	}
+	*versions = nil
+	for _, version := range strings.Split(list, ",") {
+		if version = strings.TrimSpace(version); version != "" {
+			*versions = append(*versions, version)
+		}
+	}
+}
+
+func runValidate(name string, args []string) int {
+	opts, err := parseOptions(name, args)
+	if err != nil {
+		return reportError(err)
+	}
+
+	_, config, err := loadConfig(opts)
+	if err != nil {
+		return reportError(err)
+	}
+
+	planned, _, err := util.AllocateCidrBlocks(config)
+	if err != nil {
+		return reportError(err)
+	}
+
+	validationErrors := util.Validate(planned)
+	for _, validationError := range validationErrors {
+		fmt.Println(validationError.Error())
+	}
+	if len(validationErrors) > 0 {
+		return 1
+	}
+	fmt.Println("readiness configuration is valid")
+	return 0
+}
+
+type contextPlan struct {
+	FullContextName  string                 `json:"full_context_name"`
+	CloudClusterName string                 `json:"cloud_cluster_name,omitempty"`
+	NetworkConfig    model.NetworkConfig    `json:"network_config"`
+	TerraformVars    map[string]interface{} `json:"terraform_vars,omitempty"`
+}
+
+func runPlan(name string, args []string) int {
+	opts, err := parseOptions(name, args)
+	if err != nil {
+		return reportError(err)
+	}
+
+	meta, config, err := loadConfig(opts)
+	if err != nil {
+		return reportError(err)
+	}
+
+	planned, _, err := util.AllocateCidrBlocks(config)
+	if err != nil {
+		return reportError(err)
+	}
+
+	var validationErrors []string
+	for _, validationError := range util.Validate(planned) {
+		validationErrors = append(validationErrors, validationError.Error())
+	}
+
+	contexts := map[string]contextPlan{}
+	for ctxName, ctx := range planned.Contexts {
+		if util.IsExistingCluster(ctx) {
+			contexts[ctxName] = contextPlan{FullContextName: ctx.ExistingCluster.ContextName, NetworkConfig: ctx.NetworkConfig}
+			continue
+		}
+		provider, err := cloud.Lookup(ctx.CloudConfig.Type)
+		if err != nil {
+			return reportError(err)
+		}
+		contexts[ctxName] = contextPlan{
+			FullContextName:  provider.ConstructFullContextName(ctxName, ctx.CloudConfig),
+			CloudClusterName: provider.ConstructCloudClusterName(ctxName, ctx.CloudConfig),
+			NetworkConfig:    ctx.NetworkConfig,
+			TerraformVars:    provider.TerraformVars(meta, planned, ctxName, ctx, meta.DefaultConfigPath),
+		}
+	}
+
+	return printJSON(map[string]interface{}{
+		"provision_id":      meta.ProvisionId,
+		"validation_errors": validationErrors,
+		"contexts":          contexts,
+	})
+}
+
+func runPreview(name string, args []string) int {
+	var phaseList string
+	var isJSON bool
+	opts, err := parseOptionsWith(name, args, func(flags *flag.FlagSet) {
+		flags.StringVar(&phaseList, "phases", "", fmt.Sprintf("comma separated phases to preview in order, of: %v",
+			util.PhaseOrder))
+		flags.BoolVar(&isJSON, "json", false, "report the execution plan as JSON")
+	})
+	if err != nil {
+		return reportError(err)
+	}
+
+	phases, err := util.ParsePhases(phaseList)
+	if err != nil {
+		return reportError(err)
+	}
+
+	meta, config, err := loadConfig(opts)
+	if err != nil {
+		return reportError(err)
+	}
+
+	planned, _, err := util.AllocateCidrBlocks(config)
+	if err != nil {
+		return reportError(err)
+	}
+
+	plan, err := util.BuildExecutionPlan(meta, planned, phases)
+	if err != nil {
+		return reportError(err)
+	}
+	if isJSON {
+		return printJSON(plan)
+	}
+	fmt.Print(util.FormatExecutionPlan(plan))
+	return 0
+}
+
+func runStatus(name string, args []string) int {
+	opts, err := parseOptions(name, args)
+	if err != nil {
+		return reportError(err)
+	}
+
+	meta, _, err := loadConfig(opts)
+	if err != nil {
+		return reportError(err)
+	}
+	if meta.ProvisionId == "" || meta.ArtifactsRootDir == "" {
+		return reportError(errors.New("a provision identifier is required, use --provision-id or the runs " +
+			"command to list the known identifiers"))
+	}
+
+	ledger, err := util.LoadLedger(meta)
+	if err != nil {
+		return reportError(err)
+	}
+
+	return printJSON(map[string]interface{}{
+		"artifacts_exist": files.IsExistingDir(meta.ArtifactsRootDir),
+		"ledger":          ledger,
+	})
+}
+
+func runRuns(name string, args []string) int {
+	var parentDir string
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.StringVar(&parentDir, "dir", os.TempDir(), "parent directory of the artifacts root directories")
+	if err := flags.Parse(args); err != nil {
+		return reportError(err)
+	}
+
+	ledgers, err := util.FindLedgers(parentDir)
+	if err != nil {
+		return reportError(err)
+	}
+
+	var runs = []map[string]interface{}{}
+	for _, ledger := range ledgers {
+		runs = append(runs, map[string]interface{}{
+			"provision_id":       ledger.ProvisionId,
+			"artifacts_root_dir": ledger.ArtifactsRootDir,
+			"completed_phases":   ledger.CompletedPhases,
+			"errors":             len(ledger.Errors),
+			"updated_at":         ledger.UpdatedAt,
+		})
+	}
+	return printJSON(runs)
+}
+
+func changeWorkDir(workDir string) error {
+	if workDir == "" {
+		if !files.IsExistingDir(defaultWorkDir) {
+			return nil
+		}
+		workDir = defaultWorkDir
+	}
+	return os.Chdir(workDir)
+}
+
+// absolutePath resolves a path before the working directory is changed, keeping the path when unresolved.
+func absolutePath(filePath string) string {
+	if resolved, err := filepath.Abs(filePath); err == nil {
+		return resolved
+	}
+	return filePath
+}
+
+func capitalize(name string) string {
+	if name == "" {
+		return name
+	}
+	return string(name[0]-'a'+'A') + name[1:]
+}
+
+func printJSON(value interface{}) int {
+	out, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return reportError(err)
+	}
+	fmt.Println(string(out))
+	return 0
+}
+
+func reportError(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	fmt.Fprintln(os.Stderr, "error: "+err.Error())
+	return 1
+}
@@ -0,0 +1,163 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package main
+
+import (
+	"flag"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const scenarioConfig = "../../k8ssandra/test/testdata/scenario_1/readiness-config.yaml"
+
+func TestParseOptions(t *testing.T) {
+	t.Setenv(util.DefaultReadinessConfigKey, "")
+
+	_, err := parseOptions("install", nil)
+	require.Error(t, err, "expecting a configuration file to be required")
+
+	_, err = parseOptions("install", []string{"--config", scenarioConfig, "--record", "a.json", "--replay", "b.json"})
+	require.Error(t, err, "expecting record and replay to be exclusive")
+
+	_, err = parseOptions("install", []string{"-h"})
+	require.ErrorIs(t, err, flag.ErrHelp)
+	require.Zero(t, reportError(err))
+
+	_, err = parseOptions("install", []string{"--config", scenarioConfig, "--unknown"})
+	require.Error(t, err)
+
+	opts, err := parseOptions("install", []string{"--config", scenarioConfig, "--simulate", "--provision-id",
+		"Qk9z7G", "--timeout", "90m"})
+	require.NoError(t, err)
+	expected, _ := filepath.Abs(scenarioConfig)
+	require.Equal(t, expected, opts.configPath)
+	require.True(t, opts.simulate)
+	require.Equal(t, "Qk9z7G", opts.provisionId)
+	require.Equal(t, "1h30m0s", opts.timeout.String())
+}
+
+func TestParseOptionsFromEnv(t *testing.T) {
+	t.Setenv(util.DefaultReadinessConfigKey, scenarioConfig)
+
+	opts, err := parseOptions("verify", nil)
+	require.NoError(t, err)
+	require.True(t, filepath.IsAbs(opts.configPath))
+}
+
+func TestParseOptionsWithCommandFlags(t *testing.T) {
+	var phaseList string
+	opts, err := parseOptionsWith("run", []string{"--config", scenarioConfig, "--phases", "provision, install"},
+		func(flags *flag.FlagSet) {
+			flags.StringVar(&phaseList, "phases", "", "phases")
+		})
+	require.NoError(t, err)
+	require.NotEmpty(t, opts.configPath)
+
+	phases, err := util.ParsePhases(phaseList)
+	require.NoError(t, err)
+	require.Equal(t, []model.Phase{model.PhaseProvision, model.PhaseInstall}, phases)
+}
+
+func TestRunPipelineRejectsPhases(t *testing.T) {
+	require.Equal(t, 1, runPipeline("run", []string{"--config", scenarioConfig, "--phases", "provision,deploy"}))
+	require.Equal(t, 1, runPipeline("run", []string{"--config", scenarioConfig, "--phases", " , "}))
+}
+
+func TestLoadConfig(t *testing.T) {
+	opts, err := parseOptions("install", []string{"--config", scenarioConfig, "--simulate", "--provision-id",
+		"Qk9z7G", "--adopt", "--record", "cassette.json"})
+	require.NoError(t, err)
+
+	meta, _, err := loadConfig(opts)
+	require.NoError(t, err)
+	require.Equal(t, "Qk9z7G", meta.ProvisionId)
+	require.True(t, meta.Enable.Adopt)
+	require.Equal(t, util.DefaultArtifactsRootDir("Qk9z7G"), meta.ArtifactsRootDir)
+	require.True(t, meta.Enable.Simulate)
+	require.Equal(t, util.CassetteRecordMode, meta.Cassette.Mode)
+	require.True(t, filepath.IsAbs(meta.Cassette.Path))
+
+	opts.record = ""
+	opts.replay = "cassette.json"
+	meta, _, err = loadConfig(opts)
+	require.NoError(t, err)
+	require.Equal(t, util.CassetteReplayMode, meta.Cassette.Mode)
+
+	opts.provisionId = ""
+	opts.adopt = false
+	meta, _, err = loadConfig(opts)
+	require.NoError(t, err)
+	require.NotEmpty(t, meta.ProvisionId, "expecting a provision identifier known before the run")
+	require.Equal(t, util.DefaultArtifactsRootDir(meta.ProvisionId), meta.ArtifactsRootDir)
+}
+
+func TestOverrideVersions(t *testing.T) {
+	versions := []string{"1.22"}
+	overrideVersions(&versions, "")
+	require.Equal(t, []string{"1.22"}, versions)
+
+	overrideVersions(&versions, "1.23, ,1.24")
+	require.Equal(t, []string{"1.23", "1.24"}, versions)
+}
+
+func TestCapitalize(t *testing.T) {
+	require.Equal(t, "TestK8cRun", defaultTestPrefix+capitalize("run"))
+	require.Empty(t, capitalize(""))
+}
+
+func TestPhaseRunner(t *testing.T) {
+	runner := newPhaseRunner("TestK8cRun", time.Hour)
+	deadline, hasDeadline := runner.Deadline()
+	require.True(t, hasDeadline)
+	require.WithinDuration(t, time.Now().Add(time.Hour), deadline, time.Minute)
+
+	var isStopped = true
+	require.False(t, runner.Run("install", func(t terratesting.TestingT) {
+		require.Equal(t, "TestK8cRun/install", t.Name())
+		t.FailNow()
+		isStopped = false
+	}))
+	require.True(t, isStopped, "expecting FailNow to stop the nested activity")
+	require.True(t, runner.Failed(), "expecting a nested failure to fail the runner")
+
+	require.True(t, newPhaseRunner("TestK8cRun", 0).Run("verify", func(t terratesting.TestingT) {}))
+}
+
+func TestRunActivity(t *testing.T) {
+	require.Zero(t, runActivity("TestK8cRun", model.ProvisionMeta{}, 0, func(t terratesting.TestingT) {}))
+	require.Equal(t, 1, runActivity("TestK8cRun", model.ProvisionMeta{}, 0, func(t terratesting.TestingT) {
+		t.Errorf("phase: %s failed", model.PhaseInstall)
+	}))
+
+	meta := model.ProvisionMeta{ProvisionId: "Qk9z7G", ArtifactsRootDir: t.TempDir()}
+	var isWoundDown = false
+	require.Equal(t, 1, runActivity("TestK8cRun", meta, time.Millisecond, func(t terratesting.TestingT) {
+		time.Sleep(100 * time.Millisecond)
+		isWoundDown = true
+	}), "expecting a timeout to fail the command")
+	require.True(t, isWoundDown, "expecting the activity to wind down before exiting")
+
+	ledger, err := util.LoadLedger(meta)
+	require.NoError(t, err)
+	require.Len(t, ledger.Errors, 1)
+	require.Equal(t, "TestK8cRun timed out after 1ms", ledger.Errors[0].Message)
+}
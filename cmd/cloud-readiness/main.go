@@ -0,0 +1,75 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// command is a cloud-readiness subcommand, returning the process exit code.
+type command struct {
+	description string
+	run         func(name string, args []string) int
+}
+
+var commands = map[string]command{
+	"provision": {"provision the cloud infrastructure for every context", runProvision},
+	"setup":     {"pre-install setup of repositories, cert-manager and Traefik", runSetup},
+	"install":   {"install the k8ssandra-operator, client configurations and K8ssandraCluster", runInstall},
+	"verify":    {"verify the k8ssandra-operator rollout and the K8ssandraCluster in every context", runVerify},
+	"upgrade":   {"upgrade the k8ssandra-operator of every context while monitoring the Cassandra quorum", runUpgrade},
+	"diagnose":  {"collect the pods, events and K8ssandraCluster resources of every context", runDiagnose},
+	"cleanup":   {"remove the provisioned cloud infrastructure and test artifacts", runCleanup},
+	"run":       {"apply a comma separated list of phases in order", runPipeline},
+	"validate":  {"statically validate the readiness configuration", runValidate},
+	"status":    {"report the run ledger of a provisioning run", runStatus},
+	"runs":      {"list the provisioning runs with a run ledger", runRuns},
+	"plan":      {"report the network plan and Terraform variables of every context", runPlan},
+	"preview":   {"report the execution plan of a comma separated list of phases", runPreview},
+	"matrix":    {"run install and validation across versions, reporting a compatibility table", runMatrix},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	cmd, found := commands[name]
+	if !found {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", name)
+		printUsage()
+		os.Exit(2)
+	}
+	os.Exit(cmd.run(name, os.Args[2:]))
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: cloud-readiness <command> [flags]\n\nCommands:\n")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].description)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'cloud-readiness <command> -h' for the flags of a command.\n")
+}
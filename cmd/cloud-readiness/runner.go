@@ -0,0 +1,156 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package main
+
+import (
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// defaultWindDownPeriod is the time a timed out activity is given to stop, longer than the interrupt grace period
+// of its commands.
+const defaultWindDownPeriod = executor.InterruptGracePeriod + 15*time.Second
+
+// phaseRunner is the testing.TestingT driving the phases of a command, running the nested activities of the
+// phases in their own goroutine so that FailNow stops only the failing activity.
+type phaseRunner struct {
+	name     string
+	parent   *phaseRunner
+	deadline time.Time
+
+	mutex  sync.Mutex
+	failed bool
+}
+
+func newPhaseRunner(name string, timeout time.Duration) *phaseRunner {
+	runner := &phaseRunner{name: name}
+	if timeout > 0 {
+		runner.deadline = time.Now().Add(timeout)
+	}
+	return runner
+}
+
+func (r *phaseRunner) Fail() {
+	r.mutex.Lock()
+	r.failed = true
+	r.mutex.Unlock()
+
+	if r.parent != nil {
+		r.parent.Fail()
+	}
+}
+
+func (r *phaseRunner) FailNow() {
+	r.Fail()
+	runtime.Goexit()
+}
+
+func (r *phaseRunner) Fatal(args ...interface{}) {
+	r.report(fmt.Sprint(args...))
+	r.FailNow()
+}
+
+func (r *phaseRunner) Fatalf(format string, args ...interface{}) {
+	r.report(fmt.Sprintf(format, args...))
+	r.FailNow()
+}
+
+func (r *phaseRunner) Error(args ...interface{}) {
+	r.report(fmt.Sprint(args...))
+	r.Fail()
+}
+
+func (r *phaseRunner) Errorf(format string, args ...interface{}) {
+	r.report(fmt.Sprintf(format, args...))
+	r.Fail()
+}
+
+func (r *phaseRunner) Name() string {
+	return r.name
+}
+
+// Failed indicates the runner or any of its nested activities failed.
+func (r *phaseRunner) Failed() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.failed
+}
+
+// Deadline provides the time the command times out, when a timeout is given.
+func (r *phaseRunner) Deadline() (time.Time, bool) {
+	return r.deadline, !r.deadline.IsZero()
+}
+
+// Run runs the activity as a nested activity, waiting for its completion.
+func (r *phaseRunner) Run(name string, activity func(t testing.TestingT)) bool {
+	nested := &phaseRunner{name: r.name + "/" + name, parent: r, deadline: r.deadline}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		activity(nested)
+	}()
+	<-done
+	return !nested.Failed()
+}
+
+func (r *phaseRunner) report(message string) {
+	fmt.Fprintf(os.Stderr, "%s: %s\n", r.name, message)
+}
+
+// runActivity drives the activity with a phase runner, providing the exit code of the command. On timeout, the
+// commands of the activity are interrupted at the deadline of the runner, and the activity is given the wind down
+// period to stop before the timeout is recorded in the run ledger.
+func runActivity(name string, meta model.ProvisionMeta, timeout time.Duration,
+	activity func(t testing.TestingT)) int {
+
+	runner := newPhaseRunner(name, timeout)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		activity(runner)
+	}()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
+	select {
+	case <-done:
+	case <-expired:
+		select {
+		case <-done:
+		case <-time.After(defaultWindDownPeriod):
+			fmt.Fprintf(os.Stderr, "%s: activity still running after %s\n", name, defaultWindDownPeriod)
+		}
+		err := fmt.Errorf("%s timed out after %s", name, timeout)
+		util.RecordLedgerError(runner, meta, "", "", err.Error())
+		return reportError(err)
+	}
+
+	if runner.Failed() {
+		fmt.Fprintf(os.Stderr, "FAIL: %s\n", name)
+		return 1
+	}
+	return 0
+}
@@ -14,32 +14,38 @@
 
 # Create Virtual Private Cloud
 module "vpc" {
-  source             = "../modules/vpc"
-  name               = local.name_prefix
-  environment        = var.environment
-  region             = var.region
-  public_cidr_block  = var.public_cidr_block
-  private_cidr_block = var.private_cidr_block
-  tags               = local.tags
+  source                = "../modules/vpc"
+  name                  = local.name_prefix
+  environment           = var.environment
+  region                = var.region
+  vpc_cidr_block        = var.vpc_cidr_block
+  secondary_cidr_blocks = var.secondary_cidr_blocks
+  public_cidr_block     = var.public_cidr_block
+  private_cidr_block    = var.private_cidr_block
+  availability_zones    = var.availability_zones
+  tags                  = local.tags
 }
 
 # Create Elastic Kubernetes Service
 module "eks" {
-  source                = "../modules/eks"
-  name                  = local.name_prefix
-  region                = var.region
-  environment           = var.environment
-  instance_type         = var.instance_type
-  desired_capacity      = var.desired_capacity
-  max_size              = var.max_size
-  min_size              = var.min_size
-  role_arn              = module.iam.role_arn
-  worker_role_arn       = module.iam.worker_role_arn
-  subnet_ids            = module.vpc.aws_subnet_private_ids
-  security_group_id     = module.vpc.security_group_id
-  public_subnets        = module.vpc.aws_subnet_public_ids
-  instance_profile_name = module.iam.iam_instance_profile
-  tags                  = local.tags
+  source                     = "../modules/eks"
+  name                       = local.name_prefix
+  region                     = var.region
+  environment                = var.environment
+  cluster_version            = var.cluster_version
+  instance_type              = var.instance_type
+  desired_capacity           = var.desired_capacity
+  max_size                   = var.max_size
+  min_size                   = var.min_size
+  node_groups                = var.node_groups
+  role_arn                   = module.iam.role_arn
+  worker_role_arn            = module.iam.worker_role_arn
+  subnet_ids                 = module.vpc.aws_subnet_private_ids
+  private_subnet_ids_by_zone = module.vpc.aws_subnet_private_ids_by_zone
+  security_group_id          = module.vpc.security_group_id
+  public_subnets             = module.vpc.aws_subnet_public_ids
+  instance_profile_name      = module.iam.iam_instance_profile
+  tags                       = local.tags
 }
 
 # Create Identity Access Management
@@ -56,6 +62,7 @@ module "iam" {
 module "s3" {
   source      = "../modules/s3"
   name        = local.name_prefix
+  bucket_name = var.bucket_name
   environment = var.environment
   tags        = local.tags
 }
@@ -64,6 +64,43 @@ variable "min_size" {
   default     = 3
 }
 
+variable "cluster_version" {
+  description = "Version of the EKS cluster."
+  type        = string
+  default     = "1.20"
+}
+
+variable "node_groups" {
+  description = "Managed node groups, one per availability zone, each with a name, label and location."
+  type        = list(map(string))
+  default     = []
+}
+
+variable "availability_zones" {
+  description = "Availability zones of the VPC subnets, the zones of the node groups, picked from the region when empty."
+  type        = list(string)
+  default     = []
+}
+
+variable "bucket_name" {
+  description = "Name of the S3 bucket used for Medusa backups, defaults to the name prefix when empty."
+  type        = string
+  default     = ""
+}
+
+# Expose VPC Settings
+variable "vpc_cidr_block" {
+  description = "Virtual Private Cloud CIDR block"
+  type        = string
+  default     = "10.0.0.0/16"
+}
+
+variable "secondary_cidr_blocks" {
+  description = "Secondary Virtual Private Cloud CIDR blocks"
+  type        = list(string)
+  default     = []
+}
+
 # Expose Subnet Ssettings
 variable "public_cidr_block" {
   description = "List of public subnet cidr blocks"
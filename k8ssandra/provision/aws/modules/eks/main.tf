@@ -30,8 +30,10 @@ resource "aws_eks_cluster" "eks_cluster" {
 
 }
 
-# AWS EKS node group configuration. 
+# AWS EKS node group configuration, only when no rack node groups are provided.
 resource "aws_eks_node_group" "eks_node_group" {
+  count = length(var.node_groups) == 0 ? 1 : 0
+
   cluster_name    = aws_eks_cluster.eks_cluster.name
   node_group_name = format("%s-node-group", var.name)
   node_role_arn   = var.worker_role_arn
@@ -57,3 +59,35 @@ resource "aws_eks_node_group" "eks_node_group" {
     "key" = format("%s", aws_eks_cluster.eks_cluster.name)
   }
 }
+
+# AWS EKS node group per rack, pinned to the availability zone of the rack.
+resource "aws_eks_node_group" "eks_rack_node_group" {
+  for_each = { for group in var.node_groups : group.name => group }
+
+  cluster_name    = aws_eks_cluster.eks_cluster.name
+  node_group_name = format("%s-%s", var.name, each.value.name)
+  node_role_arn   = var.worker_role_arn
+  subnet_ids      = [var.private_subnet_ids_by_zone[each.value.location]]
+  instance_types  = [var.instance_type]
+
+  scaling_config {
+    desired_size = tonumber(lookup(each.value, "desired_size", var.desired_capacity))
+    max_size     = tonumber(lookup(each.value, "max_size", var.max_size))
+    min_size     = tonumber(lookup(each.value, "min_size", var.min_size))
+  }
+  depends_on = [
+    aws_eks_cluster.eks_cluster
+  ]
+
+  tags = merge(var.tags, {
+    "Name"                                                   = format("%s-%s", var.name, each.value.name)
+    format("kubernetes.io/cluster/%s-eks-cluster", var.name) = "owned"
+    }
+  )
+
+  # The kubelet sets the zone label of the node, the rack label, validated as key=value by the readiness
+  # configuration, is optional.
+  labels = merge({
+    "key" = format("%s", aws_eks_cluster.eks_cluster.name)
+  }, { for label in compact([lookup(each.value, "label", "")]) : split("=", label)[0] => split("=", label)[1] })
+}
@@ -91,3 +91,15 @@ variable "min_size" {
   description = "Minimum number of the instances in autoscaling group"
   type        = number
 }
+
+variable "node_groups" {
+  description = "Managed node groups, one per availability zone, each with a name, label and location."
+  type        = list(map(string))
+  default     = []
+}
+
+variable "private_subnet_ids_by_zone" {
+  description = "Private subnet ids keyed by availability zone, placing each rack node group in the zone of the rack."
+  type        = map(string)
+  default     = {}
+}
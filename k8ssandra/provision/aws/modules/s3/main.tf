@@ -14,7 +14,7 @@
 
 # Create s3 bucket resource
 resource "aws_s3_bucket" "s3_bucket" {
-  bucket = format("%s-s3-bucket", var.name)
+  bucket = var.bucket_name != "" ? var.bucket_name : format("%s-s3-bucket", var.name)
   tags   = var.tags
 
   # Force destroy bucket if there are any files exists. 
@@ -39,3 +39,9 @@ variable "region" {
   type        = string
   default     = "us-east-1"
 }
+
+variable "bucket_name" {
+  description = "Name of the S3 bucket, defaults to the name prefix when empty."
+  type        = string
+  default     = ""
+}
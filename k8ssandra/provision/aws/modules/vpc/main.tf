@@ -29,6 +29,13 @@ resource "aws_vpc" "vpc" {
   }
 }
 
+# Associate the secondary CIDR blocks with the VPC.
+resource "aws_vpc_ipv4_cidr_block_association" "secondary_cidr_block" {
+  count      = length(var.secondary_cidr_blocks)
+  vpc_id     = aws_vpc.vpc.id
+  cidr_block = var.secondary_cidr_blocks[count.index]
+}
+
 # Create AWS public subnet.
 resource "aws_subnet" "public_subnet" {
   count             = local.pub_az_count
@@ -61,6 +68,9 @@ resource "aws_subnet" "private_subnet" {
   cidr_block        = var.private_cidr_block[count.index]
   availability_zone = local.pri_avilability_zones[count.index]
 
+  # The private subnets may be carved from a secondary CIDR block.
+  depends_on = [aws_vpc_ipv4_cidr_block_association.secondary_cidr_block]
+
   tags = merge(var.tags, {
     "Name" = format("%s-private-subnet-%s", var.name, local.pri_avilability_zones[count.index])
 
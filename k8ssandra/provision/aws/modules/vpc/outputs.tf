@@ -32,6 +32,11 @@ output "aws_subnet_private_ids" {
   value = aws_subnet.private_subnet.*.id
 }
 
+# The zones are known when planning, while the ids are only known once applied.
+output "aws_subnet_private_ids_by_zone" {
+  value = zipmap(aws_subnet.private_subnet.*.availability_zone, aws_subnet.private_subnet.*.id)
+}
+
 # Output atrributes of the route table ids.
 #---------------------------------------------
 output "aws_route_table_public_ids" {
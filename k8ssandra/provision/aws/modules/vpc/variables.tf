@@ -40,6 +40,13 @@ variable "vpc_cidr_block" {
   default     = "10.0.0.0/16"
 }
 
+# Secondary CIDR blocks associated with the VPC, holding the subnets outside of the vpc_cidr_block.
+variable "secondary_cidr_blocks" {
+  description = "Secondary Virtual Private Cloud CIDR blocks"
+  type        = list(string)
+  default     = []
+}
+
 # Optional Variables
 ## Exposed VPC Settings.
 variable "vpc_instance_tenancy" {
@@ -103,10 +110,19 @@ variable "single_nat_gateway" {
   default     = 0
 }
 
+variable "availability_zones" {
+  description = "Availability zones of the subnets, one subnet per zone, picked from the region when empty"
+  type        = list(string)
+  default     = []
+}
+
 locals {
+  # The availability zones of the racks when provided, otherwise queried from the region.
+  zones = length(var.availability_zones) > 0 ? var.availability_zones : data.aws_availability_zones.availability_zones.names
+
   # Query on Data to pick up avilability zone automatically based on the length cidr blocks. 
-  pri_avilability_zones = slice(data.aws_availability_zones.availability_zones.names, 0, length(var.private_cidr_block))
-  pub_avilability_zones = slice(data.aws_availability_zones.availability_zones.names, 0, length(var.public_cidr_block))
+  pri_avilability_zones = slice(local.zones, 0, min(length(local.zones), length(var.private_cidr_block)))
+  pub_avilability_zones = slice(local.zones, 0, min(length(local.zones), length(var.public_cidr_block)))
 
   # Set local variables number of avilability zones based on the query results.
   pub_az_count = length(local.pub_avilability_zones)
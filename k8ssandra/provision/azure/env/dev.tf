@@ -27,7 +27,7 @@ module "aks" {
   private_subnet      = module.vnet.private_subnets
   user_assigned_id    = module.iam.user_id
   vm_size             = var.vm_size
-
+  node_pools          = var.node_pools
 
   tags = merge(local.tags, { "resource_group" = module.iam.resource_group_name })
 }
@@ -39,6 +39,7 @@ module "vnet" {
   environment               = var.environment
   resource_group_name       = module.iam.resource_group_name
   location                  = module.iam.location
+  address_space             = var.address_space
   public_subnet_prefixes    = var.public_subnet_prefixes
   private_subnet_prefixes   = var.private_subnet_prefixes
   private_service_endpoints = var.private_service_endpoints
@@ -63,6 +64,7 @@ module "storage" {
   environment         = var.environment
   resource_group_name = module.iam.resource_group_name
   location            = module.iam.location
+  container_name      = var.container_name
 
   tags = merge(local.tags, { "resource_group" = module.iam.resource_group_name })
 }
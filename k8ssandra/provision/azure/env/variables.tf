@@ -68,6 +68,24 @@ variable "vm_size" {
   type        = string
 }
 
+variable "node_pools" {
+  description = "Node pools, one per availability zone, each with a name, label and location."
+  type        = list(map(string))
+  default     = []
+}
+
+variable "container_name" {
+  description = "Name of the Blob container used for Medusa backups, defaults to the name prefix when empty."
+  type        = string
+  default     = ""
+}
+
+variable "address_space" {
+  description = "The address space that is used by the virtual network."
+  type        = list(string)
+  default     = ["10.1.0.0/16"]
+}
+
 variable "public_subnet_prefixes" {
   description = "value"
   type        = list(string)
@@ -70,3 +70,22 @@ resource "azurerm_kubernetes_cluster" "kubernetes_cluster" {
   }
 
 }
+
+# Node pool per rack, pinned to the availability zone of the rack.
+resource "azurerm_kubernetes_cluster_node_pool" "rack_node_pool" {
+  for_each = { for pool in var.node_pools : pool.name => pool }
+
+  # The node pool only allows name with 12 characters, does not allow any special characters.
+  name                  = each.value.name
+  kubernetes_cluster_id = azurerm_kubernetes_cluster.kubernetes_cluster.id
+  vm_size               = var.vm_size
+  node_count            = tonumber(lookup(each.value, "node_count", var.node_count))
+  vnet_subnet_id        = var.private_subnet
+  availability_zones    = [each.value.location]
+
+  # The kubelet sets the zone label of the node, the rack label, validated as key=value by the readiness
+  # configuration, is optional.
+  node_labels = { for label in compact([lookup(each.value, "label", "")]) : split("=", label)[0] => split("=", label)[1] }
+
+  tags = var.tags
+}
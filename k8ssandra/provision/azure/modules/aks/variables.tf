@@ -107,3 +107,9 @@ variable "tags" {
   type        = map(string)
   default     = {}
 }
+
+variable "node_pools" {
+  description = "Node pools, one per availability zone, each with a name, label and location."
+  type        = list(map(string))
+  default     = []
+}
@@ -50,7 +50,7 @@ resource "azurerm_subnet_service_endpoint_storage_policy" "subnet_service_endpoi
 
 # Azure Storage Container
 resource "azurerm_storage_container" "storage_container" {
-  name                 = format("%s-storage-container", var.name)
+  name                 = var.container_name != "" ? var.container_name : format("%s-storage-container", var.name)
   storage_account_name = azurerm_storage_account.storage_account.name
   # Storge container access type is private always.
   container_access_type = "private"
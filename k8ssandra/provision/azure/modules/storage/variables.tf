@@ -25,6 +25,12 @@ variable "resource_group_name" {
   type        = string
 }
 
+variable "container_name" {
+  description = "Name of the storage container, defaults to the name prefix when empty."
+  type        = string
+  default     = ""
+}
+
 # Storage Account variables.
 variable "account_tier" {
   description = "The Storage Acount tier."
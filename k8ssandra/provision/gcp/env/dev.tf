@@ -33,6 +33,7 @@ module "gke" {
   project_id             = var.project_id
   initial_node_count     = var.initial_node_count
   machine_type           = var.machine_type
+  kubernetes_version     = var.kubernetes_version
   network_link           = module.vpc.network_selflink
   subnetwork_link        = module.vpc.subnetwork_selflink
   service_account        = module.iam.service_account
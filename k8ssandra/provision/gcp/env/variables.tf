@@ -119,6 +119,12 @@ variable "machine_type" {
   default     = "e2-highmem-8"
 }
 
+variable "kubernetes_version" {
+  description = "Minimum version of the Kubernetes master, the default version of the release channel when null."
+  type        = string
+  default     = null
+}
+
 variable "service_account_iam_roles" {
   type = list(string)
 
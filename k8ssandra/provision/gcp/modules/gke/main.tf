@@ -28,6 +28,7 @@ resource "google_container_cluster" "container_cluster" {
   remove_default_node_pool = true
   location                 = var.region
   node_locations           = var.node_locations
+  min_master_version       = var.kubernetes_version
   enable_shielded_nodes    = true
   network                  = var.network_link
   subnetwork               = var.subnetwork_link
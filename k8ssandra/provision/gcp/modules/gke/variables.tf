@@ -44,6 +44,12 @@ variable "machine_type" {
   default     = "e2-standard-8"
 }
 
+variable "kubernetes_version" {
+  description = "Minimum version of the Kubernetes master, the default version of the release channel when null."
+  type        = string
+  default     = null
+}
+
 variable "region" {
   description = "The location of the GKE cluster."
   type        = string
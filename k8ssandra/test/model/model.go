@@ -19,6 +19,8 @@ package model
 import (
 	"github.com/gruntwork-io/terratest/modules/k8s"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"time"
 )
 
 type PoolRackConfig struct {
@@ -28,18 +30,19 @@ type PoolRackConfig struct {
 }
 
 type CloudConfig struct {
-	Type            string           `json:"type,omitempty"`
-	Locations       []string         `json:"locations,omitempty"`
-	PoolRackConfigs []PoolRackConfig `json:"poolRackConfigs,omitempty"`
-	Zones           []string         `json:"zones,omitempty"`
-	Region          string           `json:"region,omitempty"`
-	Project         string           `json:"project,omitempty"`
-	Name            string           `json:"name,omitempty"`
-	CredPath        string           `json:"cred_path,omitempty"`
-	CredKey         string           `json:"cred_key,omitempty"`
-	Environment     string           `json:"environment,omitempty"`
-	MachineType     string           `json:"machine_type,omitempty"`
-	Bucket          string           `json:"bucket,omitempty"`
+	Type              string           `json:"type,omitempty"`
+	Locations         []string         `json:"locations,omitempty"`
+	PoolRackConfigs   []PoolRackConfig `json:"poolRackConfigs,omitempty"`
+	Zones             []string         `json:"zones,omitempty"`
+	Region            string           `json:"region,omitempty"`
+	Project           string           `json:"project,omitempty"`
+	Name              string           `json:"name,omitempty"`
+	CredPath          string           `json:"cred_path,omitempty"`
+	CredKey           string           `json:"cred_key,omitempty"`
+	Environment       string           `json:"environment,omitempty"`
+	MachineType       string           `json:"machine_type,omitempty"`
+	Bucket            string           `json:"bucket,omitempty"`
+	KubernetesVersion string           `json:"kubernetes_version,omitempty"`
 }
 
 type TFConfig struct {
@@ -58,6 +61,39 @@ type K8cConfig struct {
 	ValuesFilePath          string `json:"values_file_path,omitempty"`
 	ClusterScoped           bool   `json:"cluster_scoped,omitempty"`
 	ClusterName             string `json:"cluster_name,omitempty"`
+	CassandraVersion        string `json:"cassandra_version,omitempty"`
+	DatacenterSize          int    `json:"datacenter_size,omitempty"`
+	OverlayFilePath         string `json:"overlay_file_path,omitempty"`
+
+	ServiceAccountToken ServiceAccountTokenConfig `json:"service_account_token,omitempty"`
+	MedusaStandIn       *MedusaStandInConfig      `json:"medusa_stand_in,omitempty"`
+}
+
+// MedusaStandInConfig of the MinIO deployed in every context, standing in for the cloud bucket of Medusa.
+type MedusaStandInConfig struct {
+	BucketName string `json:"bucket_name,omitempty"`
+	AccessKey  string `json:"access_key,omitempty"`
+	SecretKey  string `json:"secret_key,omitempty"`
+}
+
+type ServiceAccountTokenConfig struct {
+	Mode              TokenMode `json:"mode,omitempty"`
+	ExpirationSeconds int64     `json:"expiration_seconds,omitempty"`
+}
+
+type TokenMode string
+
+const (
+	TokenModeAuto    TokenMode = "auto"
+	TokenModeRequest TokenMode = "request"
+	TokenModeSecret  TokenMode = "secret"
+	TokenModeLegacy  TokenMode = "legacy"
+)
+
+type MedusaStorage struct {
+	StorageProvider string `json:"storage_provider,omitempty"`
+	BucketName      string `json:"bucket_name,omitempty"`
+	Region          string `json:"region,omitempty"`
 }
 
 type NetworkConfig struct {
@@ -68,6 +104,13 @@ type NetworkConfig struct {
 	SecondaryCidrBlock  string `json:"secondary_cidr_block"`
 }
 
+type NetworkPlanConfig struct {
+	Supernet              string `json:"supernet,omitempty"`
+	SubnetPrefixLength    int    `json:"subnet_prefix_length,omitempty"`
+	SecondaryPrefixLength int    `json:"secondary_prefix_length,omitempty"`
+	MasterPrefixLength    int    `json:"master_prefix_length,omitempty"`
+}
+
 type ProvisionConfig struct {
 	DefaultRetries     int        `json:"default_retries,omitempty"`
 	DefaultSleepSecs   int        `json:"default_sleep_secs,omitempty"`
@@ -75,18 +118,97 @@ type ProvisionConfig struct {
 	HelmConfig         HelmConfig `json:"helm_config"`
 	TFConfig           TFConfig   `json:"tf_config"`
 	K8cConfig          K8cConfig  `json:"k8c_config"`
+
+	Versions VersionsConfig `json:"versions,omitempty"`
+	Upgrade  UpgradeConfig  `json:"upgrade,omitempty"`
+}
+
+// UpgradeConfig of the k8ssandra-operator upgrade, installing the from version and upgrading to the to version.
+type UpgradeConfig struct {
+	FromVersion        string       `json:"from_version,omitempty"`
+	ToVersion          string       `json:"to_version,omitempty"`
+	Order              UpgradeOrder `json:"order,omitempty"`
+	ContextOrder       []string     `json:"context_order,omitempty"`
+	QuorumIntervalSecs int          `json:"quorum_interval_secs,omitempty"`
+}
+
+// UpgradeOrder of the contexts upgraded, when no explicit context order is provided.
+type UpgradeOrder string
+
+const (
+	UpgradeControlPlaneFirst UpgradeOrder = "control-plane-first"
+	UpgradeDataPlanesFirst   UpgradeOrder = "data-planes-first"
+)
+
+// UpgradeReport of the upgraded contexts, in order, and of the quorum losses observed during the upgrade.
+type UpgradeReport struct {
+	FromVersion  string         `json:"from_version,omitempty"`
+	ToVersion    string         `json:"to_version"`
+	Contexts     []string       `json:"contexts"`
+	Samples      int            `json:"samples"`
+	QuorumLosses []QuorumSample `json:"quorum_losses,omitempty"`
+	StartedAt    time.Time      `json:"started_at"`
+	CompletedAt  time.Time      `json:"completed_at"`
+}
+
+// QuorumSample of the ready Cassandra nodes of a datacenter.
+type QuorumSample struct {
+	Context    string    `json:"context"`
+	Datacenter string    `json:"datacenter"`
+	Size       int       `json:"size"`
+	Ready      int       `json:"ready"`
+	SampledAt  time.Time `json:"sampled_at"`
+}
+
+// VersionsConfig pins the repositories, charts and manifests of the components installed.
+type VersionsConfig struct {
+	K8ssandraOperator ComponentVersion `json:"k8ssandra_operator,omitempty"`
+	CertManager       ComponentVersion `json:"cert_manager,omitempty"`
+	Traefik           ComponentVersion `json:"traefik,omitempty"`
+	MinIO             ComponentVersion `json:"minio,omitempty"`
+}
+
+// ComponentVersion of a helm chart, or of a manifest for cert-manager when no chart is provided.
+type ComponentVersion struct {
+	RepositoryName string            `json:"repository_name,omitempty"`
+	RepositoryURL  string            `json:"repository_url,omitempty"`
+	Chart          string            `json:"chart,omitempty"`
+	Version        string            `json:"version,omitempty"`
+	ValuesFile     string            `json:"values_file,omitempty"`
+	Values         map[string]string `json:"values,omitempty"`
+	Manifest       string            `json:"manifest,omitempty"`
 }
 
 type ProvisionResult struct {
-	Success bool `json:"success,omitempty"`
+	Success        bool         `json:"success,omitempty"`
+	Context        string       `json:"context"`
+	Phase          Phase        `json:"phase"`
+	Step           string       `json:"step,omitempty"`
+	Error          string       `json:"error,omitempty"`
+	Output         string       `json:"output,omitempty"`
+	Classification FailureClass `json:"classification,omitempty"`
+}
+
+type FailureClass string
+
+const (
+	FailureTimeout FailureClass = "timeout"
+	FailureConfig  FailureClass = "config"
+	FailureCloud   FailureClass = "cloud"
+)
+
+type ExistingClusterConfig struct {
+	KubeConfigPath string `json:"kube_config_path,omitempty"`
+	ContextName    string `json:"context_name,omitempty"`
 }
 
 type ContextConfig struct {
-	Name          string        `json:"name,omitempty"`
-	Namespace     string        `json:"namespace,omitempty"`
-	ClusterLabels []string      `json:"cluster_labels,omitempty"`
-	NetworkConfig NetworkConfig `json:"network_config,omitempty"`
-	CloudConfig   CloudConfig   `json:"cloud_config,omitempty"`
+	Name            string                 `json:"name,omitempty"`
+	Namespace       string                 `json:"namespace,omitempty"`
+	ClusterLabels   []string               `json:"cluster_labels,omitempty"`
+	NetworkConfig   NetworkConfig          `json:"network_config,omitempty"`
+	CloudConfig     CloudConfig            `json:"cloud_config,omitempty"`
+	ExistingCluster *ExistingClusterConfig `json:"existing_cluster,omitempty"`
 }
 
 type ContextOption struct {
@@ -107,6 +229,13 @@ type ReadinessConfig struct {
 	Contexts                 map[string]ContextConfig `json:"contexts,omitempty"`
 	ServiceAccountNameSuffix string                   `json:"service_account_name_suffix,omitempty"`
 	ExpectedNodeCount        int                      `json:"expected_node_count,omitempty"`
+	NetworkPlan              NetworkPlanConfig        `json:"network_plan,omitempty"`
+	Matrix                   MatrixConfig             `json:"matrix,omitempty"`
+}
+
+type ReadinessFile struct {
+	ProvisionMeta   ProvisionMeta   `json:"provision_meta"`
+	ReadinessConfig ReadinessConfig `json:"readiness_config"`
 }
 
 type ContextServiceAccount struct {
@@ -117,6 +246,17 @@ type ContextServiceAccount struct {
 	Namespace string `json:"namespace" yaml:"namespace,omitempty"`
 }
 
+type ClientAccess struct {
+	Context        string              `json:"context"`
+	Namespace      string              `json:"namespace"`
+	ServiceAccount string              `json:"service_account"`
+	TokenMode      TokenMode           `json:"token_mode,omitempty"`
+	TokenExpiresAt *time.Time          `json:"token_expires_at,omitempty"`
+	RoleKind       string              `json:"role_kind"`
+	RoleName       string              `json:"role_name"`
+	Rules          []rbacv1.PolicyRule `json:"rules"`
+}
+
 type ContextTestManifest struct {
 	Name            string          `json:"name"`
 	ModulesFolder   string          `json:"modules_folder"`
@@ -131,18 +271,187 @@ type ProvisionMeta struct {
 	DefaultConfigPath string            `json:"default_config_path"`
 	DefaultConfigDir  string            `json:"default_config_dir"`
 	AdminIdentity     string            `json:"admin_identity"`
+	Cassette          *CassetteConfig   `json:"cassette,omitempty"`
+}
+
+type CassetteConfig struct {
+	Mode   string `json:"mode"`
+	Path   string `json:"path,omitempty"`
+	Strict bool   `json:"strict,omitempty"`
 }
 
 type EnableConfig struct {
-	Simulate        bool `json:"simulate,omitempty"`
-	RemoveAll       bool `json:"remove_all,omitempty"`
-	Install         bool `json:"install_enabled,omitempty"`
-	ProvisionInfra  bool `json:"provision_enabled,omitempty"`
-	PreInstallSetup bool `json:"pre_install_setup,omitempty"`
+	Simulate        bool    `json:"simulate,omitempty"`
+	RemoveAll       bool    `json:"remove_all,omitempty"`
+	Install         bool    `json:"install_enabled,omitempty"`
+	ProvisionInfra  bool    `json:"provision_enabled,omitempty"`
+	PreInstallSetup bool    `json:"pre_install_setup,omitempty"`
+	Phases          []Phase `json:"phases,omitempty"`
+
+	// Adopt records the unmet prerequisites of the phases as completed for the provision identifier.
+	Adopt bool `json:"adopt,omitempty"`
+}
+
+type Phase string
+
+const (
+	PhaseProvision  Phase = "provision"
+	PhasePreInstall Phase = "pre-install"
+	PhaseInstall    Phase = "install"
+	PhaseValidate   Phase = "validate"
+	PhaseUpgrade    Phase = "upgrade"
+	PhaseDiagnose   Phase = "diagnose"
+	PhaseCleanup    Phase = "cleanup"
+)
+
+type RunLedger struct {
+	ProvisionId      string                   `json:"provision_id"`
+	ArtifactsRootDir string                   `json:"artifacts_root_dir"`
+	Contexts         map[string]LedgerContext `json:"contexts,omitempty"`
+	CompletedPhases  []Phase                  `json:"completed_phases,omitempty"`
+	Errors           []LedgerError            `json:"errors,omitempty"`
+	Checks           []CheckResult            `json:"checks,omitempty"`
+	UpdatedAt        time.Time                `json:"updated_at"`
+}
+
+type LedgerContext struct {
+	ModulesFolder  string `json:"modules_folder,omitempty"`
+	KubeConfigPath string `json:"kube_config_path,omitempty"`
+}
+
+type LedgerError struct {
+	Phase          Phase        `json:"phase"`
+	Context        string       `json:"context,omitempty"`
+	Message        string       `json:"message"`
+	Classification FailureClass `json:"classification,omitempty"`
+	OccurredAt     time.Time    `json:"occurred_at"`
+}
+
+// CheckResult of a post-install validation check, targeting the K8ssandraCluster, a datacenter or a rack.
+type CheckResult struct {
+	Context   string    `json:"context"`
+	Check     string    `json:"check"`
+	Target    string    `json:"target"`
+	Passed    bool      `json:"passed"`
+	Message   string    `json:"message,omitempty"`
+	Duration  string    `json:"duration,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type ExecutionPlan struct {
+	ProvisionId      string        `json:"provision_id,omitempty"`
+	ArtifactsRootDir string        `json:"artifacts_root_dir,omitempty"`
+	Phases           []Phase       `json:"phases"`
+	Actions          []PlanAction  `json:"actions,omitempty"`
+	Contexts         []ContextPlan `json:"contexts"`
+	CreatedAt        time.Time     `json:"created_at"`
+}
+
+type ContextPlan struct {
+	Name            string       `json:"name"`
+	FullContextName string       `json:"full_context_name"`
+	Namespace       string       `json:"namespace"`
+	ControlPlane    bool         `json:"control_plane,omitempty"`
+	ExistingCluster bool         `json:"existing_cluster,omitempty"`
+	Actions         []PlanAction `json:"actions"`
+}
+
+type PlanAction struct {
+	Phase     Phase             `json:"phase"`
+	Kind      PlanActionKind    `json:"kind"`
+	Operation string            `json:"operation"`
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace,omitempty"`
+	Source    string            `json:"source,omitempty"`
+	Version   string            `json:"version,omitempty"`
+	Values    map[string]string `json:"values,omitempty"`
+}
+
+type PlanActionKind string
+
+const (
+	PlanTerraformModule PlanActionKind = "terraform-module"
+	PlanHelmRepository  PlanActionKind = "helm-repository"
+	PlanHelmRelease     PlanActionKind = "helm-release"
+	PlanKubectlApply    PlanActionKind = "kubectl-apply"
+	PlanSecret          PlanActionKind = "secret"
+	PlanClientConfig    PlanActionKind = "client-config"
+	PlanServiceAccount  PlanActionKind = "service-account"
+	PlanRole            PlanActionKind = "role"
+	PlanDeployment      PlanActionKind = "deployment"
+	PlanArtifact        PlanActionKind = "artifact"
+	PlanCheck           PlanActionKind = "check"
+)
+
+type K8ssandraCluster struct {
+	ApiVersion string               `yaml:"apiVersion"`
+	Kind       string               `yaml:"kind"`
+	Metadata   ObjectMeta           `yaml:"metadata"`
+	Spec       K8ssandraClusterSpec `yaml:"spec"`
+}
+
+type K8ssandraClusterSpec struct {
+	Cassandra CassandraClusterSpec `yaml:"cassandra"`
+	Medusa    *MedusaClusterSpec   `yaml:"medusa,omitempty"`
+}
+
+type CassandraClusterSpec struct {
+	ServerVersion string                    `yaml:"serverVersion,omitempty"`
+	Datacenters   []CassandraDatacenterSpec `yaml:"datacenters"`
+}
+
+type CassandraDatacenterSpec struct {
+	Metadata   ObjectMeta      `yaml:"metadata"`
+	K8sContext string          `yaml:"k8sContext"`
+	Size       int             `yaml:"size"`
+	Racks      []CassandraRack `yaml:"racks,omitempty"`
+}
+
+type CassandraRack struct {
+	Name               string            `yaml:"name"`
+	NodeAffinityLabels map[string]string `yaml:"nodeAffinityLabels,omitempty"`
+}
+
+type MedusaClusterSpec struct {
+	StorageProperties MedusaStorageProperties `yaml:"storageProperties"`
+}
+
+type MedusaStorageProperties struct {
+	StorageProvider  string                      `yaml:"storageProvider,omitempty"`
+	StorageSecretRef corev1.LocalObjectReference `yaml:"storageSecretRef"`
+	BucketName       string                      `yaml:"bucketName,omitempty"`
+	Region           string                      `yaml:"region,omitempty"`
+	Host             string                      `yaml:"host,omitempty"`
+	Port             int                         `yaml:"port,omitempty"`
+	Secure           *bool                       `yaml:"secure,omitempty"`
 }
 
 type ObjectMeta struct {
-	Name string `yaml:"name"`
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+type MedusaBackupJob struct {
+	ApiVersion string              `yaml:"apiVersion"`
+	Kind       string              `yaml:"kind"`
+	Metadata   ObjectMeta          `yaml:"metadata"`
+	Spec       MedusaBackupJobSpec `yaml:"spec"`
+}
+
+type MedusaBackupJobSpec struct {
+	CassandraDatacenter string `yaml:"cassandraDatacenter"`
+}
+
+type MedusaRestoreJob struct {
+	ApiVersion string               `yaml:"apiVersion"`
+	Kind       string               `yaml:"kind"`
+	Metadata   ObjectMeta           `yaml:"metadata"`
+	Spec       MedusaRestoreJobSpec `yaml:"spec"`
+}
+
+type MedusaRestoreJobSpec struct {
+	CassandraDatacenter string `yaml:"cassandraDatacenter"`
+	Backup              string `yaml:"backup"`
 }
 
 type ClientConfigSpec struct {
@@ -156,3 +465,34 @@ type ClientConfig struct {
 	Metadata   ObjectMeta       `yaml:"metadata"`
 	Spec       ClientConfigSpec `yaml:"spec"`
 }
+
+// MatrixConfig lists the versions a scenario is expanded across, an empty list keeps the scenario version.
+type MatrixConfig struct {
+	OperatorVersions    []string `json:"operator_versions,omitempty"`
+	CertManagerVersions []string `json:"cert_manager_versions,omitempty"`
+	KubernetesVersions  []string `json:"kubernetes_versions,omitempty"`
+}
+
+// MatrixCell is a combination of versions of the matrix, an empty version being the scenario version.
+type MatrixCell struct {
+	KubernetesVersion  string `json:"kubernetes_version,omitempty"`
+	OperatorVersion    string `json:"operator_version,omitempty"`
+	CertManagerVersion string `json:"cert_manager_version,omitempty"`
+}
+
+type MatrixResult struct {
+	Cell        MatrixCell   `json:"cell"`
+	ProvisionId string       `json:"provision_id"`
+	Status      MatrixStatus `json:"status"`
+	FailedPhase Phase        `json:"failed_phase,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	Duration    string       `json:"duration,omitempty"`
+}
+
+type MatrixStatus string
+
+const (
+	MatrixPassed  MatrixStatus = "passed"
+	MatrixFailed  MatrixStatus = "failed"
+	MatrixSkipped MatrixStatus = "skipped"
+)
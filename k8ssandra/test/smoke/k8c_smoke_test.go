@@ -17,12 +17,41 @@ limitations under the License.
 **/
 
 import (
+	"flag"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
 	. "github.com/k8ssandra/cloud-readiness/k8ssandra/test/testdata/scenario_1"
 	. "github.com/k8ssandra/cloud-readiness/k8ssandra/test/util"
+	"os"
 	"testing"
 )
 
+var readinessConfigFile = flag.String("readiness-config", "",
+	"readiness configuration file, overrides the "+DefaultReadinessConfigKey+" env and the scenario_1 defaults")
+
+var adopt = flag.Bool("adopt", false,
+	"record the unmet prerequisites as completed for the "+DefaultProvisionIdKey+" provision identifier, e.g. of a "+
+		"run provisioned before the run ledger existed")
+
 func TestK8cSmoke(t *testing.T) {
-	meta, config := ReadinessConfig(t, Contexts())
+	var meta model.ProvisionMeta
+	var config model.ReadinessConfig
+	if filePath := readinessConfigPath(); filePath != "" {
+		meta, config = LoadReadinessConfig(t, filePath)
+	} else {
+		meta, config = ReadinessConfig(t, Contexts())
+	}
+	meta.Enable.Adopt = meta.Enable.Adopt || *adopt
+
+	if !meta.Enable.Simulate && IsIdentityRequired(config) && os.Getenv(meta.AdminIdentity) == "" {
+		t.Skipf("applying phases: %v to cloud contexts requires the cloud identity in $%s", ResolvePhases(meta.Enable),
+			meta.AdminIdentity)
+	}
 	Apply(t, meta, config)
 }
+
+func readinessConfigPath() string {
+	if *readinessConfigFile != "" {
+		return *readinessConfigFile
+	}
+	return os.Getenv(DefaultReadinessConfigKey)
+}
@@ -37,7 +37,7 @@ func Contexts() map[string]model.ContextConfig {
 		TraefikVersion:      util.DefaultTraefikVersion,
 		SubnetCidrBlock:     "10.6.32.0/16",
 		SecondaryCidrBlock:  "10.8.32.0/20",
-		MasterIpv4CidrBlock: "10.0.0.0/21",
+		MasterIpv4CidrBlock: "10.0.8.0/21",
 	}
 
 	centralRackConfigs := []model.PoolRackConfig{
@@ -78,6 +78,7 @@ func Contexts() map[string]model.ContextConfig {
 
 	// Cloud specific
 	cloudConfigUsCentral := model.CloudConfig{
+		Type:            "gcp",
 		Project:         "community-ecosystem",
 		Region:          "us-central1",
 		Locations:       []string{"us-central1-a"},
@@ -90,6 +91,7 @@ func Contexts() map[string]model.ContextConfig {
 	}
 
 	cloudConfigUsEast := model.CloudConfig{
+		Type:            "gcp",
 		Project:         "community-ecosystem",
 		Region:          "us-east1",
 		Locations:       []string{"us-east1-b"},
@@ -20,6 +20,7 @@ import (
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
 	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util"
+	"os"
 	"strings"
 	"testing"
 )
@@ -28,18 +29,15 @@ func ReadinessConfig(t *testing.T, contexts map[string]model.ContextConfig) (mod
 
 	configRootDir, configPath := util.FetchKubeConfigPath(t)
 
+	// A complete run, resumed from the ledger of the provision identifier when given.
 	var enablement = model.EnableConfig{
-		Simulate:        false,
-		RemoveAll:       false,
-		Install:         false,
-		ProvisionInfra:  false,
-		PreInstallSetup: true,
+		Simulate: false,
+		Phases:   []model.Phase{model.PhaseProvision, model.PhasePreInstall, model.PhaseInstall, model.PhaseValidate},
 	}
 
 	var provisionMeta = model.ProvisionMeta{
 		Enable:            enablement,
-		ProvisionId:       "k8c-whwimk",
-		ArtifactsRootDir:  "/tmp/cloud-k8c-whwimk",
+		ProvisionId:       os.Getenv(util.DefaultProvisionIdKey),
 		KubeConfigs:       nil,
 		DefaultConfigPath: configPath,
 		DefaultConfigDir:  configRootDir,
@@ -48,7 +46,7 @@ func ReadinessConfig(t *testing.T, contexts map[string]model.ContextConfig) (mod
 
 	k8cConfig := model.K8cConfig{
 		ClusterName:             "bootz-k8c-cluster",
-		ValuesFilePath:          "k8c-multi-dc.yaml",
+		OverlayFilePath:         "k8c-multi-dc-overlay.yaml",
 		MedusaSecretName:        "dev-k8ssandra-medusa-key",
 		MedusaSecretFromFileKey: "medusa_gcp_key",
 		MedusaSecretFromFile:    "medusa_gcp_key.json",
@@ -60,7 +58,7 @@ func ReadinessConfig(t *testing.T, contexts map[string]model.ContextConfig) (mod
 	}
 
 	helmConfig := model.HelmConfig{
-		ChartPath: "k8ssandra/k8ssandra",
+		ChartPath: "k8ssandra/k8ssandra-operator",
 	}
 
 	provisionConfig := model.ProvisionConfig{
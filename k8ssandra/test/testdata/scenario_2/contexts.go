@@ -0,0 +1,61 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package scenario_2
+
+import (
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util"
+)
+
+// Contexts references two existing kind clusters, no cloud provisioning is performed.
+func Contexts() map[string]model.ContextConfig {
+
+	networkConfig0 := model.NetworkConfig{
+		TraefikValuesFile: "k8c-traefik-bootz000.yaml",
+		TraefikVersion:    util.DefaultTraefikVersion,
+	}
+
+	networkConfig1 := model.NetworkConfig{
+		TraefikValuesFile: "k8c-traefik-bootz001.yaml",
+		TraefikVersion:    util.DefaultTraefikVersion,
+	}
+
+	ctxConfig1 := model.ContextConfig{
+		Name:          "k8ssandra-0",
+		Namespace:     "bootz",
+		ClusterLabels: []string{"control-plane", "data-plane"},
+		NetworkConfig: networkConfig0,
+		ExistingCluster: &model.ExistingClusterConfig{
+			ContextName: "kind-k8ssandra-0",
+		},
+	}
+
+	ctxConfig2 := model.ContextConfig{
+		Name:          "k8ssandra-1",
+		Namespace:     "bootz",
+		ClusterLabels: []string{"data-plane"},
+		NetworkConfig: networkConfig1,
+		ExistingCluster: &model.ExistingClusterConfig{
+			ContextName: "kind-k8ssandra-1",
+		},
+	}
+
+	return map[string]model.ContextConfig{
+		ctxConfig1.Name: ctxConfig1,
+		ctxConfig2.Name: ctxConfig2,
+	}
+}
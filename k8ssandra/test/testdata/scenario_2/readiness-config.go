@@ -0,0 +1,68 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package scenario_2
+
+import (
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util"
+	"path"
+	"strings"
+	"testing"
+)
+
+func ReadinessConfig(t *testing.T, contexts map[string]model.ContextConfig) (model.ProvisionMeta, model.ReadinessConfig) {
+
+	configRootDir, configPath := util.FetchKubeConfigPath(t)
+	provisionId := strings.ToLower(random.UniqueId())
+
+	var enablement = model.EnableConfig{
+		Simulate: false,
+		Install:  true,
+	}
+
+	var provisionMeta = model.ProvisionMeta{
+		Enable:            enablement,
+		ProvisionId:       provisionId,
+		ArtifactsRootDir:  path.Join("/tmp", "cloud-k8c-"+provisionId),
+		DefaultConfigPath: configPath,
+		DefaultConfigDir:  configRootDir,
+		AdminIdentity:     util.DefaultAdminIdentifier,
+	}
+
+	k8cConfig := model.K8cConfig{
+		ClusterName:     "bootz-k8c-cluster",
+		OverlayFilePath: "k8c-multi-dc-overlay.yaml",
+		ClusterScoped:   false,
+	}
+
+	provisionConfig := model.ProvisionConfig{
+		K8cConfig:          k8cConfig,
+		DefaultSleepSecs:   20,
+		DefaultRetries:     30,
+		DefaultTimeoutSecs: 240,
+	}
+
+	readinessConfig := model.ReadinessConfig{
+		UniqueId:          strings.ToLower(random.UniqueId()),
+		Contexts:          contexts,
+		ExpectedNodeCount: 1,
+		ProvisionConfig:   provisionConfig,
+	}
+
+	return provisionMeta, readinessConfig
+}
@@ -0,0 +1,172 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"path"
+	"sort"
+)
+
+const (
+	defaultClientServiceAccountName = "k8ssandra-client"
+	defaultClientAccessFileName     = "client-access.json"
+)
+
+var (
+	clientAccessWriteVerbs = []string{"get", "list", "watch", "create", "update", "patch", "delete"}
+	clientAccessReadVerbs  = []string{"get", "list", "watch"}
+)
+
+// clientAccessRules are the rules needed by a control-plane k8ssandra-operator to manage the resources of a
+// remote data-plane.
+var clientAccessRules = []rbacv1.PolicyRule{
+	{APIGroups: []string{""}, Resources: []string{"secrets", "configmaps", "services", "endpoints"},
+		Verbs: clientAccessWriteVerbs},
+	{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: clientAccessReadVerbs},
+	{APIGroups: []string{"apps"}, Resources: []string{"statefulsets"}, Verbs: clientAccessReadVerbs},
+	{APIGroups: []string{"cassandra.datastax.com"}, Resources: []string{"cassandradatacenters"},
+		Verbs: clientAccessWriteVerbs},
+	{APIGroups: []string{"stargate.k8ssandra.io"}, Resources: []string{"stargates"}, Verbs: clientAccessWriteVerbs},
+	{APIGroups: []string{"reaper.k8ssandra.io"}, Resources: []string{"reapers"}, Verbs: clientAccessWriteVerbs},
+	{APIGroups: []string{"medusa.k8ssandra.io"}, Resources: []string{"medusabackupjobs", "medusabackupjobs/status",
+		"medusarestorejobs", "medusarestorejobs/status", "medusabackups", "medusabackups/status"},
+		Verbs: clientAccessWriteVerbs},
+}
+
+// CreateClientAccess creates the dedicated service account of the client configurations, bound to the minimal
+// rules in the namespace, or cluster wide for a cluster scoped operator.
+func CreateClientAccess(t testing.TestingT, options *k8s.KubectlOptions, namespace string,
+	isClusterScoped bool) model.ClientAccess {
+
+	client := KubeClient(t, options)
+	access := model.ClientAccess{
+		Context:        options.ContextName,
+		Namespace:      namespace,
+		ServiceAccount: defaultClientServiceAccountName,
+		RoleKind:       "Role",
+		RoleName:       defaultClientServiceAccountName,
+		Rules:          clientAccessRules,
+	}
+
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: access.ServiceAccount, Namespace: namespace},
+	}
+	_, err := client.CoreV1().ServiceAccounts(namespace).Create(context.Background(), serviceAccount,
+		metav1.CreateOptions{})
+	if !apierrors.IsAlreadyExists(err) {
+		require.NoError(t, err, fmt.Sprintf("expecting service account: %s to be created", access.ServiceAccount))
+	}
+
+	subjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: access.ServiceAccount, Namespace: namespace}}
+
+	if isClusterScoped {
+		// Named by namespace, as cluster wide roles are shared by every namespace.
+		access.RoleKind = "ClusterRole"
+		access.RoleName = defaultClientServiceAccountName + "-" + namespace
+
+		roles := client.RbacV1().ClusterRoles()
+		role := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: access.RoleName}, Rules: access.Rules}
+		if _, err = roles.Create(context.Background(), role, metav1.CreateOptions{}); apierrors.IsAlreadyExists(err) {
+			_, err = roles.Update(context.Background(), role, metav1.UpdateOptions{})
+		}
+		require.NoError(t, err, fmt.Sprintf("expecting cluster role: %s to be applied", access.RoleName))
+
+		bindings := client.RbacV1().ClusterRoleBindings()
+		binding := &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: access.RoleName},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: access.RoleKind, Name: access.RoleName},
+			Subjects:   subjects,
+		}
+		if _, err = bindings.Create(context.Background(), binding, metav1.CreateOptions{}); apierrors.IsAlreadyExists(err) {
+			_, err = bindings.Update(context.Background(), binding, metav1.UpdateOptions{})
+		}
+		require.NoError(t, err, fmt.Sprintf("expecting cluster role binding: %s to be applied", access.RoleName))
+	} else {
+		roles := client.RbacV1().Roles(namespace)
+		role := &rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: access.RoleName, Namespace: namespace},
+			Rules: access.Rules}
+		if _, err = roles.Create(context.Background(), role, metav1.CreateOptions{}); apierrors.IsAlreadyExists(err) {
+			_, err = roles.Update(context.Background(), role, metav1.UpdateOptions{})
+		}
+		require.NoError(t, err, fmt.Sprintf("expecting role: %s to be applied", access.RoleName))
+
+		bindings := client.RbacV1().RoleBindings(namespace)
+		binding := &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: access.RoleName, Namespace: namespace},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: access.RoleKind, Name: access.RoleName},
+			Subjects:   subjects,
+		}
+		if _, err = bindings.Create(context.Background(), binding, metav1.CreateOptions{}); apierrors.IsAlreadyExists(err) {
+			_, err = bindings.Update(context.Background(), binding, metav1.UpdateOptions{})
+		}
+		require.NoError(t, err, fmt.Sprintf("expecting role binding: %s to be applied", access.RoleName))
+	}
+
+	logger.Log(t, fmt.Sprintf("client access: %s %s granted to service account: %s in namespace: %s",
+		access.RoleKind, access.RoleName, access.ServiceAccount, namespace))
+	return access
+}
+
+// WriteClientAccess records the access granted in every context to the artifacts root, providing the file path.
+func WriteClientAccess(meta model.ProvisionMeta, accesses []model.ClientAccess) (string, error) {
+	if meta.ArtifactsRootDir == "" {
+		return "", errors.New("an artifacts root directory is required to record the client access")
+	}
+	if err := os.MkdirAll(meta.ArtifactsRootDir, defaultTempFilePerm); err != nil {
+		return "", err
+	}
+
+	sort.Slice(accesses, func(i, j int) bool {
+		return accesses[i].Context < accesses[j].Context
+	})
+	content, err := json.MarshalIndent(accesses, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	accessPath := path.Join(meta.ArtifactsRootDir, defaultClientAccessFileName)
+	return accessPath, ioutil.WriteFile(accessPath, content, defaultTempFilePerm)
+}
+
+// ReadClientAccess reads the access granted in every context, as recorded to the artifacts root.
+func ReadClientAccess(meta model.ProvisionMeta) ([]model.ClientAccess, error) {
+	if meta.ArtifactsRootDir == "" {
+		return nil, errors.New("an artifacts root directory is required to read the client access")
+	}
+	content, err := ioutil.ReadFile(path.Join(meta.ArtifactsRootDir, defaultClientAccessFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var accesses []model.ClientAccess
+	return accesses, json.Unmarshal(content, &accesses)
+}
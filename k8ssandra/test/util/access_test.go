@@ -0,0 +1,104 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestCreateClientAccess(t *testing.T) {
+	client := useFakeClient(t)
+	options := k8s.NewKubectlOptions("kind-k8ssandra-1", "", "bootz")
+
+	CreateClientAccess(t, options, "bootz", false)
+	access := CreateClientAccess(t, options, "bootz", false)
+	require.Equal(t, "Role", access.RoleKind)
+	require.Equal(t, "kind-k8ssandra-1", access.Context)
+
+	_, err := client.CoreV1().ServiceAccounts("bootz").Get(context.Background(), defaultClientServiceAccountName,
+		metav1.GetOptions{})
+	require.NoError(t, err)
+
+	role, err := client.RbacV1().Roles("bootz").Get(context.Background(), access.RoleName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, clientAccessRules, role.Rules)
+
+	binding, err := client.RbacV1().RoleBindings("bootz").Get(context.Background(), access.RoleName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, defaultClientServiceAccountName, binding.Subjects[0].Name)
+	require.Equal(t, "Role", binding.RoleRef.Kind)
+
+	clusterRoles, err := client.RbacV1().ClusterRoles().List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, clusterRoles.Items, "expecting no cluster wide access for a namespace scoped operator")
+}
+
+func TestCreateClientAccessClusterScoped(t *testing.T) {
+	client := useFakeClient(t)
+
+	access := CreateClientAccess(t, k8s.NewKubectlOptions("kind-k8ssandra-1", "", "bootz"), "bootz", true)
+	require.Equal(t, "ClusterRole", access.RoleKind)
+	require.Equal(t, "k8ssandra-client-bootz", access.RoleName)
+
+	binding, err := client.RbacV1().ClusterRoleBindings().Get(context.Background(), access.RoleName,
+		metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "bootz", binding.Subjects[0].Namespace)
+	require.Equal(t, "ClusterRole", binding.RoleRef.Kind)
+}
+
+func TestWriteClientAccess(t *testing.T) {
+	meta := model.ProvisionMeta{ArtifactsRootDir: t.TempDir()}
+	accesses := []model.ClientAccess{
+		{Context: "kind-k8ssandra-1", Namespace: "bootz", RoleKind: "Role", Rules: clientAccessRules},
+		{Context: "kind-k8ssandra-0", Namespace: "bootz", RoleKind: "Role", Rules: clientAccessRules},
+	}
+
+	accessPath, err := WriteClientAccess(meta, accesses)
+	require.NoError(t, err)
+
+	content, err := ioutil.ReadFile(accessPath)
+	require.NoError(t, err)
+	var written []model.ClientAccess
+	require.NoError(t, json.Unmarshal(content, &written))
+	require.Equal(t, "kind-k8ssandra-0", written[0].Context, "expecting the access ordered by context")
+	require.Equal(t, clientAccessRules, written[1].Rules)
+
+	_, err = WriteClientAccess(model.ProvisionMeta{}, accesses)
+	require.Error(t, err)
+}
+
+func TestClientAccessRulesGrantMedusaJobs(t *testing.T) {
+	granted := map[string][]string{}
+	for _, rule := range clientAccessRules {
+		for _, group := range rule.APIGroups {
+			granted[group] = append(granted[group], rule.Resources...)
+		}
+	}
+
+	for _, resource := range []string{"medusabackupjobs", "medusarestorejobs", "medusabackups"} {
+		require.Contains(t, granted["medusa.k8ssandra.io"], resource)
+	}
+	require.Contains(t, defaultMedusaApiVersion, "medusa.k8ssandra.io/")
+}
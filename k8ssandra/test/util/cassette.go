@@ -0,0 +1,96 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"github.com/stretchr/testify/require"
+	"path"
+)
+
+const (
+	CassetteRecordMode      = "record"
+	CassetteReplayMode      = "replay"
+	defaultCassetteFileName = "cassette.json"
+)
+
+// UseCassette routes the external commands and Kubernetes API requests through a recorder or replayer when a
+// cassette is configured, providing t carrying it and the function completing the cassette, saving a recording.
+func UseCassette(t testing.TestingT, meta model.ProvisionMeta) (testing.TestingT, func()) {
+	config := meta.Cassette
+	if config == nil || config.Mode == "" {
+		return t, func() {}
+	}
+
+	cassettePath := CassettePath(meta)
+	require.NotEmpty(t, cassettePath, "expecting a cassette path or provision identifier for the cassette")
+
+	switch config.Mode {
+	case CassetteRecordMode:
+		logger.Log(t, fmt.Sprintf("recording external commands to cassette: %s", cassettePath))
+		recorder := executor.NewRecorder(executor.For(t))
+		return executor.With(t, recorder), func() {
+			cassette := recorder.Cassette()
+			require.NoError(t, cassette.Save(cassettePath), fmt.Sprintf("unable to save cassette: %s", cassettePath))
+			logger.Log(t, fmt.Sprintf("recorded %d external commands and %d API requests to cassette: %s",
+				len(cassette.Interactions), len(cassette.Requests), cassettePath))
+		}
+
+	case CassetteReplayMode:
+		logger.Log(t, fmt.Sprintf("replaying external commands from cassette: %s", cassettePath))
+		cassette, err := executor.LoadCassette(cassettePath)
+		require.NoError(t, err, fmt.Sprintf("unable to load cassette: %s", cassettePath))
+
+		replayer := executor.NewReplayer(cassette)
+		replayer.Strict = config.Strict
+		return executor.With(t, replayer), func() {
+			if remaining := replayer.Remaining(); len(remaining) > 0 {
+				logger.Log(t, fmt.Sprintf("WARNING: %d recorded commands were not replayed, the first: %s",
+					len(remaining), remaining[0].Command))
+			}
+			if remaining := replayer.RemainingRequests(); len(remaining) > 0 {
+				logger.Log(t, fmt.Sprintf("WARNING: %d recorded API requests were not replayed, the first: %s",
+					len(remaining), remaining[0]))
+			}
+		}
+
+	default:
+		require.FailNow(t, fmt.Sprintf("unknown cassette mode: %s, expecting %s or %s", config.Mode,
+			CassetteRecordMode, CassetteReplayMode))
+		return t, func() {}
+	}
+}
+
+// CassettePath provides the configured cassette path, defaulting to the artifacts root of the provisioning.
+func CassettePath(meta model.ProvisionMeta) string {
+	if meta.Cassette != nil && meta.Cassette.Path != "" {
+		return meta.Cassette.Path
+	}
+
+	artifactsRootDir := meta.ArtifactsRootDir
+	if artifactsRootDir == "" && meta.ProvisionId != "" {
+		artifactsRootDir = DefaultArtifactsRootDir(meta.ProvisionId)
+	}
+	if artifactsRootDir == "" {
+		return ""
+	}
+	return path.Join(artifactsRootDir, defaultCassetteFileName)
+}
@@ -0,0 +1,116 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestCassettePath(t *testing.T) {
+	require.Empty(t, CassettePath(model.ProvisionMeta{}))
+	require.Equal(t, path.Join(DefaultArtifactsRootDir("k8c-test"), "cassette.json"),
+		CassettePath(model.ProvisionMeta{ProvisionId: "k8c-test"}))
+	require.Equal(t, "/tmp/recorded.json", CassettePath(model.ProvisionMeta{ProvisionId: "k8c-test",
+		Cassette: &model.CassetteConfig{Mode: CassetteReplayMode, Path: "/tmp/recorded.json"}}))
+}
+
+func TestCassetteRecordAndReplayDiagnostics(t *testing.T) {
+	t.Setenv(DefaultAdminIdentifier, "admin")
+	contexts := validContexts()
+	delete(contexts, "central")
+	config := model.ReadinessConfig{Contexts: contexts}
+
+	cassettePath := path.Join(t.TempDir(), "cassette.json")
+	meta := model.ProvisionMeta{ArtifactsRootDir: t.TempDir(), AdminIdentity: DefaultAdminIdentifier,
+		DefaultConfigPath: "/home/tester/.kube/config",
+		Cassette:          &model.CassetteConfig{Mode: CassetteRecordMode, Path: cassettePath}}
+
+	fake := executor.NewFake().Respond(executor.Kubectl, []string{"get", "pods"}, "k8ssandra-operator-0 Running", nil)
+	recording, finishRecording := UseCassette(executor.With(t, fake), meta)
+	CollectDiagnostics(recording, meta, config)
+	finishRecording()
+
+	recorded, err := executor.LoadCassette(cassettePath)
+	require.NoError(t, err)
+	require.Len(t, recorded.Interactions, len(fake.Commands()))
+
+	// Replayed offline, without the fake, into a new artifacts directory.
+	meta.ArtifactsRootDir = t.TempDir()
+	meta.Cassette = &model.CassetteConfig{Mode: CassetteReplayMode, Path: cassettePath, Strict: true}
+	replaying, finishReplaying := UseCassette(t, meta)
+	CollectDiagnostics(replaying, meta, config)
+	finishReplaying()
+
+	report, err := os.ReadFile(path.Join(meta.ArtifactsRootDir, defaultDiagnosticsFolder, "kind.log"))
+	require.NoError(t, err)
+	require.Contains(t, string(report), "k8ssandra-operator-0 Running")
+}
+
+func TestCassetteRecordAndReplayApiRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		require.Equal(t, "/api/v1/namespaces/k8ssandra-operator/secrets/sa-token", request.URL.Path)
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"sa-token"},` +
+			`"data":{"token":"czNjcjN0"}}`))
+	}))
+
+	configPath := path.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(configPath, []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: kind
+  cluster:
+    server: %s
+contexts:
+- name: kind-k8ssandra-0
+  context:
+    cluster: kind
+    user: admin
+users:
+- name: admin
+  user:
+    token: admin-token
+`, server.URL)), 0600))
+	options := k8s.NewKubectlOptions("kind-k8ssandra-0", configPath, "k8ssandra-operator")
+
+	cassettePath := path.Join(t.TempDir(), "cassette.json")
+	meta := model.ProvisionMeta{Cassette: &model.CassetteConfig{Mode: CassetteRecordMode, Path: cassettePath}}
+	recording, finishRecording := UseCassette(t, meta)
+	require.Equal(t, "s3cr3t", FetchToken(recording, options, "sa-token", "k8ssandra-operator"))
+	finishRecording()
+	server.Close()
+
+	content, err := os.ReadFile(cassettePath)
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "czNjcjN0", "expecting the secret data to be redacted")
+
+	// Replayed with the API server gone and the kube config removed.
+	require.NoError(t, os.Remove(configPath))
+	meta.Cassette = &model.CassetteConfig{Mode: CassetteReplayMode, Path: cassettePath, Strict: true}
+	replaying, finishReplaying := UseCassette(t, meta)
+	defer finishReplaying()
+	require.Equal(t, "REDACTED", FetchToken(replaying, options, "sa-token", "k8ssandra-operator"))
+}
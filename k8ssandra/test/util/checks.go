@@ -0,0 +1,370 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	CheckK8ssandraClusterReady = "k8ssandra-cluster-ready"
+	CheckDatacenterReady       = "datacenter-ready"
+	CheckRackPods              = "rack-pods"
+	CheckNodetoolStatus        = "nodetool-status"
+
+	defaultK8ssandraClusterCondition = "CassandraInitialized"
+	defaultDatacenterCondition       = "Ready"
+	defaultCassandraContainer        = "cassandra"
+	defaultCassandraRack             = "default"
+	cassandraRackLabel               = "cassandra.datastax.com/rack"
+	nodetoolUpNormal                 = "UN"
+)
+
+// cassandraDatacenter is the part of a CassandraDatacenter resource the checks rely on.
+type cassandraDatacenter struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		Size  int `json:"size"`
+		Racks []struct {
+			Name string `json:"name"`
+		} `json:"racks"`
+	} `json:"spec"`
+}
+
+// datacenterTarget references the context and pod a datacenter is checked from with nodetool.
+type datacenterTarget struct {
+	contextName string
+	namespace   string
+	kubeConfig  *k8s.KubectlOptions
+	podName     string
+}
+
+// ValidateK8ssandraCluster checks the K8ssandraCluster of the control-plane and every CassandraDatacenter are
+// ready, the ready Cassandra pods of every rack, that every datacenter sees all the nodes as up and normal, and
+// that rows written through a datacenter are read back from every other datacenter. When Medusa is configured,
+// every datacenter is backed up and restored. The requested tokens of the client access are expected to outlast
+// the checks.
+func ValidateK8ssandraCluster(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) []model.CheckResult {
+
+	identity := FetchEnv(t, meta.AdminIdentity)
+	timeoutSecs := valueOrDefault(readinessConfig.ProvisionConfig.DefaultTimeoutSecs, defaultTimeoutSecs)
+	clusterName := readinessConfig.ProvisionConfig.K8cConfig.ClusterName
+
+	var results []model.CheckResult
+	if !meta.Enable.Simulate {
+		results = append(results, checkClientTokens(meta, timeoutSecs)...)
+	}
+
+	var sizes = map[string]int{}
+	var targets = map[string]datacenterTarget{}
+	var datacenterNames []string
+
+	for _, name := range sortedContextNames(readinessConfig) {
+		ctx := readinessConfig.Contexts[name]
+		if meta.Enable.Simulate {
+			logger.Log(t, fmt.Sprintf("SIMULATE validation of the K8ssandraCluster datacenters for: %s", name))
+			continue
+		}
+
+		kubeConfig := ConnectContext(t, meta, identity, name, ctx)
+		if IsControlPlane(ctx) {
+			results = append(results, waitForCondition(t, kubeConfig, name, CheckK8ssandraClusterReady,
+				"k8ssandracluster/"+clusterName, ctx.Namespace, defaultK8ssandraClusterCondition, timeoutSecs))
+		}
+
+		datacenters, err := fetchDatacenters(t, kubeConfig, ctx.Namespace)
+		if err == nil && len(datacenters) == 0 {
+			err = fmt.Errorf("no CassandraDatacenter found in namespace: %s", ctx.Namespace)
+		}
+		if err != nil {
+			results = append(results, checkResult(name, CheckDatacenterReady, ctx.Namespace, false, err.Error()))
+			continue
+		}
+
+		for _, datacenter := range datacenters {
+			dcName := datacenter.Metadata.Name
+			results = append(results, waitForCondition(t, kubeConfig, name, CheckDatacenterReady,
+				"cassandradatacenter/"+dcName, ctx.Namespace, defaultDatacenterCondition, timeoutSecs))
+
+			readyPods, err := fetchReadyRackPods(t, kubeConfig, ctx.Namespace, dcName)
+			if err != nil {
+				results = append(results, checkResult(name, CheckRackPods, dcName, false, err.Error()))
+				continue
+			}
+			results = append(results, evaluateRackPods(name, ctx, datacenter, readyPods,
+				readinessConfig.ExpectedNodeCount)...)
+
+			sizes[dcName] = datacenter.Spec.Size
+			datacenterNames = append(datacenterNames, dcName)
+			target := datacenterTarget{contextName: name, namespace: ctx.Namespace, kubeConfig: kubeConfig}
+			for _, rack := range sortedKeys(readyPods) {
+				if len(readyPods[rack]) > 0 {
+					target.podName = readyPods[rack][0]
+					break
+				}
+			}
+			targets[dcName] = target
+		}
+	}
+
+	for _, dcName := range datacenterNames {
+		target := targets[dcName]
+		if target.podName == "" {
+			results = append(results, checkResult(target.contextName, CheckNodetoolStatus, dcName, false,
+				"no ready Cassandra pod to run nodetool from"))
+			continue
+		}
+		out, err := executor.RunKubectl(t, target.kubeConfig, "exec", target.podName, "-n", target.namespace,
+			"-c", defaultCassandraContainer, "--", "nodetool", "status")
+		if err != nil {
+			results = append(results, checkResult(target.contextName, CheckNodetoolStatus, dcName, false,
+				fmt.Sprintf("nodetool status failed on pod: %s, %s", target.podName, err.Error())))
+			continue
+		}
+		results = append(results, evaluateNodetoolStatus(target.contextName, dcName, out, sizes))
+	}
+
+	if len(datacenterNames) > 0 && hasTargetPods(targets) {
+		results = append(results, checkCqlConsistency(t, clusterName, datacenterNames, targets, sizes,
+			timeoutSecs)...)
+		if readinessConfig.ProvisionConfig.K8cConfig.MedusaSecretName != "" {
+			results = append(results, checkMedusaBackupRestore(t, meta, clusterName, datacenterNames, targets, sizes,
+				timeoutSecs)...)
+		}
+	}
+	return results
+}
+
+// hasTargetPods expects a ready pod in every datacenter, any other is already reported by the nodetool check.
+func hasTargetPods(targets map[string]datacenterTarget) bool {
+	for _, target := range targets {
+		if target.podName == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// RequireChecksPassed logs every check result and fails when any check failed.
+func RequireChecksPassed(t testing.TestingT, results []model.CheckResult) {
+	var failed []string
+	for _, result := range results {
+		if result.Passed {
+			logger.Log(t, fmt.Sprintf("check: %s of: %s for: %s passed %s", result.Check, result.Target,
+				result.Context, result.Message))
+			continue
+		}
+		logger.Log(t, fmt.Sprintf("WARNING: check: %s of: %s for: %s FAILED, %s", result.Check, result.Target,
+			result.Context, result.Message))
+		failed = append(failed, result.Check+"/"+result.Target)
+	}
+	require.Empty(t, failed, "expecting every post-install check to pass")
+}
+
+// RecordCheckResults replaces the check results of the run ledger.
+func RecordCheckResults(t testing.TestingT, meta model.ProvisionMeta, results []model.CheckResult) {
+	UpdateLedger(t, meta, func(ledger *model.RunLedger) {
+		ledger.Checks = results
+	})
+}
+
+func checkResult(contextName string, check string, target string, passed bool, message string) model.CheckResult {
+	return model.CheckResult{Context: contextName, Check: check, Target: target, Passed: passed, Message: message,
+		CheckedAt: time.Now().UTC()}
+}
+
+// waitForCondition waits for the condition of the resource to be true, up to the timeout.
+func waitForCondition(t testing.TestingT, kubeConfig *k8s.KubectlOptions, contextName string, check string,
+	resource string, namespace string, condition string, timeoutSecs int) model.CheckResult {
+
+	out, err := executor.RunKubectl(t, kubeConfig, "wait", "--for=condition="+condition, resource,
+		"-n", namespace, fmt.Sprintf("--timeout=%ds", timeoutSecs))
+	if err != nil {
+		return checkResult(contextName, check, resource, false,
+			fmt.Sprintf("condition: %s not met, %s", condition, err.Error()))
+	}
+	return checkResult(contextName, check, resource, true, strings.TrimSpace(out))
+}
+
+func fetchDatacenters(t testing.TestingT, kubeConfig *k8s.KubectlOptions, namespace string) ([]cassandraDatacenter, error) {
+	out, err := executor.RunKubectl(t, kubeConfig, "get", "cassandradatacenters", "-n", namespace, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		Items []cassandraDatacenter `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(out), &list); err != nil {
+		return nil, fmt.Errorf("unable to parse the CassandraDatacenters of namespace: %s, %w", namespace, err)
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Metadata.Name < list.Items[j].Metadata.Name
+	})
+	return list.Items, nil
+}
+
+// fetchReadyRackPods provides the names of the ready Cassandra pods of the datacenter, by rack.
+func fetchReadyRackPods(t testing.TestingT, kubeConfig *k8s.KubectlOptions, namespace string,
+	dcName string) (map[string][]string, error) {
+
+	pods, err := KubeClient(t, kubeConfig).CoreV1().Pods(namespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: cassandraDatacenterLabel + "=" + dcName})
+	if err != nil {
+		return nil, err
+	}
+
+	var readyPods = map[string][]string{}
+	for _, pod := range pods.Items {
+		if isPodReady(pod) {
+			rack := pod.Labels[cassandraRackLabel]
+			readyPods[rack] = append(readyPods[rack], pod.Name)
+		}
+	}
+	for rack := range readyPods {
+		sort.Strings(readyPods[rack])
+	}
+	return readyPods, nil
+}
+
+func isPodReady(pod corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// evaluateRackPods expects the ready pods of every rack to match the datacenter size split across its racks, the
+// racks of a provisioned context to be its pools, and a rack not to need more pods than the nodes of its pool.
+func evaluateRackPods(contextName string, ctx model.ContextConfig, datacenter cassandraDatacenter,
+	readyPods map[string][]string, expectedNodeCount int) []model.CheckResult {
+
+	dcName := datacenter.Metadata.Name
+	var rackNames []string
+	for _, rack := range datacenter.Spec.Racks {
+		rackNames = append(rackNames, rack.Name)
+	}
+	if len(rackNames) == 0 {
+		rackNames = []string{defaultCassandraRack}
+	}
+
+	var pools = map[string]bool{}
+	for _, pool := range ctx.CloudConfig.PoolRackConfigs {
+		pools[pool.Name] = true
+	}
+	isPoolChecked := !IsExistingCluster(ctx) && len(pools) > 0
+
+	var results []model.CheckResult
+	for index, rack := range rackNames {
+		target := dcName + "/" + rack
+		expected := datacenter.Spec.Size / len(rackNames)
+		if index < datacenter.Spec.Size%len(rackNames) {
+			expected++
+		}
+
+		switch {
+		case isPoolChecked && !pools[rack]:
+			results = append(results, checkResult(contextName, CheckRackPods, target, false,
+				"rack is not a pool of the context"))
+		case isPoolChecked && expectedNodeCount > 0 && expected > expectedNodeCount:
+			results = append(results, checkResult(contextName, CheckRackPods, target, false,
+				fmt.Sprintf("expecting: %d pods, exceeding the expected node count: %d of the pool",
+					expected, expectedNodeCount)))
+		default:
+			ready := len(readyPods[rack])
+			results = append(results, checkResult(contextName, CheckRackPods, target, ready == expected,
+				fmt.Sprintf("ready pods: %d of expected: %d", ready, expected)))
+		}
+		delete(pools, rack)
+	}
+
+	if isPoolChecked {
+		for _, pool := range sortedKeys(pools) {
+			results = append(results, checkResult(contextName, CheckRackPods, dcName+"/"+pool, false,
+				"pool of the context is not a rack of the datacenter"))
+		}
+	}
+	return results
+}
+
+// parseNodetoolStatus provides the state of the nodes listed by nodetool status, by datacenter.
+func parseNodetoolStatus(out string) map[string][]string {
+	var states = map[string][]string{}
+	var datacenter string
+
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "Datacenter:") {
+			datacenter = strings.TrimSpace(strings.TrimPrefix(line, "Datacenter:"))
+			continue
+		}
+		fields := strings.Fields(line)
+		if datacenter == "" || len(fields) < 2 || len(fields[0]) != 2 || !strings.ContainsAny(fields[0][:1], "UD") {
+			continue
+		}
+		states[datacenter] = append(states[datacenter], fields[0])
+	}
+	return states
+}
+
+// evaluateNodetoolStatus expects the nodetool status of the datacenter to list every node of every datacenter
+// as up and normal.
+func evaluateNodetoolStatus(contextName string, dcName string, out string, sizes map[string]int) model.CheckResult {
+	states := parseNodetoolStatus(out)
+
+	var problems []string
+	for _, name := range sortedKeys(sizes) {
+		var upNormal int
+		for _, state := range states[name] {
+			if state == nodetoolUpNormal {
+				upNormal++
+			}
+		}
+		if upNormal != sizes[name] || len(states[name]) != sizes[name] {
+			problems = append(problems, fmt.Sprintf("%s: %d %s of %d nodes, expecting %d", name, upNormal,
+				nodetoolUpNormal, len(states[name]), sizes[name]))
+		}
+	}
+
+	if len(problems) > 0 {
+		return checkResult(contextName, CheckNodetoolStatus, dcName, false, strings.Join(problems, ", "))
+	}
+	return checkResult(contextName, CheckNodetoolStatus, dcName, true,
+		fmt.Sprintf("every node of %d datacenters is %s", len(sizes), nodetoolUpNormal))
+}
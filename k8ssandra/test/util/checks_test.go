@@ -0,0 +1,164 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"errors"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+const nodetoolOutput = `Datacenter: central
+===================
+Status=Up/Down
+|/ State=Normal/Leaving/Joining/Moving
+--  Address     Load       Tokens  Owns   Host ID                               Rack
+UN  10.1.0.12   96.46 KiB  16      ?      1c2d5e8a-45b7-4d1c-9f0e-5a9e1f3f0c11  rack1
+UN  10.1.0.13   91.02 KiB  16      ?      8e7d6c5b-2a1f-4e3d-8c7b-6a5f4e3d2c1b  rack2
+
+Datacenter: kind
+================
+Status=Up/Down
+|/ State=Normal/Leaving/Joining/Moving
+--  Address     Load       Tokens  Owns   Host ID                               Rack
+UN  10.244.0.7  88.31 KiB  16      ?      3f4e5d6c-7b8a-4c9d-8e1f-2a3b4c5d6e7f  r1
+DN  10.244.0.8  ?          16      ?      9a8b7c6d-5e4f-4a3b-9c2d-1e0f9a8b7c6d  r1
+`
+
+func cassandraPod(name string, rack string, isReady bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if isReady {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "bootz",
+			Labels: map[string]string{cassandraDatacenterLabel: "kind", cassandraRackLabel: rack}},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}},
+	}
+}
+
+func datacenter(name string, size int, racks ...string) cassandraDatacenter {
+	var dc cassandraDatacenter
+	dc.Metadata.Name = name
+	dc.Spec.Size = size
+	for _, rack := range racks {
+		dc.Spec.Racks = append(dc.Spec.Racks, struct {
+			Name string `json:"name"`
+		}{Name: rack})
+	}
+	return dc
+}
+
+func checkNames(results []model.CheckResult) []string {
+	var names []string
+	for _, result := range results {
+		state := "passed"
+		if !result.Passed {
+			state = "failed"
+		}
+		names = append(names, result.Check+"/"+result.Target+"/"+state)
+	}
+	return names
+}
+
+func TestEvaluateRackPods(t *testing.T) {
+	ctx := validContexts()["central"]
+
+	results := evaluateRackPods("central", ctx, datacenter("central", 3, "rack1", "rack2"),
+		map[string][]string{"rack1": {"central-rack1-sts-0", "central-rack1-sts-1"}, "rack2": {}}, 2)
+	require.Equal(t, []string{"rack-pods/central/rack1/passed", "rack-pods/central/rack2/failed"},
+		checkNames(results))
+	require.Equal(t, "ready pods: 0 of expected: 1", results[1].Message)
+
+	results = evaluateRackPods("central", ctx, datacenter("central", 5, "rack1", "rack3"), nil, 2)
+	require.Equal(t, []string{
+		"rack-pods/central/rack1/failed",
+		"rack-pods/central/rack3/failed",
+		"rack-pods/central/rack2/failed",
+	}, checkNames(results), "expecting the node count, unknown racks and missing pools to be reported")
+}
+
+func TestEvaluateRackPodsExistingCluster(t *testing.T) {
+	results := evaluateRackPods("kind", validContexts()["kind"], datacenter("kind", 1),
+		map[string][]string{defaultCassandraRack: {"kind-default-sts-0"}}, 2)
+	require.Equal(t, []string{"rack-pods/kind/default/passed"}, checkNames(results))
+}
+
+func TestEvaluateNodetoolStatus(t *testing.T) {
+	require.Equal(t, map[string][]string{"central": {"UN", "UN"}, "kind": {"UN", "DN"}},
+		parseNodetoolStatus(nodetoolOutput))
+
+	result := evaluateNodetoolStatus("central", "central", nodetoolOutput, map[string]int{"central": 2})
+	require.True(t, result.Passed)
+
+	result = evaluateNodetoolStatus("central", "central", nodetoolOutput, map[string]int{"central": 2, "kind": 2})
+	require.False(t, result.Passed)
+	require.Equal(t, "kind: 1 UN of 2 nodes, expecting 2", result.Message)
+}
+
+func TestValidateK8ssandraCluster(t *testing.T) {
+	fake := executor.NewFake().
+		Respond(executor.Kubectl, []string{"get", "cassandradatacenters"},
+			`{"items":[{"metadata":{"name":"kind"},"spec":{"size":3,"racks":[{"name":"r1"},{"name":"r2"}]}}]}`, nil).
+		Respond(executor.Kubectl, []string{"wait", "--for=condition=Ready"}, "", errors.New("timed out")).
+		Respond(executor.Kubectl, []string{"exec"}, nodetoolOutput, nil)
+	useFakeClient(t,
+		cassandraPod("k8c-kind-r1-sts-0", "r1", true),
+		cassandraPod("k8c-kind-r1-sts-1", "r1", true),
+		cassandraPod("k8c-kind-r2-sts-0", "r2", false))
+
+	contexts := validContexts()
+	delete(contexts, "central")
+	kind := contexts["kind"]
+	kind.ClusterLabels = []string{"control-plane"}
+	contexts["kind"] = kind
+	config := model.ReadinessConfig{Contexts: contexts}
+	config.ProvisionConfig.K8cConfig.ClusterName = "bootz-k8c-cluster"
+
+	results := ValidateK8ssandraCluster(executor.With(t, fake), model.ProvisionMeta{AdminIdentity: DefaultAdminIdentifier}, config)
+	require.Equal(t, []string{
+		"k8ssandra-cluster-ready/k8ssandracluster/bootz-k8c-cluster/passed",
+		"datacenter-ready/cassandradatacenter/kind/failed",
+		"rack-pods/kind/r1/passed",
+		"rack-pods/kind/r2/failed",
+		"nodetool-status/kind/failed",
+		"cql-consistency/kind/failed",
+	}, checkNames(results))
+	require.Contains(t, results[5].Message, "bootz-k8c-cluster-superuser")
+
+	commands := fake.CommandsOf(executor.Kubectl, "exec")
+	require.Len(t, commands, 1)
+	require.Equal(t, []string{"exec", "k8c-kind-r1-sts-0", "-n", "bootz", "-c", "cassandra", "--", "nodetool",
+		"status"}, commands[0].Args)
+}
+
+func TestRecordCheckResults(t *testing.T) {
+	meta := model.ProvisionMeta{ProvisionId: "k8c-test", ArtifactsRootDir: t.TempDir()}
+	results := []model.CheckResult{checkResult("kind", CheckRackPods, "kind/r1", true, "ready pods: 2 of expected: 2")}
+
+	RecordCheckResults(t, meta, results)
+
+	ledger, err := LoadLedger(meta)
+	require.NoError(t, err)
+	require.Len(t, ledger.Checks, 1)
+	require.Equal(t, "kind/r1", ledger.Checks[0].Target)
+}
@@ -22,29 +22,30 @@ import (
 	"github.com/gruntwork-io/terratest/modules/k8s"
 	"github.com/gruntwork-io/terratest/modules/logger"
 	ts "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
 	"github.com/stretchr/testify/require"
 	"io/ioutil"
 	"os"
 	"path"
 	"regexp"
 	"strconv"
-	"testing"
 )
 
-func DeleteResource(t *testing.T, kubeConfig *k8s.KubectlOptions, resourceKind string, resourceName string) {
+func DeleteResource(t testing.TestingT, kubeConfig *k8s.KubectlOptions, resourceKind string, resourceName string) {
 
 	require.NotEmpty(t, resourceKind, "required resource kind to be specified for delete")
 	require.NotEmpty(t, resourceName, "required resource name to be specified for delete")
 
-	_, err := k8s.RunKubectlAndGetOutputE(t, kubeConfig, "delete", resourceKind, resourceName)
+	_, err := executor.RunKubectl(t, kubeConfig, "delete", resourceKind, resourceName)
 	if err != nil {
 		logger.Log(t, fmt.Sprintf("WARNING: attempt to delete resource of kind: %s "+
 			"and name: %s failed: %s", resourceKind, resourceName, err.Error()))
 	}
 }
 
-func RemoveProvisioningArtifacts(t *testing.T, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig,
+func RemoveProvisioningArtifacts(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig,
 	isCloudCleanRequested bool) {
 
 	logger.Log(t, fmt.Sprintf("remove provisioning artifacts with cloud clean request: %s",
@@ -56,7 +57,7 @@ func RemoveProvisioningArtifacts(t *testing.T, meta model.ProvisionMeta, readine
 	}
 }
 
-func removeTempArtifacts(t *testing.T, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig,
+func removeTempArtifacts(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig,
 	isCloudCleanRequested bool) bool {
 
 	var isSuccess = true
@@ -87,8 +88,13 @@ func removeTempArtifacts(t *testing.T, meta model.ProvisionMeta, readinessConfig
 				var isResourceCleanupComplete = false
 				if isCloudCleanRequested {
 					contextConfig := readinessConfig.Contexts[artifact.Name()]
-					tfOptions := CreateTerraformOptions(meta, readinessConfig, artifact.Name(),
+					tfOptions, optionsErr := CreateTerraformOptions(meta, readinessConfig, artifact.Name(),
 						contextConfig, meta.DefaultConfigPath, path.Join(manifest.ModulesFolder, defaultTestSubFolder))
+					if optionsErr != nil {
+						logger.Log(t, fmt.Sprintf("WARNING: unable to create terraform options for: %s, error: %s",
+							artifact.Name(), optionsErr.Error()))
+						return false
+					}
 					isResourceCleanupComplete = Cleanup(t, meta, manifest.Name, &tfOptions)
 				}
 
@@ -96,7 +102,7 @@ func removeTempArtifacts(t *testing.T, meta model.ProvisionMeta, readinessConfig
 					isSuccess = removeArtifactsAndFolders(t, meta, manifest)
 					if !isSuccess {
 						logger.Log(t, fmt.Sprintf("WARNING: failed to locate the test data "+
-							"for artifact: %s in the /tmp folder", artifactPath))
+							"for artifact: %s in the temp folder", artifactPath))
 					}
 				}
 			}
@@ -105,10 +111,10 @@ func removeTempArtifacts(t *testing.T, meta model.ProvisionMeta, readinessConfig
 	return isSuccess
 }
 
-func removeArtifactsAndFolders(t *testing.T, meta model.ProvisionMeta, manifest *model.ContextTestManifest) bool {
+func removeArtifactsAndFolders(t testing.TestingT, meta model.ProvisionMeta, manifest *model.ContextTestManifest) bool {
 
-	// Extra check, only removing /tmp/TestK8cSmoke*
-	regex, err := regexp.Compile(defaultArtifactFormat)
+	// Extra check, only removing the copied modules folders of the temp dir
+	regex, err := regexp.Compile(fmt.Sprintf(defaultArtifactFormat, regexp.QuoteMeta(path.Clean(os.TempDir()))))
 	if err != nil || regex == nil {
 		return false
 	}
@@ -130,7 +136,7 @@ func removeArtifactsAndFolders(t *testing.T, meta model.ProvisionMeta, manifest
 	return false
 }
 
-func removeManifestFolder(t *testing.T, meta model.ProvisionMeta) {
+func removeManifestFolder(t testing.TestingT, meta model.ProvisionMeta) {
 
 	regex, err := regexp.Compile(defaultParentArtifactFormat)
 	if err == nil && regex != nil {
@@ -0,0 +1,57 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRemoveArtifactsAndFolders(t *testing.T) {
+	t.Setenv("TMPDIR", "/tmp")
+	meta := model.ProvisionMeta{Enable: model.EnableConfig{Simulate: true}}
+	for _, folder := range []string{
+		"/tmp/k8c-modules-1234567/provision/gcp/env",
+		"/tmp/TestK8cSmoke1234567/cloud/gcp/env",
+		"/tmp/TestK8cProvision1234567/cloud/aws/env",
+		"/tmp/TestK8cRun1234567/cloud/azure/env",
+	} {
+		require.True(t, removeArtifactsAndFolders(t, meta, &model.ContextTestManifest{ModulesFolder: folder}), folder)
+	}
+
+	for _, folder := range []string{
+		"/tmp/TestK8cUnrelated1234567/cloud/gcp/env",
+		"/tmp/TestK8cMatrix1234567/provision/gcp/env",
+		"/tmp/TestK8cSmokeData/cloud/gcp/env",
+		"/home/tester/tmp/TestK8cRun1234567/cloud/gcp/env",
+	} {
+		require.False(t, removeArtifactsAndFolders(t, meta, &model.ContextTestManifest{ModulesFolder: folder}),
+			folder)
+	}
+}
+
+func TestRemoveArtifactsAndFoldersOfTempDir(t *testing.T) {
+	t.Setenv("TMPDIR", "/var/folders/x1/T/")
+	meta := model.ProvisionMeta{Enable: model.EnableConfig{Simulate: true}}
+	require.True(t, removeArtifactsAndFolders(t, meta,
+		&model.ContextTestManifest{ModulesFolder: "/var/folders/x1/T/k8c-modules-1234567/provision/gcp/env"}))
+	require.False(t, removeArtifactsAndFolders(t, meta,
+		&model.ContextTestManifest{ModulesFolder: "/tmp/k8c-modules-1234567/provision/gcp/env"}))
+	require.False(t, removeArtifactsAndFolders(t, meta,
+		&model.ContextTestManifest{ModulesFolder: "/var/folders/x1/TXk8c-modules-1234567/provision/gcp/env"}))
+}
@@ -0,0 +1,186 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package aws
+
+import (
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"strings"
+)
+
+const (
+	Type = "aws"
+
+	defaultClusterSuffix  = "-eks-cluster"
+	defaultBucketSuffix   = "-s3-bucket"
+	defaultStorageType    = "s3"
+	defaultProfileKey     = "AWS_PROFILE"
+	defaultRegionKey      = "AWS_REGION"
+	defaultCredentialsKey = "AWS_SHARED_CREDENTIALS_FILE"
+
+	// EKS cluster names are limited to 100 characters.
+	maxClusterNameLength = 100
+
+	// The VPC module creates a private subnet per rack availability zone, from the three private CIDR blocks.
+	maxRackLocations = 3
+)
+
+// Provider is the EKS implementation of the cloud provider.
+type Provider struct{}
+
+func (Provider) Switch(t testing.TestingT, identity string, env map[string]string) bool {
+	return Switch(t, identity, env)
+}
+
+func (Provider) FetchCreds(t testing.TestingT, cloudConfig model.CloudConfig, env map[string]string, clusterName string) bool {
+	return FetchCreds(t, cloudConfig, env, clusterName)
+}
+
+func (Provider) ConstructFullContextName(contextName string, config model.CloudConfig) string {
+	return ConstructFullContextName(contextName, config)
+}
+
+func (Provider) ConstructCloudClusterName(contextName string, config model.CloudConfig) string {
+	return ConstructCloudClusterName(contextName, config)
+}
+
+func (Provider) ConstructServiceAccountName(contextName string, suffix string, config model.CloudConfig) string {
+	return ConstructCloudClusterName(contextName, config) + "-" + suffix
+}
+
+func (Provider) IdentityEnv(configPath string, identity string, config model.CloudConfig) map[string]string {
+	env := credentialsEnv(config)
+	env["KUBECONFIG"] = configPath
+	env[defaultProfileKey] = identity
+	return env
+}
+
+func (Provider) TerraformVars(meta model.ProvisionMeta, config model.ReadinessConfig, name string,
+	ctx model.ContextConfig, kubeConfigPath string) map[string]interface{} {
+	return TerraformVars(meta, config, name, ctx, kubeConfigPath)
+}
+
+func (Provider) TerraformEnv(config model.CloudConfig) map[string]string {
+	return credentialsEnv(config)
+}
+
+// IsLocationInRegion expects an availability zone of the region followed by a single zone letter, e.g. us-east-2a.
+func (Provider) IsLocationInRegion(location string, region string) bool {
+	return strings.HasPrefix(location, region) && len(location) == len(region)+1
+}
+
+func (Provider) NodeZone(location string, config model.CloudConfig) string {
+	return location
+}
+
+func (Provider) MaxClusterNameLength() int {
+	return maxClusterNameLength
+}
+
+func (Provider) MaxRackLocations() int {
+	return maxRackLocations
+}
+
+func (Provider) HasMasterCidrBlock() bool {
+	return false
+}
+
+func (Provider) MedusaStorage(config model.ReadinessConfig, name string, ctx model.ContextConfig) model.MedusaStorage {
+	return model.MedusaStorage{
+		StorageProvider: defaultStorageType,
+		BucketName:      ConstructBucketName(name, config, ctx.CloudConfig),
+		Region:          ctx.CloudConfig.Region,
+	}
+}
+
+// ConstructFullContextName provides the context alias written by `aws eks update-kubeconfig`, the cluster ARN.
+// The CloudConfig project is expected to carry the AWS account identifier.
+func ConstructFullContextName(contextName string, config model.CloudConfig) string {
+	return "arn:aws:eks:" + config.Region + ":" + config.Project + ":cluster/" +
+		ConstructCloudClusterName(contextName, config)
+}
+
+func ConstructCloudClusterName(contextName string, config model.CloudConfig) string {
+	return config.Environment + "-" + strings.ToLower(contextName) + defaultClusterSuffix
+}
+
+// ConstructBucketName provides the S3 bucket for Medusa, unique per readiness run when a bucket is configured.
+func ConstructBucketName(contextName string, config model.ReadinessConfig, cloudConfig model.CloudConfig) string {
+	if cloudConfig.Bucket == "" {
+		return cloudConfig.Environment + "-" + strings.ToLower(contextName) + defaultBucketSuffix
+	}
+	bucket := cloudConfig.Bucket
+	if config.UniqueId != "" {
+		bucket = bucket + "-" + config.UniqueId
+	}
+	return strings.ReplaceAll(strings.ToLower(bucket), "_", "-")
+}
+
+func FetchCreds(t testing.TestingT, cloudConfig model.CloudConfig, env map[string]string, clusterName string) bool {
+	args := []string{"eks", "update-kubeconfig", "--name", clusterName, "--region", cloudConfig.Region}
+	if kubeConfig := env["KUBECONFIG"]; kubeConfig != "" {
+		args = append(args, "--kubeconfig", kubeConfig)
+	}
+	var cmd = shell.Command{
+		Command:    "aws",
+		Args:       args,
+		WorkingDir: "/tmp",
+		Env:        env,
+		Logger:     logger.Default,
+	}
+	_, cmdErr := executor.RunShell(t, cmd)
+	if cmdErr != nil {
+		logger.Log(t, fmt.Sprintf("failed eks update-kubeconfig for cluster: %s error: %s", clusterName, cmdErr))
+		return false
+	}
+	return true
+}
+
+// Switch verifies the named profile resolves to a caller identity, the profile is carried by the identity env.
+func Switch(t testing.TestingT, profile string, env map[string]string) bool {
+	args := []string{"sts", "get-caller-identity", "--profile", profile, "--output", "json"}
+	var cmd = shell.Command{
+		Command:    "aws",
+		Args:       args,
+		WorkingDir: "/tmp",
+		Env:        env,
+		Logger:     logger.Default,
+	}
+	_, cmdErr := executor.RunShell(t, cmd)
+	if cmdErr != nil {
+		logger.Log(t, fmt.Sprintf("failed profile switch to: %s", profile))
+		return false
+	}
+	logger.Log(t, fmt.Sprintf("switched to profile: %s", profile))
+	return true
+}
+
+func credentialsEnv(config model.CloudConfig) map[string]string {
+	env := map[string]string{defaultRegionKey: config.Region}
+	if config.CredPath != "" {
+		credKey := config.CredKey
+		if credKey == "" {
+			credKey = defaultCredentialsKey
+		}
+		env[credKey] = config.CredPath
+	}
+	return env
+}
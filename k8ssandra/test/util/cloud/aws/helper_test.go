@@ -0,0 +1,122 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package aws
+
+import (
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/cloud/clitest"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const fakeAwsScript = `#!/bin/sh
+echo "$AWS_PROFILE $*" >> "$FAKE_CLI_LOG"
+if [ -n "$FAKE_CLI_FAIL" ]; then
+  exit 1
+fi
+echo '{"Account": "123456789012"}'
+`
+
+func testCloudConfig() model.CloudConfig {
+	return model.CloudConfig{
+		Type:        Type,
+		Project:     "123456789012",
+		Region:      "us-east-2",
+		Environment: "dev",
+		Bucket:      "medusa_bucket",
+		PoolRackConfigs: []model.PoolRackConfig{
+			{Name: "rack1", Label: "k8ssandra.io/rack=rack1", Location: "us-east-2a"},
+			{Name: "rack2", Label: "k8ssandra.io/rack=rack2", Location: "us-east-2b"},
+		},
+	}
+}
+
+func TestConstructNames(t *testing.T) {
+	config := testCloudConfig()
+
+	require.Equal(t, "dev-bootz-east-eks-cluster", ConstructCloudClusterName("Bootz-East", config))
+	require.Equal(t, "arn:aws:eks:us-east-2:123456789012:cluster/dev-bootz-east-eks-cluster",
+		ConstructFullContextName("bootz-east", config))
+}
+
+func TestFetchCreds(t *testing.T) {
+	logPath := clitest.Install(t, "aws", fakeAwsScript)
+	config := testCloudConfig()
+	env := Provider{}.IdentityEnv("/tmp/kube/config", "readiness", config)
+
+	require.True(t, FetchCreds(t, config, env, ConstructCloudClusterName("bootz-east", config)))
+	require.Equal(t, []string{"readiness eks update-kubeconfig --name dev-bootz-east-eks-cluster " +
+		"--region us-east-2 --kubeconfig /tmp/kube/config"}, clitest.Invocations(t, logPath))
+}
+
+func TestFetchCredsFailure(t *testing.T) {
+	clitest.Install(t, "aws", fakeAwsScript)
+	clitest.Fail(t)
+	config := testCloudConfig()
+
+	require.False(t, FetchCreds(t, config, map[string]string{}, ConstructCloudClusterName("bootz-east", config)))
+}
+
+func TestSwitch(t *testing.T) {
+	logPath := clitest.Install(t, "aws", fakeAwsScript)
+	config := testCloudConfig()
+	env := Provider{}.IdentityEnv("/tmp/kube/config", "readiness", config)
+
+	require.True(t, Switch(t, "readiness", env))
+	require.Equal(t, []string{"readiness sts get-caller-identity --profile readiness --output json"},
+		clitest.Invocations(t, logPath))
+}
+
+func TestSplitCidrBlock(t *testing.T) {
+	require.Equal(t, []string{"10.0.16.0/22", "10.0.20.0/22", "10.0.24.0/22"}, splitCidrBlock("10.0.16.0/20", 3))
+	require.Equal(t, []string{"10.0.16.0/20"}, splitCidrBlock("10.0.16.0/20", 1))
+	require.Equal(t, []string{"10.0.0.0/32", "10.0.0.1/32"}, splitCidrBlock("10.0.0.0/31", 3))
+}
+
+func TestTerraformVars(t *testing.T) {
+	config := model.ReadinessConfig{UniqueId: "abc123", ExpectedNodeCount: 2}
+	ctx := model.ContextConfig{Name: "bootz-east", CloudConfig: testCloudConfig()}
+
+	vars := TerraformVars(model.ProvisionMeta{ProvisionId: "k8c-xyz"}, config, "bootz-east", ctx, "")
+
+	require.Equal(t, "bootz-east", vars["name"])
+	require.Equal(t, "medusa-bucket-abc123", vars["bucket_name"])
+	require.Equal(t, []map[string]string{
+		{"name": "rack1", "label": "k8ssandra.io/rack=rack1", "location": "us-east-2a",
+			"desired_size": "2", "min_size": "2", "max_size": "2"},
+		{"name": "rack2", "label": "k8ssandra.io/rack=rack2", "location": "us-east-2b",
+			"desired_size": "2", "min_size": "2", "max_size": "2"},
+	}, vars["node_groups"])
+	require.Equal(t, []string{"us-east-2a", "us-east-2b"}, vars["availability_zones"])
+	require.NotContains(t, vars, "vpc_cidr_block")
+
+	ctx.NetworkConfig = model.NetworkConfig{SubnetCidrBlock: "10.0.0.0/20", SecondaryCidrBlock: "10.0.16.0/20"}
+	vars = TerraformVars(model.ProvisionMeta{ProvisionId: "k8c-xyz"}, config, "bootz-east", ctx, "")
+	require.Equal(t, "10.0.0.0/20", vars["vpc_cidr_block"])
+	require.Equal(t, []string{"10.0.0.0/21", "10.0.8.0/21"}, vars["public_cidr_block"])
+	require.Equal(t, []string{"10.0.16.0/20"}, vars["secondary_cidr_blocks"])
+	require.Equal(t, []string{"10.0.16.0/21", "10.0.24.0/21"}, vars["private_cidr_block"])
+
+	require.NotContains(t, vars, "cluster_version")
+	ctx.CloudConfig.KubernetesVersion = "1.23"
+	vars = TerraformVars(model.ProvisionMeta{ProvisionId: "k8c-xyz"}, config, "bootz-east", ctx, "")
+	require.Equal(t, "1.23", vars["cluster_version"])
+
+	storage := Provider{}.MedusaStorage(config, "bootz-east", ctx)
+	require.Equal(t, "s3", storage.StorageProvider)
+	require.Equal(t, "medusa-bucket-abc123", storage.BucketName)
+}
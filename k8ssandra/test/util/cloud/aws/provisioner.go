@@ -0,0 +1,135 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package aws
+
+import (
+	"encoding/binary"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"k8s.io/utils/strings/slices"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultResourceOwner = "cloud-readiness"
+	defaultSubnetCount   = 3
+)
+
+// TerraformVars maps a context to the variables of the EKS env module.
+func TerraformVars(meta model.ProvisionMeta, config model.ReadinessConfig, name string,
+	ctx model.ContextConfig, kubeConfigPath string) map[string]interface{} {
+
+	owner := defaultResourceOwner
+	if meta.AdminIdentity != "" && os.Getenv(meta.AdminIdentity) != "" {
+		owner = os.Getenv(meta.AdminIdentity)
+	}
+
+	vars := map[string]interface{}{
+		"name":           strings.ToLower(name),
+		"environment":    ctx.CloudConfig.Environment,
+		"provision_id":   meta.ProvisionId,
+		"resource_owner": owner,
+		"region":         ctx.CloudConfig.Region,
+		"node_groups":    createNodeGroups(config, ctx),
+		"bucket_name":    ConstructBucketName(name, config, ctx.CloudConfig),
+
+		// The subnets are created in the availability zones of the racks, so that every node group has a subnet.
+		"availability_zones": rackZones(ctx),
+	}
+
+	// The public subnets are carved from the VPC block, the private subnets holding the nodes and pods from the
+	// secondary block associated with the VPC.
+	zoneCount := len(rackZones(ctx))
+	if zoneCount == 0 {
+		zoneCount = defaultSubnetCount
+	}
+	networkConfig := ctx.NetworkConfig
+	if networkConfig.SubnetCidrBlock != "" {
+		vars["vpc_cidr_block"] = networkConfig.SubnetCidrBlock
+		vars["public_cidr_block"] = splitCidrBlock(networkConfig.SubnetCidrBlock, zoneCount)
+	}
+	if networkConfig.SecondaryCidrBlock != "" {
+		vars["secondary_cidr_blocks"] = []string{networkConfig.SecondaryCidrBlock}
+		vars["private_cidr_block"] = splitCidrBlock(networkConfig.SecondaryCidrBlock, zoneCount)
+	}
+
+	if ctx.CloudConfig.MachineType != "" {
+		vars["instance_type"] = ctx.CloudConfig.MachineType
+	}
+	if ctx.CloudConfig.KubernetesVersion != "" {
+		vars["cluster_version"] = ctx.CloudConfig.KubernetesVersion
+	}
+	return vars
+}
+
+// rackZones provides the distinct availability zones of the racks, in the order of the racks.
+func rackZones(ctx model.ContextConfig) []string {
+	var zones []string
+	for _, prc := range ctx.CloudConfig.PoolRackConfigs {
+		if !slices.Contains(zones, prc.Location) {
+			zones = append(zones, prc.Location)
+		}
+	}
+	return zones
+}
+
+// splitCidrBlock divides an IPv4 block into the smallest equal subnets providing count subnets, the first count of
+// them are returned.
+func splitCidrBlock(block string, count int) []string {
+	_, network, err := net.ParseCIDR(block)
+	if err != nil || network.IP.To4() == nil {
+		return []string{block}
+	}
+
+	ones, bits := network.Mask.Size()
+	subnetOnes := ones
+	for 1<<uint(subnetOnes-ones) < count && subnetOnes < bits {
+		subnetOnes++
+	}
+
+	start := binary.BigEndian.Uint32(network.IP.To4())
+	size := uint32(1) << uint(bits-subnetOnes)
+	var subnets []string
+	for i := 0; i < count && i < 1<<uint(subnetOnes-ones); i++ {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, start+uint32(i)*size)
+		subnets = append(subnets, (&net.IPNet{IP: ip, Mask: net.CIDRMask(subnetOnes, bits)}).String())
+	}
+	return subnets
+}
+
+// createNodeGroups maps each rack to a managed node group in the availability zone of the rack.
+func createNodeGroups(config model.ReadinessConfig, ctx model.ContextConfig) []map[string]string {
+
+	var nodeGroups []map[string]string
+	for _, prc := range ctx.CloudConfig.PoolRackConfigs {
+		var nodeGroup = map[string]string{}
+		nodeGroup["label"] = prc.Label
+		nodeGroup["name"] = prc.Name
+		nodeGroup["location"] = prc.Location
+		if config.ExpectedNodeCount > 0 {
+			count := strconv.Itoa(config.ExpectedNodeCount)
+			nodeGroup["desired_size"] = count
+			nodeGroup["min_size"] = count
+			nodeGroup["max_size"] = count
+		}
+		nodeGroups = append(nodeGroups, nodeGroup)
+	}
+	return nodeGroups
+}
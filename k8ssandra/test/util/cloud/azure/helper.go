@@ -0,0 +1,211 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package azure
+
+import (
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"strings"
+)
+
+const (
+	Type = "azure"
+
+	defaultClusterSuffix       = "-aks-cluster"
+	defaultResourceGroupSuffix = "-resource-group"
+	defaultContainerSuffix     = "-storage-container"
+	defaultStorageType         = "azure_blobs"
+	defaultSubscriptionKey     = "ARM_SUBSCRIPTION_ID"
+	defaultCredentialsKey      = "AZURE_AUTH_LOCATION"
+
+	// AKS cluster names are limited to 63 characters.
+	maxClusterNameLength = 63
+)
+
+// availabilityZones are the zones offered within an Azure region.
+var availabilityZones = []string{"1", "2", "3"}
+
+// Provider is the AKS implementation of the cloud provider.
+type Provider struct{}
+
+func (Provider) Switch(t testing.TestingT, identity string, env map[string]string) bool {
+	return Switch(t, identity, env)
+}
+
+func (Provider) FetchCreds(t testing.TestingT, cloudConfig model.CloudConfig, env map[string]string, clusterName string) bool {
+	return FetchCreds(t, cloudConfig, env, clusterName)
+}
+
+func (Provider) ConstructFullContextName(contextName string, config model.CloudConfig) string {
+	return ConstructFullContextName(contextName, config)
+}
+
+func (Provider) ConstructCloudClusterName(contextName string, config model.CloudConfig) string {
+	return ConstructCloudClusterName(contextName, config)
+}
+
+func (Provider) ConstructServiceAccountName(contextName string, suffix string, config model.CloudConfig) string {
+	return ConstructCloudClusterName(contextName, config) + "-" + suffix
+}
+
+func (Provider) IdentityEnv(configPath string, identity string, config model.CloudConfig) map[string]string {
+	env := credentialsEnv(config)
+	env["KUBECONFIG"] = configPath
+	return env
+}
+
+func (Provider) TerraformVars(meta model.ProvisionMeta, config model.ReadinessConfig, name string,
+	ctx model.ContextConfig, kubeConfigPath string) map[string]interface{} {
+	return TerraformVars(meta, config, name, ctx, kubeConfigPath)
+}
+
+func (Provider) TerraformEnv(config model.CloudConfig) map[string]string {
+	return credentialsEnv(config)
+}
+
+// IsLocationInRegion expects one of the region availability zones, Azure zones are numbered within every region.
+func (Provider) IsLocationInRegion(location string, region string) bool {
+	for _, zone := range availabilityZones {
+		if location == zone {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeZone provides the zone label of an AKS node, the region followed by the zone number, e.g. eastus-1.
+func (Provider) NodeZone(location string, config model.CloudConfig) string {
+	return config.Region + "-" + location
+}
+
+func (Provider) MaxClusterNameLength() int {
+	return maxClusterNameLength
+}
+
+func (Provider) MaxRackLocations() int {
+	return 0
+}
+
+func (Provider) HasMasterCidrBlock() bool {
+	return false
+}
+
+func (Provider) MedusaStorage(config model.ReadinessConfig, name string, ctx model.ContextConfig) model.MedusaStorage {
+	return model.MedusaStorage{
+		StorageProvider: defaultStorageType,
+		BucketName:      ConstructContainerName(name, config, ctx.CloudConfig),
+		Region:          ctx.CloudConfig.Region,
+	}
+}
+
+// ConstructFullContextName provides the context written by `az aks get-credentials`, named after the cluster.
+func ConstructFullContextName(contextName string, config model.CloudConfig) string {
+	return ConstructCloudClusterName(contextName, config)
+}
+
+func ConstructCloudClusterName(contextName string, config model.CloudConfig) string {
+	return constructPrefix(contextName, config) + defaultClusterSuffix
+}
+
+func ConstructResourceGroupName(contextName string, config model.CloudConfig) string {
+	return constructPrefix(contextName, config) + defaultResourceGroupSuffix
+}
+
+// ConstructContainerName provides the Blob container for Medusa, unique per readiness run when a bucket is configured.
+func ConstructContainerName(contextName string, config model.ReadinessConfig, cloudConfig model.CloudConfig) string {
+	if cloudConfig.Bucket == "" {
+		return constructPrefix(contextName, cloudConfig) + defaultContainerSuffix
+	}
+	container := cloudConfig.Bucket
+	if config.UniqueId != "" {
+		container = container + "-" + config.UniqueId
+	}
+	return strings.ReplaceAll(strings.ToLower(container), "_", "-")
+}
+
+// FetchCreds merges the AKS credentials, the resource group is derived from the cloud cluster name.
+func FetchCreds(t testing.TestingT, cloudConfig model.CloudConfig, env map[string]string, clusterName string) bool {
+	resourceGroup := strings.TrimSuffix(clusterName, defaultClusterSuffix) + defaultResourceGroupSuffix
+	args := []string{"aks", "get-credentials", "--resource-group", resourceGroup, "--name", clusterName,
+		"--overwrite-existing"}
+	if cloudConfig.Project != "" {
+		args = append(args, "--subscription", cloudConfig.Project)
+	}
+	if kubeConfig := env["KUBECONFIG"]; kubeConfig != "" {
+		args = append(args, "--file", kubeConfig)
+	}
+	var cmd = shell.Command{
+		Command:    "az",
+		Args:       args,
+		WorkingDir: "/tmp",
+		Env:        env,
+		Logger:     logger.Default,
+	}
+	_, cmdErr := executor.RunShell(t, cmd)
+	if cmdErr != nil {
+		logger.Log(t, fmt.Sprintf("failed aks get-credentials for cluster: %s error: %s", clusterName, cmdErr))
+		return false
+	}
+	return true
+}
+
+// Switch selects the subscription carried by the identity env for the signed in identity.
+func Switch(t testing.TestingT, identity string, env map[string]string) bool {
+	subscription := env[defaultSubscriptionKey]
+	if subscription == "" {
+		logger.Log(t, fmt.Sprintf("no subscription provided, using the default subscription for: %s", identity))
+		return true
+	}
+	args := []string{"account", "set", "--subscription", subscription}
+	var cmd = shell.Command{
+		Command:    "az",
+		Args:       args,
+		WorkingDir: "/tmp",
+		Env:        env,
+		Logger:     logger.Default,
+	}
+	_, cmdErr := executor.RunShell(t, cmd)
+	if cmdErr != nil {
+		logger.Log(t, fmt.Sprintf("failed subscription switch to: %s for: %s", subscription, identity))
+		return false
+	}
+	logger.Log(t, fmt.Sprintf("switched to subscription: %s for: %s", subscription, identity))
+	return true
+}
+
+func constructPrefix(contextName string, config model.CloudConfig) string {
+	return strings.ToLower(config.Environment) + "-" + strings.ToLower(contextName)
+}
+
+func credentialsEnv(config model.CloudConfig) map[string]string {
+	env := map[string]string{}
+	if config.Project != "" {
+		env[defaultSubscriptionKey] = config.Project
+	}
+	if config.CredPath != "" {
+		credKey := config.CredKey
+		if credKey == "" {
+			credKey = defaultCredentialsKey
+		}
+		env[credKey] = config.CredPath
+	}
+	return env
+}
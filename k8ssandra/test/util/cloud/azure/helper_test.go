@@ -0,0 +1,113 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package azure
+
+import (
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/cloud/clitest"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const fakeAzScript = `#!/bin/sh
+echo "$*" >> "$FAKE_CLI_LOG"
+if [ -n "$FAKE_CLI_FAIL" ]; then
+  exit 1
+fi
+`
+
+func testCloudConfig() model.CloudConfig {
+	return model.CloudConfig{
+		Type:        Type,
+		Project:     "00000000-1111-2222-3333-444444444444",
+		Region:      "eastus",
+		Environment: "Dev",
+		Bucket:      "medusa_container",
+		PoolRackConfigs: []model.PoolRackConfig{
+			{Name: "rack-1", Label: "k8ssandra.io/rack=rack1", Location: "1"},
+			{Name: "rack-2", Label: "k8ssandra.io/rack=rack2", Location: "2"},
+		},
+	}
+}
+
+func TestConstructNames(t *testing.T) {
+	config := testCloudConfig()
+
+	require.Equal(t, "dev-bootz-east-aks-cluster", ConstructCloudClusterName("bootz-east", config))
+	require.Equal(t, "dev-bootz-east-aks-cluster", ConstructFullContextName("bootz-east", config))
+	require.Equal(t, "dev-bootz-east-resource-group", ConstructResourceGroupName("bootz-east", config))
+	require.Equal(t, "rack1", ConstructNodePoolName("Rack-1"))
+	require.Equal(t, "averylongrac", ConstructNodePoolName("a-very-long-rack-name"))
+	require.Equal(t, "eastus-2", Provider{}.NodeZone("2", config))
+}
+
+func TestFetchCreds(t *testing.T) {
+	logPath := clitest.Install(t, "az", fakeAzScript)
+	config := testCloudConfig()
+	env := Provider{}.IdentityEnv("/tmp/kube/config", "readiness@example.com", config)
+
+	require.True(t, FetchCreds(t, config, env, ConstructCloudClusterName("bootz-east", config)))
+	require.Equal(t, []string{"aks get-credentials --resource-group dev-bootz-east-resource-group " +
+		"--name dev-bootz-east-aks-cluster --overwrite-existing " +
+		"--subscription 00000000-1111-2222-3333-444444444444 --file /tmp/kube/config"}, clitest.Invocations(t, logPath))
+}
+
+func TestFetchCredsFailure(t *testing.T) {
+	clitest.Install(t, "az", fakeAzScript)
+	clitest.Fail(t)
+	config := testCloudConfig()
+
+	require.False(t, FetchCreds(t, config, map[string]string{}, ConstructCloudClusterName("bootz-east", config)))
+}
+
+func TestSwitch(t *testing.T) {
+	logPath := clitest.Install(t, "az", fakeAzScript)
+	env := Provider{}.IdentityEnv("/tmp/kube/config", "readiness@example.com", testCloudConfig())
+
+	require.True(t, Switch(t, "readiness@example.com", env))
+	require.Equal(t, []string{"account set --subscription 00000000-1111-2222-3333-444444444444"},
+		clitest.Invocations(t, logPath))
+}
+
+func TestTerraformVars(t *testing.T) {
+	config := model.ReadinessConfig{UniqueId: "abc123", ExpectedNodeCount: 1}
+	ctx := model.ContextConfig{Name: "bootz-east", CloudConfig: testCloudConfig()}
+
+	vars := TerraformVars(model.ProvisionMeta{ProvisionId: "k8c-xyz"}, config, "bootz-east", ctx, "")
+
+	require.Equal(t, "medusa-container-abc123", vars["container_name"])
+	require.Equal(t, []map[string]string{
+		{"name": "rack1", "label": "k8ssandra.io/rack=rack1", "location": "1", "node_count": "1"},
+		{"name": "rack2", "label": "k8ssandra.io/rack=rack2", "location": "2", "node_count": "1"},
+	}, vars["node_pools"])
+	require.NotContains(t, vars, "address_space")
+
+	ctx.NetworkConfig = model.NetworkConfig{SubnetCidrBlock: "10.0.0.0/20", SecondaryCidrBlock: "10.0.16.0/20"}
+	vars = TerraformVars(model.ProvisionMeta{ProvisionId: "k8c-xyz"}, config, "bootz-east", ctx, "")
+	require.Equal(t, []string{"10.0.0.0/20", "10.0.16.0/20"}, vars["address_space"])
+	require.Equal(t, []string{"10.0.0.0/20"}, vars["public_subnet_prefixes"])
+	require.Equal(t, []string{"10.0.16.0/20"}, vars["private_subnet_prefixes"])
+
+	require.NotContains(t, vars, "kubernetes_version")
+	ctx.CloudConfig.KubernetesVersion = "1.23.8"
+	vars = TerraformVars(model.ProvisionMeta{ProvisionId: "k8c-xyz"}, config, "bootz-east", ctx, "")
+	require.Equal(t, "1.23.8", vars["kubernetes_version"])
+
+	storage := Provider{}.MedusaStorage(config, "bootz-east", ctx)
+	require.Equal(t, "azure_blobs", storage.StorageProvider)
+	require.Equal(t, "medusa-container-abc123", storage.BucketName)
+}
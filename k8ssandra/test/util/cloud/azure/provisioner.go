@@ -0,0 +1,101 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package azure
+
+import (
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultResourceOwner   = "cloud-readiness"
+	maxNodePoolNameLength  = 12
+	nodePoolNameDisallowed = "[^a-z0-9]"
+)
+
+// TerraformVars maps a context to the variables of the AKS env module.
+func TerraformVars(meta model.ProvisionMeta, config model.ReadinessConfig, name string,
+	ctx model.ContextConfig, kubeConfigPath string) map[string]interface{} {
+
+	owner := defaultResourceOwner
+	if meta.AdminIdentity != "" && os.Getenv(meta.AdminIdentity) != "" {
+		owner = os.Getenv(meta.AdminIdentity)
+	}
+
+	vars := map[string]interface{}{
+		"name":           strings.ToLower(name),
+		"environment":    ctx.CloudConfig.Environment,
+		"provision_id":   meta.ProvisionId,
+		"resource_owner": owner,
+		"region":         ctx.CloudConfig.Region,
+		"node_pools":     createNodePools(config, ctx),
+		"container_name": ConstructContainerName(name, config, ctx.CloudConfig),
+	}
+
+	// The virtual network spans the planned blocks, the public subnet taking the subnet block and the private
+	// subnet holding the nodes and pods the secondary block.
+	var addressSpace []string
+	networkConfig := ctx.NetworkConfig
+	if networkConfig.SubnetCidrBlock != "" {
+		addressSpace = append(addressSpace, networkConfig.SubnetCidrBlock)
+		vars["public_subnet_prefixes"] = []string{networkConfig.SubnetCidrBlock}
+	}
+	if networkConfig.SecondaryCidrBlock != "" {
+		addressSpace = append(addressSpace, networkConfig.SecondaryCidrBlock)
+		vars["private_subnet_prefixes"] = []string{networkConfig.SecondaryCidrBlock}
+	}
+	if len(addressSpace) > 0 {
+		vars["address_space"] = addressSpace
+	}
+
+	if ctx.CloudConfig.MachineType != "" {
+		vars["vm_size"] = ctx.CloudConfig.MachineType
+	}
+	if ctx.CloudConfig.KubernetesVersion != "" {
+		vars["kubernetes_version"] = ctx.CloudConfig.KubernetesVersion
+	}
+	return vars
+}
+
+// createNodePools maps each rack to a node pool in the availability zone given by the rack location.
+func createNodePools(config model.ReadinessConfig, ctx model.ContextConfig) []map[string]string {
+
+	var nodePools []map[string]string
+	for _, prc := range ctx.CloudConfig.PoolRackConfigs {
+		var nodePool = map[string]string{}
+		nodePool["label"] = prc.Label
+		nodePool["name"] = ConstructNodePoolName(prc.Name)
+		nodePool["location"] = prc.Location
+		if config.ExpectedNodeCount > 0 {
+			nodePool["node_count"] = strconv.Itoa(config.ExpectedNodeCount)
+		}
+		nodePools = append(nodePools, nodePool)
+	}
+	return nodePools
+}
+
+// ConstructNodePoolName restricts a rack name to the lowercase alphanumeric 12 characters allowed for node pools.
+func ConstructNodePoolName(rackName string) string {
+	poolName := regexp.MustCompile(nodePoolNameDisallowed).ReplaceAllString(strings.ToLower(rackName), "")
+	if len(poolName) > maxNodePoolNameLength {
+		poolName = poolName[:maxNodePoolNameLength]
+	}
+	return poolName
+}
@@ -0,0 +1,55 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package clitest
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	// LogEnv names the file a fake CLI script appends its invocations to.
+	LogEnv = "FAKE_CLI_LOG"
+	// FailEnv, when set, makes a fake CLI script exit with an error.
+	FailEnv = "FAKE_CLI_FAIL"
+)
+
+// Install places the script as the binary first on the PATH, returning the file receiving its invocations.
+func Install(t *testing.T, binary string, script string) string {
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, binary), []byte(script), 0755))
+
+	logPath := filepath.Join(t.TempDir(), binary+".log")
+	t.Setenv(LogEnv, logPath)
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logPath
+}
+
+// Fail makes the installed script exit with an error.
+func Fail(t *testing.T) {
+	t.Setenv(FailEnv, "true")
+}
+
+// Invocations provides the invocations recorded in the log file, one per line.
+func Invocations(t *testing.T, logPath string) []string {
+	out, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(out)), "\n")
+}
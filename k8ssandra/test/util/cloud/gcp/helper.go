@@ -21,10 +21,91 @@ import (
 	_ "github.com/gruntwork-io/terratest/modules/gcp"
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
-	"testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"strings"
 )
 
+const (
+	Type = "gcp"
+
+	defaultIdentityDomain = "@community-ecosystem.iam.gserviceaccount.com"
+	defaultCredentialsKey = "GOOGLE_APPLICATION_CREDENTIALS"
+	defaultStorageType    = "google_storage"
+
+	// GKE cluster names are limited to 40 characters.
+	maxClusterNameLength = 40
+)
+
+// Provider is the GKE implementation of the cloud provider.
+type Provider struct{}
+
+func (Provider) Switch(t testing.TestingT, identity string, env map[string]string) bool {
+	return Switch(t, identity, env)
+}
+
+func (Provider) FetchCreds(t testing.TestingT, cloudConfig model.CloudConfig, env map[string]string, clusterName string) bool {
+	return FetchCreds(t, cloudConfig, env, clusterName)
+}
+
+func (Provider) ConstructFullContextName(contextName string, config model.CloudConfig) string {
+	return ConstructFullContextName(contextName, config)
+}
+
+func (Provider) ConstructCloudClusterName(contextName string, config model.CloudConfig) string {
+	return ConstructCloudClusterName(contextName, config)
+}
+
+func (Provider) ConstructServiceAccountName(contextName string, suffix string, config model.CloudConfig) string {
+	return ConstructServiceAccountName(contextName, suffix, config)
+}
+
+func (Provider) IdentityEnv(configPath string, identity string, config model.CloudConfig) map[string]string {
+	return map[string]string{
+		"KUBECONFIG":            configPath,
+		"GOOGLE_IDENTITY_EMAIL": identity,
+		defaultCredentialsKey:   config.CredPath,
+	}
+}
+
+func (Provider) TerraformVars(meta model.ProvisionMeta, config model.ReadinessConfig, name string,
+	ctx model.ContextConfig, kubeConfigPath string) map[string]interface{} {
+	return TerraformVars(meta, config, name, ctx, kubeConfigPath)
+}
+
+func (Provider) TerraformEnv(config model.CloudConfig) map[string]string {
+	return map[string]string{defaultCredentialsKey: config.CredPath}
+}
+
+func (Provider) IsLocationInRegion(location string, region string) bool {
+	return strings.HasPrefix(location, region+"-")
+}
+
+func (Provider) NodeZone(location string, config model.CloudConfig) string {
+	return location
+}
+
+func (Provider) MaxClusterNameLength() int {
+	return maxClusterNameLength
+}
+
+func (Provider) MaxRackLocations() int {
+	return 0
+}
+
+func (Provider) HasMasterCidrBlock() bool {
+	return true
+}
+
+func (Provider) MedusaStorage(config model.ReadinessConfig, name string, ctx model.ContextConfig) model.MedusaStorage {
+	return model.MedusaStorage{
+		StorageProvider: defaultStorageType,
+		BucketName:      strings.ToLower(ConstructCloudClusterName(name, ctx.CloudConfig) + "-storage-bucket"),
+		Region:          ctx.CloudConfig.Region,
+	}
+}
+
 func ConstructFullContextName(contextName string, config model.CloudConfig) string {
 	return "gke_" + config.Project + "_" + config.Region + "_" +
 		config.Environment + "-" + contextName
@@ -34,7 +115,11 @@ func ConstructCloudClusterName(contextName string, config model.CloudConfig) str
 	return config.Environment + "-" + contextName
 }
 
-func FetchCreds(t *testing.T, cloudConfig model.CloudConfig, env map[string]string, clusterName string) bool {
+func ConstructServiceAccountName(contextName string, suffix string, config model.CloudConfig) string {
+	return ConstructCloudClusterName(contextName, config) + "-" + suffix + defaultIdentityDomain
+}
+
+func FetchCreds(t testing.TestingT, cloudConfig model.CloudConfig, env map[string]string, clusterName string) bool {
 	region := cloudConfig.Region
 	project := cloudConfig.Project
 	args := []string{"container", "clusters", "get-credentials", clusterName, "--region", region, "--project", project}
@@ -45,7 +130,7 @@ func FetchCreds(t *testing.T, cloudConfig model.CloudConfig, env map[string]stri
 		Env:        env,
 		Logger:     logger.Default,
 	}
-	_, cmdErr := shell.RunCommandAndGetOutputE(t, cmd)
+	_, cmdErr := executor.RunShell(t, cmd)
 	if cmdErr != nil {
 		logger.Log(t, fmt.Sprintf("failed service account activation key create: %s", cmdErr))
 		return false
@@ -54,7 +139,7 @@ func FetchCreds(t *testing.T, cloudConfig model.CloudConfig, env map[string]stri
 
 }
 
-func Switch(t *testing.T, serviceAccount string, env map[string]string) bool {
+func Switch(t testing.TestingT, serviceAccount string, env map[string]string) bool {
 	args := []string{"config", "set", "account", serviceAccount}
 	var cmd = shell.Command{
 		Command:    "gcloud",
@@ -63,7 +148,7 @@ func Switch(t *testing.T, serviceAccount string, env map[string]string) bool {
 		Env:        env,
 		Logger:     logger.Default,
 	}
-	_, cmdErr := shell.RunCommandAndGetOutputE(t, cmd)
+	_, cmdErr := executor.RunShell(t, cmd)
 	if cmdErr != nil {
 		logger.Log(t, fmt.Sprintf("failed service account switch to: %s", serviceAccount))
 		return false
@@ -0,0 +1,48 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package gcp
+
+import (
+	"errors"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFetchCreds(t *testing.T) {
+	fake := executor.NewFake()
+	config := model.CloudConfig{Type: Type, Region: "us-central1", Project: "community-ecosystem"}
+	require.True(t, FetchCreds(executor.With(t, fake), config, map[string]string{"KUBECONFIG": "/tmp/kubeconfig"}, "dev-central"))
+
+	commands := fake.CommandsOf(executor.Shell)
+	require.Len(t, commands, 1)
+	require.Equal(t, "gcloud", commands[0].Binary)
+	require.Equal(t, []string{"container", "clusters", "get-credentials", "dev-central", "--region", "us-central1",
+		"--project", "community-ecosystem"}, commands[0].Args)
+	require.Equal(t, "/tmp/kubeconfig", commands[0].Env["KUBECONFIG"])
+}
+
+func TestSwitch(t *testing.T) {
+	fake := executor.NewFake()
+	require.True(t, Switch(executor.With(t, fake), "dev-central-sa@community-ecosystem.iam.gserviceaccount.com", map[string]string{}))
+	require.Equal(t, []string{"config", "set", "account", "dev-central-sa@community-ecosystem.iam.gserviceaccount.com"},
+		fake.Commands()[0].Args)
+
+	fake.Respond(executor.Shell, []string{"config"}, "", errors.New("unknown account"))
+	require.False(t, Switch(executor.With(t, fake), "unknown", map[string]string{}))
+}
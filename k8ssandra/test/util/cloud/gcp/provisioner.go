@@ -17,12 +17,69 @@ limitations under the License.
 package gcp
 
 import (
+	"fmt"
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"github.com/stretchr/testify/require"
+	"strings"
 )
 
 func RemoveBucket(t testing.TestingT, options *terraform.Options) {
-	destroyOut := terraform.Destroy(t, options)
+	destroyOut, err := executor.TerraformDestroy(t, options)
+	require.NoError(t, err, "expecting the bucket to be destroyed")
 	logger.Log(t, destroyOut)
 }
+
+// TerraformVars maps a context to the variables of the GKE env module.
+func TerraformVars(meta model.ProvisionMeta, config model.ReadinessConfig, name string,
+	ctx model.ContextConfig, kubeConfigPath string) map[string]interface{} {
+
+	uniqueClusterName := strings.ToLower(name)
+	saName := ConstructServiceAccountName(name, config.ServiceAccountNameSuffix, ctx.CloudConfig)
+	uniqueBucketName := strings.ToLower(fmt.Sprintf(ctx.CloudConfig.Bucket+"-%s", config.UniqueId))
+
+	vars := map[string]interface{}{
+		"project_id":              ctx.CloudConfig.Project,
+		"name":                    uniqueClusterName,
+		"machine_type":            ctx.CloudConfig.MachineType,
+		"environment":             ctx.CloudConfig.Environment,
+		"provision_id":            meta.ProvisionId,
+		"region":                  ctx.CloudConfig.Region,
+		"zone":                    ctx.CloudConfig.Region,
+		"node_pools":              createNodePools(ctx),
+		"node_locations":          ctx.CloudConfig.Locations,
+		"kubectl_config_path":     kubeConfigPath,
+		"initial_node_count":      config.ExpectedNodeCount,
+		"cluster_name":            uniqueClusterName,
+		"service_account":         saName,
+		"enable_private_endpoint": false,
+		"enable_private_nodes":    false,
+		"cidr_block":              ctx.NetworkConfig.SubnetCidrBlock,
+		"secondary_cidr_block":    ctx.NetworkConfig.SecondaryCidrBlock,
+		"master_ipv4_cidr_block":  ctx.NetworkConfig.MasterIpv4CidrBlock,
+		"bucket_policy_only":      true,
+		"role":                    "roles/storage.admin",
+		ctx.CloudConfig.Bucket:    uniqueBucketName,
+	}
+
+	if ctx.CloudConfig.KubernetesVersion != "" {
+		vars["kubernetes_version"] = ctx.CloudConfig.KubernetesVersion
+	}
+	return vars
+}
+
+func createNodePools(ctx model.ContextConfig) []map[string]interface{} {
+
+	var nodePools []map[string]interface{}
+	for _, prc := range ctx.CloudConfig.PoolRackConfigs {
+		var nodePool = map[string]interface{}{}
+		nodePool["label"] = prc.Label
+		nodePool["name"] = prc.Name
+		nodePool["location"] = prc.Location
+		nodePools = append(nodePools, nodePool)
+	}
+	return nodePools
+}
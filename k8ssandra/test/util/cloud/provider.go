@@ -0,0 +1,127 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package cloud
+
+import (
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/cloud/aws"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/cloud/azure"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/cloud/gcp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultType is used when a context does not declare a cloud type.
+const DefaultType = gcp.Type
+
+// CloudProvider captures the cloud specific activities required to provision and reach a context.
+type CloudProvider interface {
+
+	// Switch activates the identity used for subsequent cloud CLI calls.
+	Switch(t testing.TestingT, identity string, env map[string]string) bool
+
+	// FetchCreds merges credentials for the cloud cluster into the kube config referenced by env.
+	FetchCreds(t testing.TestingT, cloudConfig model.CloudConfig, env map[string]string, clusterName string) bool
+
+	// ConstructFullContextName provides the kube context name as written by FetchCreds.
+	ConstructFullContextName(contextName string, config model.CloudConfig) string
+
+	// ConstructCloudClusterName provides the cluster name as known by the cloud provider.
+	ConstructCloudClusterName(contextName string, config model.CloudConfig) string
+
+	// ConstructServiceAccountName provides the cloud identity assigned to the context.
+	ConstructServiceAccountName(contextName string, suffix string, config model.CloudConfig) string
+
+	// IdentityEnv provides the environment used for cloud CLI calls.
+	IdentityEnv(configPath string, identity string, config model.CloudConfig) map[string]string
+
+	// TerraformVars provides the variables for the cloud-specific Terraform env module.
+	TerraformVars(meta model.ProvisionMeta, config model.ReadinessConfig, name string,
+		ctx model.ContextConfig, kubeConfigPath string) map[string]interface{}
+
+	// TerraformEnv provides the credential environment for Terraform invocations.
+	TerraformEnv(config model.CloudConfig) map[string]string
+
+	// IsLocationInRegion indicates the zone location belongs to the region.
+	IsLocationInRegion(location string, region string) bool
+
+	// NodeZone provides the topology.kubernetes.io/zone label value of the nodes placed in the rack location.
+	NodeZone(location string, config model.CloudConfig) string
+
+	// MaxClusterNameLength provides the longest cloud cluster name accepted by the provider.
+	MaxClusterNameLength() int
+
+	// MaxRackLocations provides the most distinct rack locations of a context, zero when unlimited.
+	MaxRackLocations() int
+
+	// HasMasterCidrBlock indicates the cluster control plane is given the master CIDR block of the network config.
+	HasMasterCidrBlock() bool
+
+	// MedusaStorage provides the Medusa backup storage provisioned for the context bucket.
+	MedusaStorage(config model.ReadinessConfig, name string, ctx model.ContextConfig) model.MedusaStorage
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]CloudProvider{
+		gcp.Type:   gcp.Provider{},
+		aws.Type:   aws.Provider{},
+		azure.Type: azure.Provider{},
+	}
+)
+
+// Register adds or replaces the provider for a cloud type.
+func Register(cloudType string, provider CloudProvider) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry[strings.ToLower(cloudType)] = provider
+}
+
+// Lookup provides the provider registered for the cloud type, using the DefaultType when empty.
+func Lookup(cloudType string) (CloudProvider, error) {
+	if cloudType == "" {
+		cloudType = DefaultType
+	}
+
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	provider, found := registry[strings.ToLower(cloudType)]
+	if !found {
+		return nil, fmt.Errorf("unsupported cloud type: %s, expecting one of: %s", cloudType,
+			strings.Join(typesLocked(), ", "))
+	}
+	return provider, nil
+}
+
+// Types provides the registered cloud types.
+func Types() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	return typesLocked()
+}
+
+func typesLocked() []string {
+	var types []string
+	for cloudType := range registry {
+		types = append(types, cloudType)
+	}
+	sort.Strings(types)
+	return types
+}
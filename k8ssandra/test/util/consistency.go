@@ -0,0 +1,184 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"context"
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"time"
+)
+
+const (
+	CheckCqlConsistency = "cql-consistency"
+
+	defaultConsistencyKeyspace = "cloud_readiness"
+	defaultConsistencyTable    = "consistency"
+	defaultConsistencyRows     = 10
+	defaultConsistencyLevel    = "LOCAL_QUORUM"
+	defaultReplicationFactor   = 3
+	defaultSuperuserSuffix     = "-superuser"
+	defaultConsistencyInterval = time.Second
+
+	// cqlshScript writes the cqlshrc provided on stdin to a private temporary file, keeping the credentials out
+	// of the command line, then runs the statements of the first argument with it.
+	cqlshScript = `umask 077; cqlshrc=$(mktemp); cat > "$cqlshrc"; cqlsh --cqlshrc "$cqlshrc" -e "$1"; ` +
+		`status=$?; rm -f "$cqlshrc"; exit $status`
+)
+
+// checkCqlConsistency writes rows at LOCAL_QUORUM through the first datacenter, into a keyspace replicated to every
+// datacenter, and reads them back at LOCAL_QUORUM from every other datacenter. As a LOCAL_QUORUM write does not
+// wait for the remote datacenters, each read is retried up to the timeout, recording the time taken for the rows
+// to reach the datacenter. The statements are run with cqlsh in a Cassandra pod, authenticated with the
+// superuser secret of the cluster.
+func checkCqlConsistency(t testing.TestingT, clusterName string, datacenterNames []string,
+	targets map[string]datacenterTarget, sizes map[string]int, timeoutSecs int) []model.CheckResult {
+
+	writer := datacenterNames[0]
+	token := strings.ToLower(random.UniqueId())
+
+	out, err := runCqlsh(t, clusterName, targets[writer], consistencyWriteStatements(defaultConsistencyTable,
+		datacenterNames, sizes, token))
+	if err != nil {
+		return []model.CheckResult{checkResult(targets[writer].contextName, CheckCqlConsistency, writer, false,
+			fmt.Sprintf("unable to write at %s, %s %s", defaultConsistencyLevel, err.Error(), out))}
+	}
+	written := time.Now()
+	results := []model.CheckResult{checkResult(targets[writer].contextName, CheckCqlConsistency, writer, true,
+		fmt.Sprintf("wrote %d rows at %s", defaultConsistencyRows, defaultConsistencyLevel))}
+
+	readers := datacenterNames[1:]
+	if len(readers) == 0 {
+		readers = []string{writer}
+	}
+	for _, reader := range readers {
+		results = append(results, readConsistencyRows(t, clusterName, reader, targets[reader], writer, token,
+			written, timeoutSecs))
+	}
+	return results
+}
+
+// readConsistencyRows reads the rows of the token from the datacenter until every row is read or the timeout
+// passes, the result holding the time elapsed since the write.
+func readConsistencyRows(t testing.TestingT, clusterName string, reader string, target datacenterTarget, writer string,
+	token string, written time.Time, timeoutSecs int) model.CheckResult {
+
+	deadline := time.Now().Add(time.Duration(timeoutSecs) * time.Second)
+	for {
+		out, err := runCqlsh(t, clusterName, target, consistencyReadStatements(defaultConsistencyTable))
+		read := countRows(out, token)
+		elapsed := time.Since(written).Round(time.Millisecond)
+
+		message := fmt.Sprintf("read %d of %d rows at %s written through: %s after %s", read,
+			defaultConsistencyRows, defaultConsistencyLevel, writer, elapsed)
+		if err != nil {
+			message = fmt.Sprintf("unable to read at %s, %s %s", defaultConsistencyLevel, err.Error(), out)
+		}
+		result := checkResult(target.contextName, CheckCqlConsistency, reader,
+			err == nil && read == defaultConsistencyRows, message)
+		result.Duration = elapsed.String()
+
+		if result.Passed || time.Now().Add(defaultConsistencyInterval).After(deadline) {
+			return result
+		}
+		time.Sleep(defaultConsistencyInterval)
+	}
+}
+
+// runCqlsh runs the statements in the Cassandra pod of the target. The credentials are provided as a cqlshrc on
+// stdin, rather than as arguments, keeping them out of the logs and the cassette.
+func runCqlsh(t testing.TestingT, clusterName string, target datacenterTarget, statements string) (string, error) {
+	username, password, err := fetchSuperuser(t, target.kubeConfig, target.namespace, clusterName)
+	if err != nil {
+		return "", err
+	}
+
+	command := executor.KubectlCommand(target.kubeConfig, "exec", "-i", target.podName, "-n", target.namespace,
+		"-c", defaultCassandraContainer, "--", "sh", "-c", cqlshScript, "cqlsh", statements)
+	command.Stdin = fmt.Sprintf("[authentication]\nusername = %s\npassword = %s\n", username, password)
+	return executor.Run(t, command)
+}
+
+// fetchSuperuser provides the credentials of the superuser secret created by the operator for the cluster.
+func fetchSuperuser(t testing.TestingT, kubeConfig *k8s.KubectlOptions, namespace string,
+	clusterName string) (string, string, error) {
+
+	name := clusterName + defaultSuperuserSuffix
+	secret, err := KubeClient(t, kubeConfig).CoreV1().Secrets(namespace).Get(context.Background(), name,
+		metav1.GetOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("unable to fetch the superuser secret: %s, %w", name, err)
+	}
+	return string(secret.Data["username"]), string(secret.Data["password"]), nil
+}
+
+// consistencyReplication replicates to every datacenter, up to the default replication factor.
+func consistencyReplication(datacenterNames []string, sizes map[string]int) string {
+	replication := []string{"'class': 'NetworkTopologyStrategy'"}
+	for _, name := range datacenterNames {
+		factor := sizes[name]
+		if factor > defaultReplicationFactor || factor < 1 {
+			factor = defaultReplicationFactor
+		}
+		replication = append(replication, fmt.Sprintf("'%s': %d", name, factor))
+	}
+	return "{" + strings.Join(replication, ", ") + "}"
+}
+
+// consistencyWriteStatements replicate the keyspace to every datacenter, replacing the replication of a prior
+// run, and write the rows of the run token to the table.
+func consistencyWriteStatements(table string, datacenterNames []string, sizes map[string]int, token string) string {
+	replication := consistencyReplication(datacenterNames, sizes)
+	statements := []string{
+		fmt.Sprintf("CREATE KEYSPACE IF NOT EXISTS %s WITH replication = %s", defaultConsistencyKeyspace, replication),
+		fmt.Sprintf("ALTER KEYSPACE %s WITH replication = %s", defaultConsistencyKeyspace, replication),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.%s (id int PRIMARY KEY, token text)", defaultConsistencyKeyspace,
+			table),
+		"CONSISTENCY " + defaultConsistencyLevel,
+	}
+	for id := 0; id < defaultConsistencyRows; id++ {
+		statements = append(statements, fmt.Sprintf("INSERT INTO %s.%s (id, token) VALUES (%d, '%s')",
+			defaultConsistencyKeyspace, table, id, token))
+	}
+	return strings.Join(statements, "; ") + ";"
+}
+
+func consistencyReadStatements(table string) string {
+	var ids []string
+	for id := 0; id < defaultConsistencyRows; id++ {
+		ids = append(ids, fmt.Sprint(id))
+	}
+	return fmt.Sprintf("CONSISTENCY %s; SELECT token FROM %s.%s WHERE id IN (%s);", defaultConsistencyLevel,
+		defaultConsistencyKeyspace, table, strings.Join(ids, ", "))
+}
+
+// countRows counts the rows of the cqlsh output holding the token.
+func countRows(out string, token string) int {
+	var count int
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == token {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,120 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"testing"
+)
+
+func TestConsistencyWriteStatements(t *testing.T) {
+	statements := consistencyWriteStatements(defaultConsistencyTable, []string{"central", "kind"}, map[string]int{"central": 4, "kind": 1},
+		"qk9z7g")
+
+	require.True(t, strings.HasPrefix(statements, "CREATE KEYSPACE IF NOT EXISTS cloud_readiness WITH replication = "+
+		"{'class': 'NetworkTopologyStrategy', 'central': 3, 'kind': 1}; ALTER KEYSPACE cloud_readiness"), statements)
+	require.Contains(t, statements, "; CONSISTENCY LOCAL_QUORUM; INSERT INTO cloud_readiness.consistency "+
+		"(id, token) VALUES (0, 'qk9z7g');")
+	require.Equal(t, defaultConsistencyRows, strings.Count(statements, "INSERT INTO"))
+	require.True(t, strings.HasPrefix(consistencyReadStatements(defaultConsistencyTable), "CONSISTENCY LOCAL_QUORUM; SELECT token FROM "+
+		"cloud_readiness.consistency WHERE id IN (0, 1, "))
+}
+
+func TestCountRows(t *testing.T) {
+	out := "Consistency level set to LOCAL_QUORUM.\n\n token\n--------\n qk9z7g\n qk9z7g\n oldrun\n\n(3 rows)"
+	require.Equal(t, 2, countRows(out, "qk9z7g"))
+}
+
+func TestCheckCqlConsistency(t *testing.T) {
+	fake := executor.NewFake().RespondFunc(func(command executor.Command) bool {
+		return strings.Contains(strings.Join(command.Args, " "), "SELECT token")
+	}, "\n token\n--------\n\n(0 rows)", nil)
+	useFakeClient(t, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bootz-k8c-cluster-superuser", Namespace: "bootz"},
+		Data:       map[string][]byte{"username": []byte("bootz-k8c-cluster-superuser"), "password": []byte("s3cr3t")},
+	})
+	targets := map[string]datacenterTarget{
+		"central": {contextName: "central", namespace: "bootz", podName: "k8c-central-rack1-sts-0",
+			kubeConfig: k8s.NewKubectlOptions("gke_project_us-central1_dev-central", "", "bootz")},
+		"kind": {contextName: "kind", namespace: "bootz", podName: "k8c-kind-default-sts-0",
+			kubeConfig: k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz")},
+	}
+
+	results := checkCqlConsistency(executor.With(t, fake), "bootz-k8c-cluster", []string{"central", "kind"}, targets,
+		map[string]int{"central": 3, "kind": 1}, 0)
+	require.Equal(t, []string{"cql-consistency/central/passed", "cql-consistency/kind/failed"}, checkNames(results))
+	require.Contains(t, results[1].Message, "read 0 of 10 rows at LOCAL_QUORUM written through: central after ")
+	require.NotEmpty(t, results[1].Duration)
+
+	commands := fake.CommandsOf(executor.Kubectl, "exec")
+	require.Len(t, commands, 2)
+	require.Equal(t, "gke_project_us-central1_dev-central", commands[0].Context)
+	require.Equal(t, []string{"exec", "-i", "k8c-central-rack1-sts-0", "-n", "bootz", "-c", "cassandra", "--",
+		"sh", "-c", cqlshScript, "cqlsh"}, commands[0].Args[:12])
+	require.NotContains(t, strings.Join(commands[0].Args, " "), "s3cr3t",
+		"expecting the credentials to be kept out of the arguments")
+	require.Equal(t, "[authentication]\nusername = bootz-k8c-cluster-superuser\npassword = s3cr3t\n",
+		commands[0].Stdin)
+	require.Equal(t, "kind-k8ssandra-0", commands[1].Context)
+}
+
+func TestCheckCqlConsistencyRetriesRemoteRead(t *testing.T) {
+	var token string
+	var reads int
+	fake := executorFunc(func(command executor.Command) (string, error) {
+		statements := command.Args[len(command.Args)-1]
+		if strings.Contains(statements, "INSERT INTO") {
+			token = strings.Split(strings.SplitN(statements, "VALUES (0, '", 2)[1], "'")[0]
+		}
+		if strings.Contains(statements, "SELECT token") {
+			reads++
+			if reads > 1 {
+				return strings.Repeat(" "+token+"\n", defaultConsistencyRows), nil
+			}
+		}
+		return "", nil
+	})
+	useFakeClient(t, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bootz-k8c-cluster-superuser", Namespace: "bootz"},
+		Data:       map[string][]byte{"username": []byte("bootz-k8c-cluster-superuser"), "password": []byte("s3cr3t")},
+	})
+	targets := map[string]datacenterTarget{
+		"central": {contextName: "central", namespace: "bootz", podName: "k8c-central-rack1-sts-0",
+			kubeConfig: k8s.NewKubectlOptions("gke_project_us-central1_dev-central", "", "bootz")},
+		"kind": {contextName: "kind", namespace: "bootz", podName: "k8c-kind-default-sts-0",
+			kubeConfig: k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz")},
+	}
+
+	results := checkCqlConsistency(executor.With(t, fake), "bootz-k8c-cluster", []string{"central", "kind"}, targets,
+		map[string]int{"central": 3, "kind": 3}, 5)
+	require.Equal(t, []string{"cql-consistency/central/passed", "cql-consistency/kind/passed"}, checkNames(results))
+	require.Equal(t, 2, reads, "expecting the remote read to be retried until every row is read")
+	require.Contains(t, results[1].Message, "read 10 of 10 rows at LOCAL_QUORUM written through: central after ")
+}
+
+// executorFunc runs every command with the function.
+type executorFunc func(command executor.Command) (string, error)
+
+func (f executorFunc) Run(_ terratesting.TestingT, command executor.Command) (string, error) {
+	return f(command)
+}
@@ -0,0 +1,335 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const redactedValue = "REDACTED"
+
+// sensitiveEnvKeys are the fragments of env keys and helm value keys whose values are never written to a
+// cassette.
+var sensitiveEnvKeys = []string{"TOKEN", "SECRET", "PASSWORD", "KEY", "CREDENTIAL", "AUTH", "PRIVATE"}
+
+// sensitiveFlags are the flags whose values are never written to a cassette.
+var sensitiveFlags = []string{"-p", "--password", "--token", "--client-secret"}
+
+// valueFlags are the helm flags setting the value of a key, redacted when the key is sensitive.
+var valueFlags = []string{"--set", "--set-string"}
+
+// sensitiveLine matches an output line setting the value of a key, e.g. in YAML, redacted when the key is
+// sensitive.
+var sensitiveLine = regexp.MustCompile(`^(\s*(?:-\s+)?"?([\w.-]+)"?\s*[:=]\s*)\S.*$`)
+
+// Interaction is a recorded command along with its result.
+type Interaction struct {
+	Command Command `json:"command"`
+	Result  Result  `json:"result"`
+}
+
+// Cassette is the ordered record of the commands and Kubernetes API requests of a session.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+	Requests     []Exchange    `json:"requests,omitempty"`
+}
+
+// LoadCassette reads a cassette file.
+func LoadCassette(path string) (Cassette, error) {
+	var cassette Cassette
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cassette, err
+	}
+	if err := json.Unmarshal(content, &cassette); err != nil {
+		return cassette, fmt.Errorf("%s: %w", path, err)
+	}
+	return cassette, nil
+}
+
+// Save writes the cassette file, creating its directory when needed.
+func (c Cassette) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0600)
+}
+
+// Recorder runs the commands with its delegate, recording each command with a redacted env along with its
+// redacted result.
+type Recorder struct {
+	mutex    sync.Mutex
+	delegate Executor
+	cassette Cassette
+}
+
+func NewRecorder(delegate Executor) *Recorder {
+	return &Recorder{delegate: delegate}
+}
+
+func (r *Recorder) Run(t testing.TestingT, command Command) (string, error) {
+	var result Result
+	var err error
+
+	if detailed, isDetailed := r.delegate.(DetailedExecutor); isDetailed {
+		result, err = detailed.RunDetailed(t, command)
+	} else {
+		result.Combined, err = r.delegate.Run(t, command)
+		result.Stdout = result.Combined
+		if err != nil {
+			result.ExitCode = 1
+			result.Stderr = err.Error()
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{Command: Redact(command),
+		Result: RedactResult(result)})
+	return result.Combined, err
+}
+
+// Cassette provides the interactions recorded so far.
+func (r *Recorder) Cassette() Cassette {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return Cassette{
+		Interactions: append([]Interaction{}, r.cassette.Interactions...),
+		Requests:     append([]Exchange{}, r.cassette.Requests...),
+	}
+}
+
+// Replayer serves the results of a cassette. A command is served by the first unused interaction with the
+// same command, or when strict only by the next interaction in order. The env, working directory and kube
+// config path are not compared as they are redacted or differ between machines.
+type Replayer struct {
+	mutex        sync.Mutex
+	interactions []Interaction
+	used         []bool
+	requests     []Exchange
+	usedRequests []bool
+	Strict       bool
+}
+
+func NewReplayer(cassette Cassette) *Replayer {
+	return &Replayer{
+		interactions: cassette.Interactions,
+		used:         make([]bool, len(cassette.Interactions)),
+		requests:     cassette.Requests,
+		usedRequests: make([]bool, len(cassette.Requests)),
+	}
+}
+
+func (r *Replayer) Run(_ testing.TestingT, command Command) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, interaction := range r.interactions {
+		if r.used[i] {
+			continue
+		}
+		if isSameCommand(interaction.Command, Redact(command)) {
+			r.used[i] = true
+			return interaction.Result.Combined, interaction.Result.err()
+		}
+		if r.Strict {
+			return "", fmt.Errorf("replay expected command: %s, received: %s", interaction.Command, command)
+		}
+	}
+	return "", fmt.Errorf("replay has no recorded interaction for command: %s", command)
+}
+
+// Remaining provides the interactions not yet replayed.
+func (r *Replayer) Remaining() []Interaction {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var remaining []Interaction
+	for i, interaction := range r.interactions {
+		if !r.used[i] {
+			remaining = append(remaining, interaction)
+		}
+	}
+	return remaining
+}
+
+// Redact replaces the env values of sensitive keys, along with the argument values of sensitive flags and of
+// the helm values set for sensitive keys.
+func Redact(command Command) Command {
+	command.Args = redactArgs(command.Args)
+	if len(command.Env) == 0 {
+		return command
+	}
+
+	env := map[string]string{}
+	for key, value := range command.Env {
+		env[key] = value
+		if isSensitiveKey(key) {
+			env[key] = redactedValue
+		}
+	}
+	command.Env = env
+	return command
+}
+
+// RedactResult replaces the values of sensitive keys in the outputs of a result, e.g. in the values of a helm
+// release printed as JSON or YAML.
+func RedactResult(result Result) Result {
+	result.Stdout = redactOutput(result.Stdout)
+	result.Stderr = redactOutput(result.Stderr)
+	result.Combined = redactOutput(result.Combined)
+	return result
+}
+
+// redactOutput redacts the JSON document ending the output, e.g. following helm warnings, or otherwise every
+// line setting a sensitive key.
+func redactOutput(output string) string {
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			var document interface{}
+			if json.Unmarshal([]byte(strings.Join(lines[i:], "\n")), &document) == nil {
+				redacted, err := json.Marshal(redactDocument(document, false))
+				if err != nil {
+					return output
+				}
+				return strings.Join(append(lines[:i], string(redacted)), "\n")
+			}
+		}
+		if match := sensitiveLine.FindStringSubmatch(line); match != nil && isSensitiveKey(match[2]) {
+			lines[i] = match[1] + redactedValue
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// redactDocument replaces the scalar values of sensitive keys, every value below a sensitive key included.
+func redactDocument(document interface{}, isSensitive bool) interface{} {
+	switch value := document.(type) {
+	case map[string]interface{}:
+		for key, nested := range value {
+			value[key] = redactDocument(nested, isSensitive || isSensitiveKey(key))
+		}
+		return value
+	case []interface{}:
+		for i, nested := range value {
+			value[i] = redactDocument(nested, isSensitive)
+		}
+		return value
+	case nil:
+		return value
+	}
+	if isSensitive {
+		return redactedValue
+	}
+	return document
+}
+
+func redactArgs(args []string) []string {
+	if len(args) == 0 {
+		return args
+	}
+
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = arg
+		if i > 0 && contains(sensitiveFlags, args[i-1]) {
+			redacted[i] = redactedValue
+			continue
+		}
+		if i > 0 && contains(valueFlags, args[i-1]) {
+			redacted[i] = redactValues(arg)
+			continue
+		}
+
+		flag, value, hasValue := strings.Cut(arg, "=")
+		if hasValue && strings.HasPrefix(flag, "-") {
+			if contains(sensitiveFlags, flag) {
+				redacted[i] = flag + "=" + redactedValue
+			} else if contains(valueFlags, flag) {
+				redacted[i] = flag + "=" + redactValues(value)
+			}
+		}
+	}
+	return redacted
+}
+
+// redactValues redacts the comma separated helm values of sensitive keys.
+func redactValues(values string) string {
+	pairs := strings.Split(values, ",")
+	for i, pair := range pairs {
+		if key, _, hasValue := strings.Cut(pair, "="); hasValue && isSensitiveKey(key) {
+			pairs[i] = key + "=" + redactedValue
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+func isSensitiveKey(key string) bool {
+	upperKey := strings.ToUpper(key)
+	for _, sensitive := range sensitiveEnvKeys {
+		if strings.Contains(upperKey, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func isSameCommand(recorded Command, command Command) bool {
+	return recorded.Kind == command.Kind &&
+		recorded.binary() == command.binary() &&
+		recorded.Context == command.Context &&
+		recorded.Namespace == command.Namespace &&
+		reflect.DeepEqual(normalizeArgs(recorded.Args), normalizeArgs(command.Args))
+}
+
+func normalizeArgs(args []string) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	return args
+}
+
+func (r Result) err() error {
+	if r.ExitCode == 0 {
+		return nil
+	}
+	return fmt.Errorf("replayed exit status %d; %s", r.ExitCode, r.Stderr)
+}
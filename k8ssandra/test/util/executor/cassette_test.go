@@ -0,0 +1,169 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package executor
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	recorder := NewRecorder(Local{})
+
+	out, err := recorder.Run(t, Command{Kind: Shell, Binary: "sh", Args: []string{"-c", "echo ready"},
+		Env: map[string]string{"ARM_CLIENT_SECRET": "s3cr3t", "KUBECONFIG": "/tmp/kubeconfig"}})
+	require.NoError(t, err)
+	require.Equal(t, "ready", out)
+
+	_, err = recorder.Run(t, Command{Kind: Shell, Binary: "sh", Args: []string{"-c", "echo denied >&2; exit 3"}})
+	require.Error(t, err)
+
+	cassettePath := filepath.Join(t.TempDir(), "cassette.json")
+	require.NoError(t, recorder.Cassette().Save(cassettePath))
+
+	cassette, err := LoadCassette(cassettePath)
+	require.NoError(t, err)
+	require.Len(t, cassette.Interactions, 2)
+
+	first := cassette.Interactions[0]
+	require.Equal(t, redactedValue, first.Command.Env["ARM_CLIENT_SECRET"])
+	require.Equal(t, "/tmp/kubeconfig", first.Command.Env["KUBECONFIG"])
+	require.Equal(t, "ready", first.Result.Stdout)
+
+	second := cassette.Interactions[1]
+	require.Equal(t, 3, second.Result.ExitCode)
+	require.Equal(t, "denied", second.Result.Stderr)
+	require.Empty(t, second.Result.Stdout)
+
+	replayer := NewReplayer(cassette)
+	_, err = replayer.Run(t, Command{Kind: Shell, Binary: "sh", Args: []string{"-c", "echo denied >&2; exit 3"}})
+	require.Error(t, err)
+	out, err = replayer.Run(t, Command{Kind: Shell, Binary: "sh", Args: []string{"-c", "echo ready"}})
+	require.NoError(t, err)
+	require.Equal(t, "ready", out)
+	require.Empty(t, replayer.Remaining())
+
+	_, err = replayer.Run(t, Command{Kind: Shell, Binary: "sh", Args: []string{"-c", "echo ready"}})
+	require.Error(t, err, "expecting an interaction to be replayed only once")
+}
+
+func TestStrictReplay(t *testing.T) {
+	cassette := Cassette{Interactions: []Interaction{
+		{Command: Command{Kind: Kubectl, Context: "kind-k8ssandra-0", Args: []string{"get", "pods"}}},
+		{Command: Command{Kind: Kubectl, Context: "kind-k8ssandra-0", Args: []string{"get", "events"}}},
+	}}
+
+	replayer := NewReplayer(cassette)
+	replayer.Strict = true
+	_, err := replayer.Run(t, Command{Kind: Kubectl, Context: "kind-k8ssandra-0", Args: []string{"get", "events"}})
+	require.Error(t, err, "expecting an out of order command to fail a strict replay")
+
+	_, err = replayer.Run(t, Command{Kind: Kubectl, Context: "kind-k8ssandra-1", Args: []string{"get", "pods"}})
+	require.Error(t, err, "expecting the context to be compared")
+
+	_, err = replayer.Run(t, Command{Kind: Kubectl, Context: "kind-k8ssandra-0", Args: []string{"get", "pods"},
+		ConfigPath: "/home/ci/.kube/config"})
+	require.NoError(t, err)
+	require.Len(t, replayer.Remaining(), 1)
+}
+
+func TestRecorderWithoutDetailedDelegate(t *testing.T) {
+	fake := NewFake().Respond(Helm, []string{"status"}, "", errors.New("release: not found"))
+	recorder := NewRecorder(fake)
+
+	_, err := recorder.Run(t, Command{Kind: Helm, Args: []string{"status", "traefik"}})
+	require.Error(t, err)
+
+	interactions := recorder.Cassette().Interactions
+	require.Len(t, interactions, 1)
+	require.Equal(t, 1, interactions[0].Result.ExitCode)
+	require.Equal(t, "release: not found", interactions[0].Result.Stderr)
+}
+
+func TestRedact(t *testing.T) {
+	command := Command{Kind: Shell, Env: map[string]string{
+		"AWS_SECRET_ACCESS_KEY":          "secret",
+		"GOOGLE_APPLICATION_CREDENTIALS": "/home/tester/key.json",
+		"AWS_REGION":                     "us-east-1",
+	}}
+
+	redacted := Redact(command)
+	require.Equal(t, redactedValue, redacted.Env["AWS_SECRET_ACCESS_KEY"])
+	require.Equal(t, redactedValue, redacted.Env["GOOGLE_APPLICATION_CREDENTIALS"])
+	require.Equal(t, "us-east-1", redacted.Env["AWS_REGION"])
+	require.Equal(t, "secret", command.Env["AWS_SECRET_ACCESS_KEY"], "expecting the command not to be modified")
+}
+
+func TestRedactArgs(t *testing.T) {
+	command := Command{Kind: Helm, Args: []string{"install", "minio", "bitnami/minio",
+		"--set", "auth.rootPassword=s3cr3t", "--set", "defaultBuckets=k8c-medusa",
+		"--set=secretKey=s3cr3t,region=us-east-1", "--set-string", "persistence.enabled=false"}}
+
+	redacted := Redact(command)
+	require.Equal(t, []string{"install", "minio", "bitnami/minio",
+		"--set", "auth.rootPassword=REDACTED", "--set", "defaultBuckets=k8c-medusa",
+		"--set=secretKey=REDACTED,region=us-east-1", "--set-string", "persistence.enabled=false"}, redacted.Args)
+	require.Equal(t, "auth.rootPassword=s3cr3t", command.Args[4], "expecting the command not to be modified")
+
+	cqlsh := Redact(Command{Kind: Kubectl, Args: []string{"exec", "dc1-rack1-sts-0", "--", "cqlsh",
+		"-u", "superuser", "-p", "s3cr3t", "--token=t0k3n", "--password", "s3cr3t", "-e", "SELECT now() FROM system.local"}})
+	require.Equal(t, []string{"exec", "dc1-rack1-sts-0", "--", "cqlsh",
+		"-u", "superuser", "-p", redactedValue, "--token=" + redactedValue, "--password", redactedValue,
+		"-e", "SELECT now() FROM system.local"}, cqlsh.Args)
+}
+
+func TestRecordRedactedResult(t *testing.T) {
+	values := "WARNING: kube config is group-readable\n" +
+		`{"auth":{"rootPassword":"s3cr3t","rootUser":"admin"},"defaultBuckets":"k8c-medusa"}`
+	recorder := NewRecorder(NewFake().
+		Respond(Helm, []string{"get", "values", "minio", "-o", "json"}, values, nil).
+		Respond(Helm, []string{"get", "values", "minio"}, "auth:\n  rootPassword: s3cr3t\ndefaultBuckets: k8c-medusa", nil))
+
+	out, err := recorder.Run(t, Command{Kind: Helm, Args: []string{"get", "values", "minio", "-o", "json"}})
+	require.NoError(t, err)
+	require.Equal(t, values, out, "expecting the output to be redacted in the cassette only")
+	_, err = recorder.Run(t, Command{Kind: Helm, Args: []string{"get", "values", "minio"}})
+	require.NoError(t, err)
+
+	interactions := recorder.Cassette().Interactions
+	require.Equal(t, "WARNING: kube config is group-readable\n"+
+		`{"auth":{"rootPassword":"REDACTED","rootUser":"REDACTED"},"defaultBuckets":"k8c-medusa"}`,
+		interactions[0].Result.Combined)
+	require.Equal(t, "auth:\n  rootPassword: REDACTED\ndefaultBuckets: k8c-medusa", interactions[1].Result.Stdout)
+}
+
+func TestReplayRedactedArgs(t *testing.T) {
+	command := Command{Kind: Helm, Args: []string{"install", "minio", "--set", "auth.rootPassword=s3cr3t"}}
+	recorder := NewRecorder(NewFake().Respond(Helm, []string{"install"}, "deployed", nil))
+	_, err := recorder.Run(t, command)
+	require.NoError(t, err)
+
+	cassettePath := filepath.Join(t.TempDir(), "cassette.json")
+	require.NoError(t, recorder.Cassette().Save(cassettePath))
+	content, err := os.ReadFile(cassettePath)
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "s3cr3t")
+
+	cassette, err := LoadCassette(cassettePath)
+	require.NoError(t, err)
+	out, err := NewReplayer(cassette).Run(t, command)
+	require.NoError(t, err, "expecting the redacted command to be replayed")
+	require.Equal(t, "deployed", out)
+}
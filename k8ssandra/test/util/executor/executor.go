@@ -0,0 +1,394 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Kind of external command invoked.
+type Kind string
+
+const (
+	Kubectl   Kind = "kubectl"
+	Helm      Kind = "helm"
+	Terraform Kind = "terraform"
+	Shell     Kind = "shell"
+)
+
+// InterruptGracePeriod is the time a command interrupted at the deadline of its test is given to stop, e.g. for
+// terraform to release its state lock, before being killed.
+const InterruptGracePeriod = 30 * time.Second
+
+// Command is an external invocation. The context, kube config and namespace are kept apart from the
+// arguments, allowing them to be asserted on directly. The stdin, e.g. holding credentials, is never written
+// to a cassette.
+type Command struct {
+	Kind       Kind              `json:"kind"`
+	Binary     string            `json:"binary,omitempty"`
+	Args       []string          `json:"args,omitempty"`
+	Context    string            `json:"context,omitempty"`
+	ConfigPath string            `json:"config_path,omitempty"`
+	Namespace  string            `json:"namespace,omitempty"`
+	WorkingDir string            `json:"working_dir,omitempty"`
+	Env        map[string]string `json:"env,omitempty"`
+	Stdin      string            `json:"-"`
+}
+
+// Executor runs the external commands, providing the combined output.
+type Executor interface {
+	Run(t testing.TestingT, command Command) (string, error)
+}
+
+// Result of a command with its outputs kept apart.
+type Result struct {
+	Stdout   string `json:"stdout,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	Combined string `json:"combined,omitempty"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// DetailedExecutor is an executor able to provide the separate outputs and exit code of a command.
+type DetailedExecutor interface {
+	Executor
+	RunDetailed(t testing.TestingT, command Command) (Result, error)
+}
+
+// Local runs the commands as local processes.
+type Local struct{}
+
+func (l Local) Run(t testing.TestingT, command Command) (string, error) {
+	result, err := l.RunDetailed(t, command)
+	return result.Combined, err
+}
+
+func (Local) RunDetailed(t testing.TestingT, command Command) (Result, error) {
+	shellCommand := command.ShellCommand()
+	logged := Redact(command).ShellCommand()
+	logger.Logf(t, "Running command %s with args %s", logged.Command, logged.Args)
+
+	cmd := exec.Command(shellCommand.Command, shellCommand.Args...)
+	cmd.Dir = shellCommand.WorkingDir
+	cmd.Stdin = os.Stdin
+	if command.Stdin != "" {
+		cmd.Stdin = strings.NewReader(command.Stdin)
+	}
+	cmd.Env = os.Environ()
+	for key, value := range shellCommand.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+
+	var stdout, stderr, combined bytes.Buffer
+	cmd.Stdout = io.MultiWriter(&stdout, &combined)
+	cmd.Stderr = io.MultiWriter(&stderr, &combined)
+
+	ctx, cancel := commandContext(t)
+	defer cancel()
+	err := runUntilDone(ctx, cmd)
+	result := Result{
+		Stdout:   strings.TrimSuffix(stdout.String(), "\n"),
+		Stderr:   strings.TrimSuffix(stderr.String(), "\n"),
+		Combined: strings.TrimSuffix(combined.String(), "\n"),
+	}
+	if result.Combined != "" {
+		logger.Log(t, result.Combined)
+	}
+
+	if err == nil {
+		return result, nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+	} else {
+		result.ExitCode = -1
+	}
+	return result, fmt.Errorf("error while running command: %w; %s", err, result.Stderr)
+}
+
+// commandContext provides the context of a command, done at the deadline of t when known.
+func commandContext(t testing.TestingT) (context.Context, context.CancelFunc) {
+	if limited, ok := Unwrap(t).(interface{ Deadline() (time.Time, bool) }); ok {
+		if deadline, hasDeadline := limited.Deadline(); hasDeadline {
+			return context.WithDeadline(context.Background(), deadline)
+		}
+	}
+	return context.WithCancel(context.Background())
+}
+
+// runUntilDone runs the command, interrupting it once the context is done and killing it when still running
+// after the interrupt grace period, so that no command outlives a timed out run.
+func runUntilDone(ctx context.Context, cmd *exec.Cmd) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	waited := make(chan error, 1)
+	go func() {
+		waited <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-waited:
+		return err
+	case <-ctx.Done():
+	}
+
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		_ = cmd.Process.Kill()
+	}
+	select {
+	case err := <-waited:
+		return fmt.Errorf("%w: %v", ctx.Err(), err)
+	case <-time.After(InterruptGracePeriod):
+		_ = cmd.Process.Kill()
+		return fmt.Errorf("%w: %v", ctx.Err(), <-waited)
+	}
+}
+
+// carrier is a testing.TestingT carrying the executor of the package functions called with it.
+type carrier struct {
+	testing.TestingT
+	executor Executor
+}
+
+// With provides t carrying the executor, used by the package functions called with it and by the nested
+// activities run by the util package.  The executor is not shared with other tests, e.g. parallel subtests.
+func With(t testing.TestingT, executor Executor) testing.TestingT {
+	return carrier{TestingT: Unwrap(t), executor: executor}
+}
+
+// Inherit provides t carrying the executor of parent, e.g. for a subtest of parent, t as is when parent
+// carries no executor.
+func Inherit(parent testing.TestingT, t testing.TestingT) testing.TestingT {
+	if c, ok := parent.(carrier); ok {
+		return With(t, c.executor)
+	}
+	return t
+}
+
+// For provides the executor carried by t, the local executor otherwise.
+func For(t testing.TestingT) Executor {
+	if c, ok := t.(carrier); ok {
+		return c.executor
+	}
+	return Local{}
+}
+
+// Unwrap provides the testing.TestingT carrying the executor, t as is when it carries none.
+func Unwrap(t testing.TestingT) testing.TestingT {
+	if c, ok := t.(carrier); ok {
+		return c.TestingT
+	}
+	return t
+}
+
+// Run runs the command with the executor carried by t.
+func Run(t testing.TestingT, command Command) (string, error) {
+	return For(t).Run(t, command)
+}
+
+// RunKubectl runs kubectl with the context, kube config and namespace of the options.
+func RunKubectl(t testing.TestingT, options *k8s.KubectlOptions, args ...string) (string, error) {
+	return Run(t, KubectlCommand(options, args...))
+}
+
+// RunHelm runs helm with the context, kube config and namespace of the options, the first argument is the
+// helm command.
+func RunHelm(t testing.TestingT, options *helm.Options, args ...string) (string, error) {
+	return Run(t, HelmCommand(options, args...))
+}
+
+// RunTerraform runs terraform in the directory of the options with the formatted arguments, retrying the
+// errors matching the retryable errors of the options as terratest does.
+func RunTerraform(t testing.TestingT, options *terraform.Options, args ...string) (string, error) {
+	command := TerraformCommand(options, args...)
+	description := fmt.Sprintf("%s %v", command.binary(), command.Args)
+
+	var lastErr error
+	out, err := retry.DoWithRetryableErrorsE(t, description, options.RetryableTerraformErrors, options.MaxRetries,
+		options.TimeBetweenRetries, func() (string, error) {
+			out, err := Run(t, command)
+			lastErr = err
+			return out, err
+		})
+
+	// The errors are reported as the command failed, keeping them classifiable by their message.
+	var fatalErr retry.FatalError
+	var exceededErr retry.MaxRetriesExceeded
+	switch {
+	case errors.As(err, &fatalErr):
+		return out, fatalErr.Underlying
+	case errors.As(err, &exceededErr):
+		return out, fmt.Errorf("%s: %w", exceededErr.Error(), lastErr)
+	}
+	return out, err
+}
+
+// RunShell runs an arbitrary command, e.g. the gcloud, aws or az cli.
+func RunShell(t testing.TestingT, command shell.Command) (string, error) {
+	return Run(t, ShellCommand(command))
+}
+
+// TerraformInit runs terraform init with the backend configuration of the options.
+func TerraformInit(t testing.TestingT, options *terraform.Options) (string, error) {
+	args := []string{"init", fmt.Sprintf("-upgrade=%t", options.Upgrade)}
+	if options.Reconfigure {
+		args = append(args, "-reconfigure")
+	}
+	args = append(args, terraform.FormatTerraformBackendConfigAsArgs(options.BackendConfig)...)
+	args = append(args, terraform.FormatTerraformPluginDirAsArgs(options.PluginDir)...)
+	return RunTerraform(t, options, args...)
+}
+
+// TerraformInitAndPlan runs terraform init followed by plan, providing the output of both.
+func TerraformInitAndPlan(t testing.TestingT, options *terraform.Options) (string, error) {
+	initOut, err := TerraformInit(t, options)
+	if err != nil {
+		return initOut, err
+	}
+	planOut, err := RunTerraform(t, options, terraform.FormatArgs(options, "plan", "-input=false", "-lock=false")...)
+	return initOut + planOut, err
+}
+
+// TerraformApply runs terraform apply with the variables of the options.
+func TerraformApply(t testing.TestingT, options *terraform.Options) (string, error) {
+	return RunTerraform(t, options, terraform.FormatArgs(options, "apply", "-input=false", "-auto-approve")...)
+}
+
+// TerraformDestroy runs terraform destroy with the variables of the options.
+func TerraformDestroy(t testing.TestingT, options *terraform.Options) (string, error) {
+	return RunTerraform(t, options, terraform.FormatArgs(options, "destroy", "-auto-approve", "-input=false")...)
+}
+
+// KubectlCommand provides the kubectl command of the options.
+func KubectlCommand(options *k8s.KubectlOptions, args ...string) Command {
+	command := Command{Kind: Kubectl, Binary: string(Kubectl), Args: args}
+	if options != nil {
+		command.Context = options.ContextName
+		command.ConfigPath = options.ConfigPath
+		command.Namespace = options.Namespace
+		command.Env = options.Env
+	}
+	return command
+}
+
+// HelmCommand provides the helm command of the options.
+func HelmCommand(options *helm.Options, args ...string) Command {
+	command := Command{Kind: Helm, Binary: string(Helm), Args: args}
+	if options != nil {
+		command.Env = options.EnvVars
+		if options.KubectlOptions != nil {
+			command.Context = options.KubectlOptions.ContextName
+			command.ConfigPath = options.KubectlOptions.ConfigPath
+			command.Namespace = options.KubectlOptions.Namespace
+		}
+	}
+	return command
+}
+
+// TerraformCommand provides the terraform command of the options, defaulting the binary as terratest does.
+func TerraformCommand(options *terraform.Options, args ...string) Command {
+	options, args = terraform.GetCommonOptions(options, args...)
+	return Command{
+		Kind:       Terraform,
+		Binary:     options.TerraformBinary,
+		Args:       args,
+		WorkingDir: options.TerraformDir,
+		Env:        options.EnvVars,
+	}
+}
+
+// ShellCommand provides the command of a shell invocation.
+func ShellCommand(command shell.Command) Command {
+	return Command{
+		Kind:       Shell,
+		Binary:     command.Command,
+		Args:       command.Args,
+		WorkingDir: command.WorkingDir,
+		Env:        command.Env,
+	}
+}
+
+// ShellCommand provides the process invocation of the command, placing the context, kube config and
+// namespace flags as kubectl and helm expect them.
+func (c Command) ShellCommand() shell.Command {
+	var args []string
+	switch c.Kind {
+	case Kubectl:
+		args = append(c.contextArgs("--context"), c.Args...)
+	case Helm:
+		if len(c.Args) > 0 {
+			args = append([]string{c.Args[0]}, c.contextArgs("--kube-context")...)
+			args = append(args, c.Args[1:]...)
+		}
+	default:
+		args = c.Args
+	}
+
+	return shell.Command{
+		Command:    c.binary(),
+		Args:       args,
+		WorkingDir: c.WorkingDir,
+		Env:        c.Env,
+	}
+}
+
+func (c Command) String() string {
+	command := c.ShellCommand()
+	return strings.TrimSpace(command.Command + " " + strings.Join(command.Args, " "))
+}
+
+func (c Command) binary() string {
+	if c.Binary != "" {
+		return c.Binary
+	}
+	return string(c.Kind)
+}
+
+func (c Command) contextArgs(contextFlag string) []string {
+	var args []string
+	if c.Context != "" {
+		args = append(args, contextFlag, c.Context)
+	}
+	if c.ConfigPath != "" {
+		args = append(args, "--kubeconfig", c.ConfigPath)
+	}
+	if c.Namespace != "" {
+		args = append(args, "--namespace", c.Namespace)
+	}
+	return args
+}
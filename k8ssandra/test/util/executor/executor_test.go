@@ -0,0 +1,167 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestKubectlCommand(t *testing.T) {
+	command := KubectlCommand(k8s.NewKubectlOptions("kind-k8ssandra-0", "/home/tester/.kube/config", "bootz"),
+		"get", "pods")
+
+	require.Equal(t, Kubectl, command.Kind)
+	require.Equal(t, "kind-k8ssandra-0", command.Context)
+	require.Equal(t, "bootz", command.Namespace)
+	require.Equal(t, []string{"get", "pods"}, command.Args)
+	require.Equal(t, "kubectl --context kind-k8ssandra-0 --kubeconfig /home/tester/.kube/config "+
+		"--namespace bootz get pods", command.String())
+}
+
+func TestHelmCommand(t *testing.T) {
+	options := &helm.Options{
+		KubectlOptions: k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz"),
+		EnvVars:        map[string]string{"K8C_ENV": "dev"},
+	}
+	command := HelmCommand(options, "install", "traefik", "traefik/traefik")
+
+	require.Equal(t, Helm, command.Kind)
+	require.Equal(t, "dev", command.Env["K8C_ENV"])
+	require.Equal(t, "helm install --kube-context kind-k8ssandra-0 --namespace bootz traefik traefik/traefik",
+		command.String())
+}
+
+func TestTerraformCommand(t *testing.T) {
+	options := &terraform.Options{
+		TerraformDir: "/tmp/TestK8cSmoke1234/env",
+		Vars:         map[string]interface{}{"environment": "dev"},
+		NoColor:      true,
+	}
+	command := TerraformCommand(options, terraform.FormatArgs(options, "apply", "-input=false")...)
+
+	require.Equal(t, Terraform, command.Kind)
+	require.Equal(t, "terraform", command.Binary)
+	require.Equal(t, "/tmp/TestK8cSmoke1234/env", command.WorkingDir)
+	require.Equal(t, []string{"apply", "-input=false", "-var", "environment=dev", "-no-color", "-lock=false"}, command.Args)
+}
+
+func TestWithCarriesExecutor(t *testing.T) {
+	fake := NewFake().Respond(Shell, []string{"config", "set"}, "Updated property", nil)
+	carrying := With(t, fake)
+
+	out, err := RunShell(carrying, shell.Command{Command: "gcloud", Args: []string{"config", "set", "account", "sa"}})
+	require.NoError(t, err)
+	require.Equal(t, "Updated property", out)
+	require.Len(t, fake.Commands(), 1)
+
+	require.Equal(t, t, Unwrap(carrying))
+	require.Equal(t, fake, For(Inherit(carrying, t)), "expecting a subtest to inherit the executor")
+	_, isLocal := For(t).(Local)
+	require.True(t, isLocal, "expecting the local executor without a carried executor")
+}
+
+func TestRunTerraformRetries(t *testing.T) {
+	var attempts int
+	fake := NewFake().RespondFunc(func(command Command) bool {
+		attempts++
+		return attempts == 1
+	}, "Error installing provider: connection reset by peer", errors.New("exit status 1"))
+	options := &terraform.Options{
+		TerraformDir:             "/tmp/TestK8cSmoke1234/env",
+		RetryableTerraformErrors: map[string]string{".*connection reset by peer.*": "transient network error"},
+		MaxRetries:               1,
+	}
+
+	_, err := TerraformInit(With(t, fake), options)
+	require.NoError(t, err)
+	require.Len(t, fake.CommandsOf(Terraform, "init"), 2, "expecting the retryable error to be retried")
+}
+
+func TestRunTerraformReportsCommandError(t *testing.T) {
+	fake := NewFake().Respond(Terraform, []string{"apply"}, "Error: quota exceeded", errors.New("exit status 1"))
+	options := &terraform.Options{
+		TerraformDir:             "/tmp/TestK8cSmoke1234/env",
+		RetryableTerraformErrors: map[string]string{".*connection reset by peer.*": "transient network error"},
+		MaxRetries:               1,
+	}
+
+	_, err := TerraformApply(With(t, fake), options)
+	require.EqualError(t, err, "exit status 1", "expecting the error of the command, not a retry error")
+	require.Len(t, fake.Commands(), 1)
+
+	fake = NewFake().Respond(Terraform, []string{"apply"}, "connection reset by peer", errors.New("exit status 1"))
+	_, err = TerraformApply(With(t, fake), options)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "exit status 1")
+	require.Len(t, fake.Commands(), 2)
+}
+
+func TestFakeResponses(t *testing.T) {
+	fake := NewFake().
+		Respond(Kubectl, []string{"get", "ep"}, "10.0.0.1", nil).
+		Respond(Kubectl, []string{"get"}, "", errors.New("not found"))
+
+	out, err := fake.Run(t, Command{Kind: Kubectl, Args: []string{"get", "ep", "webhook"}})
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.1", out)
+
+	_, err = fake.Run(t, Command{Kind: Kubectl, Args: []string{"get", "pods"}})
+	require.Error(t, err)
+
+	out, err = fake.Run(t, Command{Kind: Helm, Args: []string{"get", "values"}})
+	require.NoError(t, err)
+	require.Empty(t, out)
+
+	require.Len(t, fake.CommandsOf(Kubectl), 2)
+	require.Len(t, fake.CommandsOf(Kubectl, "get", "ep"), 1)
+	require.Len(t, fake.CommandsOf(Helm), 1)
+}
+
+func TestLocal(t *testing.T) {
+	out, err := Local{}.Run(t, Command{Kind: Shell, Binary: "echo", Args: []string{"ready"}})
+	require.NoError(t, err)
+	require.Equal(t, "ready", out)
+}
+
+// limitedT is a test timing out at its deadline, as the phase runner of the command line.
+type limitedT struct {
+	*testing.T
+	deadline time.Time
+}
+
+func (l limitedT) Deadline() (time.Time, bool) {
+	return l.deadline, true
+}
+
+func TestLocalInterruptsAtDeadline(t *testing.T) {
+	started := time.Now()
+	_, err := Local{}.Run(limitedT{T: t, deadline: started.Add(100 * time.Millisecond)},
+		Command{Kind: Shell, Binary: "sleep", Args: []string{"30"}})
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "expecting the command interrupted at the deadline")
+	require.Less(t, time.Since(started), InterruptGracePeriod)
+
+	_, err = Local{}.Run(limitedT{T: t, deadline: started}, Command{Kind: Shell, Binary: "echo"})
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "expecting no command started past the deadline")
+}
@@ -0,0 +1,99 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package executor
+
+import (
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"sync"
+)
+
+// Fake is a scripted executor recording every command it is asked to run. Commands without a scripted
+// response succeed with an empty output.
+type Fake struct {
+	mutex     sync.Mutex
+	responses []response
+	commands  []Command
+}
+
+type response struct {
+	matches func(command Command) bool
+	output  string
+	err     error
+}
+
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+// Respond scripts the output and error of the commands of the kind whose arguments start with the
+// provided arguments. The first matching response applies.
+func (f *Fake) Respond(kind Kind, args []string, output string, err error) *Fake {
+	return f.RespondFunc(func(command Command) bool {
+		return command.Kind == kind && hasPrefix(command.Args, args)
+	}, output, err)
+}
+
+// RespondFunc scripts the output and error of the commands matched by the function.
+func (f *Fake) RespondFunc(matches func(command Command) bool, output string, err error) *Fake {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.responses = append(f.responses, response{matches: matches, output: output, err: err})
+	return f
+}
+
+func (f *Fake) Run(_ testing.TestingT, command Command) (string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.commands = append(f.commands, command)
+	for _, r := range f.responses {
+		if r.matches(command) {
+			return r.output, r.err
+		}
+	}
+	return "", nil
+}
+
+// Commands provides the commands run, in order.
+func (f *Fake) Commands() []Command {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]Command{}, f.commands...)
+}
+
+// CommandsOf provides the commands of the kind run whose arguments start with the provided arguments.
+func (f *Fake) CommandsOf(kind Kind, args ...string) []Command {
+	var commands []Command
+	for _, command := range f.Commands() {
+		if command.Kind == kind && hasPrefix(command.Args, args) {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+func hasPrefix(args []string, prefix []string) bool {
+	if len(prefix) > len(args) {
+		return false
+	}
+	for i := range prefix {
+		if args[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,198 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package executor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// Exchange is a recorded Kubernetes API request along with its response. Only the context, method and
+// request URI are compared on replay; the bodies are kept for reference, with secret data redacted.
+type Exchange struct {
+	Context      string `json:"context,omitempty"`
+	Method       string `json:"method"`
+	URI          string `json:"uri"`
+	RequestBody  string `json:"request_body,omitempty"`
+	StatusCode   int    `json:"status_code"`
+	ResponseBody string `json:"response_body,omitempty"`
+}
+
+func (e Exchange) String() string {
+	return fmt.Sprintf("%s %s (context: %s)", e.Method, e.URI, e.Context)
+}
+
+// Transport wraps the transport of a Kubernetes client of the context, recording its requests.
+func (r *Recorder) Transport(context string, delegate http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		requestBody, err := readBody(&request.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := delegate.RoundTrip(request)
+		if err != nil {
+			return nil, err
+		}
+		responseBody, err := readBody(&response.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.cassette.Requests = append(r.cassette.Requests, Exchange{
+			Context:      context,
+			Method:       request.Method,
+			URI:          request.URL.RequestURI(),
+			RequestBody:  RedactBody(requestBody),
+			StatusCode:   response.StatusCode,
+			ResponseBody: RedactBody(responseBody),
+		})
+		return response, nil
+	})
+}
+
+// Transport serves the recorded requests of the context, each by the first unused exchange with the same
+// method and request URI, or when strict only by the next exchange of the context in order.
+func (r *Replayer) Transport(context string) http.RoundTripper {
+	return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		uri := request.URL.RequestURI()
+		for i, exchange := range r.requests {
+			if r.usedRequests[i] || exchange.Context != context {
+				continue
+			}
+			if exchange.Method == request.Method && exchange.URI == uri {
+				r.usedRequests[i] = true
+				return &http.Response{
+					StatusCode: exchange.StatusCode,
+					Status:     fmt.Sprintf("%d %s", exchange.StatusCode, http.StatusText(exchange.StatusCode)),
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       ioutil.NopCloser(bytes.NewBufferString(exchange.ResponseBody)),
+					Request:    request,
+				}, nil
+			}
+			if r.Strict {
+				return nil, fmt.Errorf("replay expected request: %s, received: %s %s", exchange, request.Method, uri)
+			}
+		}
+		return nil, fmt.Errorf("replay has no recorded request for: %s %s (context: %s)", request.Method, uri,
+			context)
+	})
+}
+
+// RemainingRequests provides the recorded requests not yet replayed.
+func (r *Replayer) RemainingRequests() []Exchange {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var remaining []Exchange
+	for i, exchange := range r.requests {
+		if !r.usedRequests[i] {
+			remaining = append(remaining, exchange)
+		}
+	}
+	return remaining
+}
+
+// RedactBody replaces the data of secrets and the issued service account tokens of a JSON API body. The
+// redacted secret values remain valid base64, allowing a replay to decode them.
+func RedactBody(body string) string {
+	var document map[string]interface{}
+	if body == "" || json.Unmarshal([]byte(body), &document) != nil {
+		return body
+	}
+
+	if !redactObject(document) {
+		return body
+	}
+	redacted, err := json.Marshal(document)
+	if err != nil {
+		return body
+	}
+	return string(redacted)
+}
+
+func redactObject(object map[string]interface{}) bool {
+	switch object["kind"] {
+	case "Secret":
+		return redactSecret(object)
+
+	case "SecretList":
+		items, _ := object["items"].([]interface{})
+		redacted := false
+		for _, item := range items {
+			if secret, isMap := item.(map[string]interface{}); isMap {
+				redacted = redactSecret(secret) || redacted
+			}
+		}
+		return redacted
+
+	case "TokenRequest":
+		status, isMap := object["status"].(map[string]interface{})
+		if !isMap || status["token"] == nil {
+			return false
+		}
+		status["token"] = redactedValue
+		return true
+	}
+	return false
+}
+
+func redactSecret(secret map[string]interface{}) bool {
+	redacted := false
+	if data, isMap := secret["data"].(map[string]interface{}); isMap {
+		for key := range data {
+			data[key] = base64.StdEncoding.EncodeToString([]byte(redactedValue))
+			redacted = true
+		}
+	}
+	if stringData, isMap := secret["stringData"].(map[string]interface{}); isMap {
+		for key := range stringData {
+			stringData[key] = redactedValue
+			redacted = true
+		}
+	}
+	return redacted
+}
+
+func readBody(body *io.ReadCloser) (string, error) {
+	if *body == nil {
+		return "", nil
+	}
+	content, err := ioutil.ReadAll(*body)
+	_ = (*body).Close()
+	if err != nil {
+		return "", err
+	}
+	*body = ioutil.NopCloser(bytes.NewReader(content))
+	return string(content), nil
+}
+
+type roundTripperFunc func(request *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
@@ -0,0 +1,89 @@
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+package executor
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransportRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/api/v1/namespaces/default/secrets/sa-token" {
+			_, _ = writer.Write([]byte(`{"kind":"Secret","data":{"token":"czNjcjN0"}}`))
+			return
+		}
+		writer.WriteHeader(http.StatusNotFound)
+		_, _ = writer.Write([]byte(`{"kind":"Status","code":404}`))
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder(NewFake())
+	client := &http.Client{Transport: recorder.Transport("kind-k8ssandra-0", http.DefaultTransport)}
+
+	response, err := client.Get(server.URL + "/api/v1/namespaces/default/secrets/sa-token")
+	require.NoError(t, err)
+	body, _ := ioutil.ReadAll(response.Body)
+	require.Contains(t, string(body), "czNjcjN0", "expecting the caller to receive the unredacted response")
+
+	response, err = client.Get(server.URL + "/api/v1/namespaces/default/pods?labelSelector=app")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	requests := recorder.Cassette().Requests
+	require.Len(t, requests, 2)
+	require.NotContains(t, requests[0].ResponseBody, "czNjcjN0")
+	require.Equal(t, "/api/v1/namespaces/default/pods?labelSelector=app", requests[1].URI)
+
+	replayer := NewReplayer(recorder.Cassette())
+	replayed := &http.Client{Transport: replayer.Transport("kind-k8ssandra-0")}
+	response, err = replayed.Get("https://replay.invalid/api/v1/namespaces/default/pods?labelSelector=app")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	_, err = (&http.Client{Transport: replayer.Transport("kind-k8ssandra-1")}).Get(
+		"https://replay.invalid/api/v1/namespaces/default/secrets/sa-token")
+	require.Error(t, err, "expecting the context to be compared")
+
+	response, err = replayed.Get("https://replay.invalid/api/v1/namespaces/default/secrets/sa-token")
+	require.NoError(t, err)
+	body, _ = ioutil.ReadAll(response.Body)
+	require.Contains(t, string(body), "UkVEQUNURUQ=")
+	require.Empty(t, replayer.RemainingRequests())
+}
+
+func TestRedactBody(t *testing.T) {
+	secret := RedactBody(`{"kind":"Secret","data":{"password":"czNjcjN0"},"stringData":{"user":"admin"}}`)
+	require.NotContains(t, secret, "czNjcjN0")
+	require.NotContains(t, secret, "admin")
+
+	list := RedactBody(`{"kind":"SecretList","items":[{"metadata":{"name":"a"},"data":{"token":"czNjcjN0"}}]}`)
+	require.NotContains(t, list, "czNjcjN0")
+	require.Contains(t, list, `"name":"a"`)
+
+	token := RedactBody(`{"kind":"TokenRequest",` +
+		`"status":{"token":"eyJhbGciOi","expirationTimestamp":"2022-01-01T00:00:00Z"}}`)
+	require.NotContains(t, token, "eyJhbGciOi")
+	require.Contains(t, token, "expirationTimestamp")
+
+	pods := `{"kind":"PodList","items":[]}`
+	require.Equal(t, pods, RedactBody(pods))
+	require.Equal(t, "not json", RedactBody("not json"))
+}
@@ -17,177 +17,174 @@ limitations under the License.
 **/
 
 import (
-	"encoding/base64"
+	"context"
 	"fmt"
 	"github.com/goccy/go-yaml"
 	"github.com/gruntwork-io/terratest/modules/files"
 	"github.com/gruntwork-io/terratest/modules/k8s"
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
-	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/cloud/gcp"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/cloud"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
 	"github.com/mitchellh/go-homedir"
 	"github.com/stretchr/testify/require"
 	"io/ioutil"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/clientcmd/api"
 	v1 "k8s.io/client-go/tools/clientcmd/api/v1"
 	"k8s.io/utils/strings/slices"
-	"log"
 	"os"
 	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"testing"
 	"time"
 )
 
 const (
-	defaultArtifactFormat       = "/tmp/TestK8cSmoke(\\w+)/"
+	// defaultArtifactFormat matches, under the quoted temp dir, the folder of the copied modules suffixed by its
+	// random digits, including the folders named after the smoke test or the provision and run commands of the CLI
+	// by earlier runs.
+	defaultArtifactFormat       = "^%s/(k8c-modules-|TestK8c(Smoke|Provision|Run))\\d+/"
 	defaultParentArtifactFormat = "/tmp/(\\w+)"
 )
 
 // Apply based on provision meta and configuration settings
-func Apply(t *testing.T, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) {
+func Apply(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) {
 
 	logger.Log(t, fmt.Sprintf("SIMULATE mode: %s", strconv.FormatBool(meta.Enable.Simulate)))
-	if meta.Enable.RemoveAll {
+	readinessConfig = PlanNetworks(t, readinessConfig)
+	RequireValid(t, readinessConfig)
 
-		logger.Log(t, fmt.Sprintf("remove all requested, existing infrastructure provisioning "+
-			"is being referenced: %s. Starting artifact removal.", meta.ArtifactsRootDir))
-		RemoveProvisioningArtifacts(t, meta, readinessConfig, true)
-
-	} else if meta.Enable.ProvisionInfra && !meta.Enable.Install {
-		logger.Log(t, fmt.Sprintf("existing infrastructure provisioning is not being referenced, "+
-			"provision started %s", meta.ProvisionId))
-		meta = ProvisionMultiCluster(t, readinessConfig, meta)
-		require.NotEmpty(t, meta.ProvisionId, "expected provision step to occur.")
-
-	} else if meta.Enable.Install && !meta.Enable.ProvisionInfra {
-		logger.Log(t, fmt.Sprintf("installation starting for provision identifier: %s", meta.ProvisionId))
-		InstallK8ssandra(t, readinessConfig, meta)
-	} else if meta.Enable.PreInstallSetup && !meta.Enable.ProvisionInfra {
-		PreInstallSetup(t, meta, readinessConfig)
-	} else {
-		logger.Log(t, fmt.Sprintf("NOTICE: a single meta activity is not provided for apply "+
-			"(e.g. Install, ProvisionInfra, RemoveAll).  It may be required that another enablement is causing conflict."))
+	phases := ResolvePhases(meta.Enable)
+	if len(phases) == 0 {
+		logger.Log(t, "NOTICE: no phases are provided for apply "+
+			"(e.g. provision, pre-install, install, validate, diagnose, cleanup).")
+		return
 	}
 
+	t, finishCassette := UseCassette(t, meta)
+	defer finishCassette()
+
+	logger.Log(t, fmt.Sprintf("applying phases: %v", phases))
+	RunPipeline(t, meta, readinessConfig, phases)
 }
 
 func CreateTerraformOptions(meta model.ProvisionMeta, config model.ReadinessConfig,
-	name string, ctx model.ContextConfig, kubeConfigPath string, rootFolder string) terraform.Options {
-
-	uniqueClusterName := strings.ToLower(fmt.Sprintf(name))
-	saName := gcp.ConstructCloudClusterName(name, ctx.CloudConfig) + "-" +
-		config.ServiceAccountNameSuffix + defaultIdentityDomain
-
-	nodePools := createNodePools(ctx)
-
-	uniqueBucketName := strings.ToLower(fmt.Sprintf(ctx.CloudConfig.Bucket+"-%s", config.UniqueId))
-	vars := map[string]interface{}{
-		"project_id":              ctx.CloudConfig.Project,
-		"name":                    uniqueClusterName,
-		"machine_type":            ctx.CloudConfig.MachineType,
-		"environment":             ctx.CloudConfig.Environment,
-		"provision_id":            meta.ProvisionId,
-		"region":                  ctx.CloudConfig.Region,
-		"zone":                    ctx.CloudConfig.Region,
-		"node_pools":              nodePools,
-		"node_locations":          ctx.CloudConfig.Locations,
-		"kubectl_config_path":     kubeConfigPath,
-		"initial_node_count":      config.ExpectedNodeCount,
-		"cluster_name":            uniqueClusterName,
-		"service_account":         saName,
-		"enable_private_endpoint": false,
-		"enable_private_nodes":    false,
-		"cidr_block":              ctx.NetworkConfig.SubnetCidrBlock,
-		"secondary_cidr_block":    ctx.NetworkConfig.SecondaryCidrBlock,
-		"master_ipv4_cidr_block":  ctx.NetworkConfig.MasterIpv4CidrBlock,
-		"bucket_policy_only":      true,
-		"role":                    "roles/storage.admin",
-		ctx.CloudConfig.Bucket:    uniqueBucketName,
-	}
+	name string, ctx model.ContextConfig, kubeConfigPath string, rootFolder string) (terraform.Options, error) {
 
-	if meta.Enable.Simulate {
-		println("SIMULATE: tf options output:")
-		for k, v := range vars {
-			println(fmt.Sprintf(" [%s,%s]", k, v))
-		}
+	provider, err := cloud.Lookup(ctx.CloudConfig.Type)
+	if err != nil {
+		return terraform.Options{}, err
 	}
 
-	envVars := map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": ctx.CloudConfig.CredPath,
-		defaultControlPlaneKey: strconv.FormatBool(IsControlPlane(config.Contexts[name]))}
+	vars := provider.TerraformVars(meta, config, name, ctx, kubeConfigPath)
+
+	envVars := provider.TerraformEnv(ctx.CloudConfig)
+	envVars[defaultControlPlaneKey] = strconv.FormatBool(IsControlPlane(config.Contexts[name]))
 
 	return terraform.Options{
 		TerraformDir: rootFolder,
 		Vars:         vars,
 		EnvVars:      envVars,
-	}
+	}, nil
 
 }
 
-func createNodePools(ctx model.ContextConfig) []map[string]interface{} {
-
-	var nodePools []map[string]interface{}
-	for _, prc := range ctx.CloudConfig.PoolRackConfigs {
-		var nodePool = map[string]interface{}{}
-		nodePool["label"] = prc.Label
-		nodePool["name"] = prc.Name
-		nodePool["location"] = prc.Location
-		nodePools = append(nodePools, nodePool)
-	}
-	return nodePools
+// FetchCloudProvider provides the cloud provider registered for the context cloud type.
+func FetchCloudProvider(t testing.TestingT, ctx model.ContextConfig) cloud.CloudProvider {
+	provider, err := cloud.Lookup(ctx.CloudConfig.Type)
+	require.NoError(t, err, fmt.Sprintf("expecting cloud provider for context: %s", ctx.Name))
+	return provider
 }
 
 func IsControlPlane(ctxConfig model.ContextConfig) bool {
 	return slices.Contains(ctxConfig.ClusterLabels, defaultControlPlaneLabel)
 }
 
-func FetchCertificate(t *testing.T, options *k8s.KubectlOptions, secret string, namespace string) ([]byte, error) {
-	logger.Log(t, fmt.Sprintf("obtaining certificate"))
-	out, err := k8s.RunKubectlAndGetOutputE(t, options, "get", "secret", secret, "-n", namespace, "-o", "jsonpath={.data['ca\\.crt']}")
-	require.NoError(t, err)
-	return base64.StdEncoding.DecodeString(out)
+// IsExistingCluster indicates the context references an existing kube config context, not provisioned by the framework.
+func IsExistingCluster(ctxConfig model.ContextConfig) bool {
+	return ctxConfig.ExistingCluster != nil && ctxConfig.ExistingCluster.ContextName != ""
 }
 
-func FetchToken(t *testing.T, options *k8s.KubectlOptions, secret string, namespace string) string {
-	out, err := k8s.RunKubectlAndGetOutputE(t, options, "--context", options.ContextName,
-		"-n", namespace, "get", "secret", secret, "-o", "jsonpath={.data.token}")
+// IsIdentityRequired indicates at least one context is provisioned in the cloud, requiring a cloud identity.
+func IsIdentityRequired(readinessConfig model.ReadinessConfig) bool {
+	for _, ctx := range readinessConfig.Contexts {
+		if !IsExistingCluster(ctx) {
+			return true
+		}
+	}
+	return false
+}
 
-	require.NoError(t, err)
-	require.NotNil(t, out)
+// ConstructFullContextName provides the kube context name for either an existing or a cloud provisioned cluster.
+func ConstructFullContextName(t testing.TestingT, name string, ctx model.ContextConfig) string {
+	if IsExistingCluster(ctx) {
+		return ctx.ExistingCluster.ContextName
+	}
+	return FetchCloudProvider(t, ctx).ConstructFullContextName(name, ctx.CloudConfig)
+}
+
+// ExistingKubeConfigPath provides the kube config of an existing cluster, using the default path when not specified.
+func ExistingKubeConfigPath(meta model.ProvisionMeta, ctx model.ContextConfig) string {
+	if ctx.ExistingCluster.KubeConfigPath != "" {
+		return ctx.ExistingCluster.KubeConfigPath
+	}
+	return meta.DefaultConfigPath
+}
 
-	decoded, err := base64.StdEncoding.DecodeString(out)
+func FetchCertificate(t testing.TestingT, options *k8s.KubectlOptions, secret string, namespace string) ([]byte, error) {
+	logger.Log(t, fmt.Sprintf("obtaining certificate"))
+	found, err := KubeClient(t, options).CoreV1().Secrets(namespace).Get(context.Background(), secret, metav1.GetOptions{})
 	if err != nil {
-		log.Fatalf("Some error occured during base64 decode. Error %s", err.Error())
+		return nil, err
 	}
-	return string(decoded)
+	return found.Data[corev1.ServiceAccountRootCAKey], nil
+}
+
+func FetchToken(t testing.TestingT, options *k8s.KubectlOptions, secret string, namespace string) string {
+	found, err := KubeClient(t, options).CoreV1().Secrets(namespace).Get(context.Background(), secret, metav1.GetOptions{})
+	require.NoError(t, err, fmt.Sprintf("expecting secret: %s to be available", secret))
+
+	token := found.Data[corev1.ServiceAccountTokenKey]
+	require.NotEmpty(t, token, fmt.Sprintf("expecting a token in secret: %s", secret))
+	return string(token)
 }
 
-func FetchSecret(t *testing.T, options *k8s.KubectlOptions, serviceAccount string, namespace string) string {
+func FetchSecret(t testing.TestingT, options *k8s.KubectlOptions, serviceAccount string, namespace string) string {
 
 	options.Namespace = namespace
-	sa := k8s.GetServiceAccount(t, options, serviceAccount)
-	require.NotNil(t, sa, fmt.Sprintf("Expecting service account to be available: %s", serviceAccount))
-	secret := sa.Secrets[0].Name
-	require.NotNil(t, secret, fmt.Sprintf("Expecting secret to be availabe for service account: %s", serviceAccount))
-	return secret
+	sa, err := KubeClient(t, options).CoreV1().ServiceAccounts(namespace).Get(context.Background(), serviceAccount,
+		metav1.GetOptions{})
+	require.NoError(t, err, fmt.Sprintf("Expecting service account to be available: %s", serviceAccount))
+	require.NotEmpty(t, sa.Secrets, fmt.Sprintf("Expecting secret to be availabe for service account: %s", serviceAccount))
+	return sa.Secrets[0].Name
 }
 
-func FetchKubeConfigPath(t *testing.T) (string, string) {
-	home, err := homedir.Dir()
+func FetchKubeConfigPath(t testing.TestingT) (string, string) {
+	home, configPath, err := defaultKubeConfigPath()
 	require.NoError(t, err, "unable to locate home directory for config path")
-	return home, filepath.Join(home, ".kube", "kubeconfig")
+	return home, configPath
+}
+
+func defaultKubeConfigPath() (string, string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", "", err
+	}
+	return home, filepath.Join(home, ".kube", "kubeconfig"), nil
 }
 
-func FetchEnv(t *testing.T, key string) string {
+func FetchEnv(t testing.TestingT, key string) string {
 	require.NotEmpty(t, key, "expecting key to be defined for fetch env")
 	return os.Getenv(key)
 }
 
-func CreateClientConfigurations(t *testing.T, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig,
+func CreateClientConfigurations(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig,
 	ctxOptions map[string]model.ContextOption) {
 
 	if meta.Enable.Simulate {
@@ -197,6 +194,7 @@ func CreateClientConfigurations(t *testing.T, meta model.ProvisionMeta, readines
 
 	logger.Log(t, "\n\nK8ssandra: creating client configurations")
 	var generatedClientConfigs []string
+	var accesses []model.ClientAccess
 	var kubeConfig *k8s.KubectlOptions
 
 	for name, ctxConfig := range readinessConfig.Contexts {
@@ -204,13 +202,19 @@ func CreateClientConfigurations(t *testing.T, meta model.ProvisionMeta, readines
 		kubeConfig = ctxOptions[name].KubectlOptions
 		SetCurrentContext(t, ctxOptions[name].FullName, kubeConfig)
 
-		AddServiceAccount(t, ctxOptions[name], ctxConfig.Namespace, kubeConfig)
+		access := AddServiceAccount(t, ctxOptions[name], ctxConfig.Namespace, kubeConfig,
+			readinessConfig.ProvisionConfig.K8cConfig)
+		accesses = append(accesses, access)
 		SetupTestArtifactDirectory(t, ctxOptions[name])
 
 		generatedClientConfig := GenerateClientConfig(t, ctxOptions[name])
 		generatedClientConfigs = append(generatedClientConfigs, generatedClientConfig)
 	}
 
+	accessPath, accessErr := WriteClientAccess(meta, accesses)
+	require.NoError(t, accessErr, "expecting the client access to be recorded")
+	logger.Log(t, fmt.Sprintf("client access recorded in: %s", accessPath))
+
 	CreateConfigs(t, ctxOptions, readinessConfig)
 
 	logger.Log(t, "\n\nK8ssandra: Creating the generic secret ...")
@@ -235,7 +239,7 @@ func CreateClientConfigurations(t *testing.T, meta model.ProvisionMeta, readines
 	}
 }
 
-func SetupTestArtifactDirectory(t *testing.T, ctxOption model.ContextOption) {
+func SetupTestArtifactDirectory(t testing.TestingT, ctxOption model.ContextOption) {
 
 	rootPath := ConfigRootPath(t, ctxOption, "")
 	mkdError := os.MkdirAll(rootPath, defaultTempFilePerm)
@@ -243,7 +247,7 @@ func SetupTestArtifactDirectory(t *testing.T, ctxOption model.ContextOption) {
 		"root path: %s", rootPath))
 }
 
-func CreateConfigs(t *testing.T, ctxOptions map[string]model.ContextOption, readinessConfig model.ReadinessConfig) {
+func CreateConfigs(t testing.TestingT, ctxOptions map[string]model.ContextOption, readinessConfig model.ReadinessConfig) {
 
 	var clusters []v1.NamedCluster
 	var auths []v1.NamedAuthInfo
@@ -314,23 +318,19 @@ func CreateConfigs(t *testing.T, ctxOptions map[string]model.ContextOption, read
 
 }
 
-func CreateIdentityEnv(configPath string, identity string, credPath string) map[string]string {
-	return map[string]string{
-		"KUBECONFIG":                     configPath,
-		"GOOGLE_IDENTITY_EMAIL":          identity,
-		"GOOGLE_APPLICATION_CREDENTIALS": credPath,
-	}
+func CreateIdentityEnv(t testing.TestingT, configPath string, identity string, ctx model.ContextConfig) map[string]string {
+	return FetchCloudProvider(t, ctx).IdentityEnv(configPath, identity, ctx.CloudConfig)
 }
 
-func SetCurrentContext(t *testing.T, ctxName string, kubeConfig *k8s.KubectlOptions) bool {
+func SetCurrentContext(t testing.TestingT, ctxName string, kubeConfig *k8s.KubectlOptions) bool {
 	kubeConfig.Env["KUBECONFIG"] = kubeConfig.ConfigPath
 	logger.Log(t, fmt.Sprintf("==== setting current context with kubeconfig target: %s", kubeConfig.Env["KUBECONFIG"]))
-	_, err := k8s.RunKubectlAndGetOutputE(t, kubeConfig, "config", "set", "current-context", ctxName)
+	_, err := executor.RunKubectl(t, kubeConfig, "config", "set", "current-context", ctxName)
 	require.NoError(t, err, "expecting to set current context without error")
 	return err == nil
 }
 
-func ConfigRootPath(t *testing.T, contextOption model.ContextOption, fileName string) string {
+func ConfigRootPath(t testing.TestingT, contextOption model.ContextOption, fileName string) string {
 	rootPath := path.Join(contextOption.ProvisionMeta.ArtifactsRootDir, contextOption.FullName)
 	if fileName != "" {
 		return path.Join(rootPath, fileName)
@@ -339,7 +339,7 @@ func ConfigRootPath(t *testing.T, contextOption model.ContextOption, fileName st
 	return rootPath
 }
 
-func ConfigCloudTempRootPath(t *testing.T, contextOption model.ContextOption, fileName string) string {
+func ConfigCloudTempRootPath(t testing.TestingT, contextOption model.ContextOption, fileName string) string {
 	rootPath := contextOption.ProvisionMeta.ArtifactsRootDir
 	if fileName != "" {
 		return path.Join(rootPath, fileName)
@@ -348,26 +348,29 @@ func ConfigCloudTempRootPath(t *testing.T, contextOption model.ContextOption, fi
 	return rootPath
 }
 
-func CreateGenericSecret(t *testing.T, namespace string, kubeConfig *k8s.KubectlOptions) {
+func CreateGenericSecret(t testing.TestingT, namespace string, kubeConfig *k8s.KubectlOptions) {
 	logger.Log(t, fmt.Sprintf("generating secret with name: %s", defaultK8ssandraSecret))
 
 	kubeConfig.Namespace = namespace
-	var _, err = k8s.RunKubectlAndGetOutputE(t, kubeConfig, "create", "secret", "generic",
-		defaultK8ssandraSecret, "-n", namespace, "--from-file", kubeConfig.ConfigPath)
-
-	if err != nil {
-		// Try recovery by removing existing.
-		_, err2 := k8s.RunKubectlAndGetOutputE(t, kubeConfig, "delete", "secret", defaultK8ssandraSecret,
-			"-n", namespace)
-		require.NoError(t, err2)
+	content, readErr := ioutil.ReadFile(kubeConfig.ConfigPath)
+	require.NoError(t, readErr, fmt.Sprintf("expecting kube config: %s to be readable", kubeConfig.ConfigPath))
+
+	// Keyed by the file name, as kubectl create secret generic --from-file.
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: defaultK8ssandraSecret, Namespace: namespace},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{filepath.Base(kubeConfig.ConfigPath): content},
+	}
 
-		_, err = k8s.RunKubectlAndGetOutputE(t, kubeConfig,
-			"create", "secret", "generic", defaultK8ssandraSecret, "-n", namespace, "--from-file", kubeConfig.ConfigPath)
+	secrets := KubeClient(t, kubeConfig).CoreV1().Secrets(namespace)
+	_, err := secrets.Create(context.Background(), secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(context.Background(), secret, metav1.UpdateOptions{})
 	}
-	require.NoError(t, err)
+	require.NoError(t, err, fmt.Sprintf("expecting secret: %s to be created", defaultK8ssandraSecret))
 }
 
-func GenerateClientConfig(t *testing.T, ctxOption model.ContextOption) string {
+func GenerateClientConfig(t testing.TestingT, ctxOption model.ContextOption) string {
 	var clientConfigSpec = model.ClientConfigSpec{
 		ContextName:      ctxOption.FullName,
 		KubeConfigSecret: corev1.LocalObjectReference{Name: defaultK8ssandraSecret},
@@ -385,7 +388,7 @@ func GenerateClientConfig(t *testing.T, ctxOption model.ContextOption) string {
 	return WriteClientConfig(t, ctxOption, clientConfig)
 }
 
-func WriteClientConfig(t *testing.T, ctxOption model.ContextOption, clientConfig model.ClientConfig) string {
+func WriteClientConfig(t testing.TestingT, ctxOption model.ContextOption, clientConfig model.ClientConfig) string {
 	yamlOut, marshalError := yaml.Marshal(&clientConfig)
 	if marshalError != nil {
 		logger.Log(t, marshalError.Error())
@@ -406,7 +409,7 @@ func WriteClientConfig(t *testing.T, ctxOption model.ContextOption, clientConfig
 	return absoluteFilePath
 }
 
-func WriteKubeConfig(t *testing.T, ctxOption model.ContextOption, clientConfig v1.Config) string {
+func WriteKubeConfig(t testing.TestingT, ctxOption model.ContextOption, clientConfig v1.Config) string {
 	yamlOut, marshalError := yaml.Marshal(&clientConfig)
 	if marshalError != nil {
 		logger.Log(t, marshalError.Error())
@@ -429,26 +432,41 @@ func WriteKubeConfig(t *testing.T, ctxOption model.ContextOption, clientConfig v
 	return absoluteFilePath
 }
 
-func AddServiceAccount(t *testing.T, ctxOption model.ContextOption, namespace string,
-	kubeConfig *k8s.KubectlOptions) {
+// AddServiceAccount creates the dedicated service account of the client configurations, assigning its token
+// and certificate to the context option, and provides the access granted to it.
+func AddServiceAccount(t testing.TestingT, ctxOption model.ContextOption, namespace string,
+	kubeConfig *k8s.KubectlOptions, k8cConfig model.K8cConfig) model.ClientAccess {
 
-	logger.Log(t, fmt.Sprintf("adding service account:%s to context using ns:%s", defaultK8ssandraOperatorReleaseName, namespace))
+	logger.Log(t, fmt.Sprintf("adding service account:%s to context using ns:%s", defaultClientServiceAccountName, namespace))
 
 	kubeConfig.Namespace = namespace
-	csa := model.ContextServiceAccount{}
-	csa.Namespace = namespace
-
-	csa.Secret = FetchSecret(t, kubeConfig, defaultK8ssandraOperatorReleaseName, namespace)
-	csa.Token = FetchToken(t, kubeConfig, csa.Secret, namespace)
-	csa.Cert, _ = FetchCertificate(t, kubeConfig, csa.Secret, namespace)
+	access := CreateClientAccess(t, kubeConfig, namespace, k8cConfig.ClusterScoped)
+
+	// Resolved once, as the server version lookup of the auto mode may differ between calls.
+	tokenConfig := k8cConfig.ServiceAccountToken
+	tokenConfig.Mode = ResolveTokenMode(t, kubeConfig, tokenConfig.Mode)
+	access.TokenMode = tokenConfig.Mode
+
+	token := fetchServiceAccountToken(t, kubeConfig, access.ServiceAccount, namespace, tokenConfig.Mode,
+		tokenConfig.ExpirationSeconds)
+	access.TokenExpiresAt = token.expiresAt
+	csa := model.ContextServiceAccount{Name: access.ServiceAccount, Namespace: namespace, Secret: token.secret,
+		Token: token.token}
+
+	// A requested token has no secret, the certificate of the cluster is kept.
+	csa.Cert = ctxOption.ServiceAccount.Cert
+	if len(token.cert) > 0 {
+		csa.Cert = token.cert
+	}
 
 	require.NotEmpty(t, csa.Cert, "Expected certificate data available for secret")
 	*ctxOption.ServiceAccount = csa
 
-	logger.Log(t, fmt.Sprintf("certificate and token obtained for secret:%s", csa.Secret))
+	logger.Log(t, fmt.Sprintf("certificate and token obtained for service account:%s", access.ServiceAccount))
+	return access
 }
 
-func CreateContextOptions(t *testing.T, readinessConfig model.ReadinessConfig,
+func CreateContextOptions(t testing.TestingT, readinessConfig model.ReadinessConfig,
 	provisionMeta model.ProvisionMeta, configs map[string]*k8s.KubectlOptions) map[string]model.ContextOption {
 
 	logger.Log(t, fmt.Sprintf("\n\ncreating all context options for "+
@@ -457,12 +475,15 @@ func CreateContextOptions(t *testing.T, readinessConfig model.ReadinessConfig,
 	ctxOptions := map[string]model.ContextOption{}
 
 	for name, ctx := range readinessConfig.Contexts {
-		fullName := gcp.ConstructFullContextName(name, ctx.CloudConfig)
+		fullName := ConstructFullContextName(t, name, ctx)
 		logger.Log(t, fmt.Sprintf("creating context options for context:%s with ns:%s",
 			ctx.Name, ctx.Namespace))
 
-		cloudClusterName := gcp.ConstructCloudClusterName(name, ctx.CloudConfig)
-		saName := cloudClusterName + "-" + readinessConfig.ServiceAccountNameSuffix + defaultIdentityDomain
+		var saName = defaultK8ssandraOperatorReleaseName
+		if !IsExistingCluster(ctx) {
+			saName = FetchCloudProvider(t, ctx).ConstructServiceAccountName(name,
+				readinessConfig.ServiceAccountNameSuffix, ctx.CloudConfig)
+		}
 
 		kubeCluster := SelectClusterFromKube(t, name, configs)
 		require.NotNil(t, kubeCluster, fmt.Sprintf("expected kube cluster to be found for name: %s", name))
@@ -483,11 +504,17 @@ func CreateContextOptions(t *testing.T, readinessConfig model.ReadinessConfig,
 	return ctxOptions
 }
 
-func SelectClusterFromKube(t *testing.T, name string, configs map[string]*k8s.KubectlOptions) *api.Cluster {
+func SelectClusterFromKube(t testing.TestingT, name string, configs map[string]*k8s.KubectlOptions) *api.Cluster {
 
 	ko := configs[name]
 	rawConfig, err := k8s.LoadConfigFromPath(ko.ConfigPath).RawConfig()
 	require.NoError(t, err, "Expecting to be able to obtain infrastructure provisioned cluster raw configuration")
+
+	if kubeContext, found := rawConfig.Contexts[ko.ContextName]; found {
+		if config, found := rawConfig.Clusters[kubeContext.Cluster]; found {
+			return config
+		}
+	}
 	for clusterName, config := range rawConfig.Clusters {
 		if strings.Contains(clusterName, name) {
 			return config
@@ -496,69 +523,56 @@ func SelectClusterFromKube(t *testing.T, name string, configs map[string]*k8s.Ku
 	return nil
 }
 
-func RestartOperator(t *testing.T, namespace string, options *k8s.KubectlOptions) {
+func RestartOperator(t testing.TestingT, namespace string, options *k8s.KubectlOptions) {
 	logger.Log(t, "\n\nK8ssandra: restarting k8ssandra-operator")
-
-	pod, err := k8s.RunKubectlAndGetOutputE(t, options, "get", "pod",
-		"-l", "app.kubernetes.io/name=k8ssandra-operator", "-n", namespace, "-o", "name")
-
-	if err == nil && pod != "" {
-		_, err := k8s.RunKubectlAndGetOutputE(t, options, "delete", pod, "-n", namespace)
-
-		if err != nil {
-			logger.Log(t, fmt.Sprintf("WARNING: attempt to delete pod: %s failed due to: %s", pod, err))
-		}
-	}
-
-	_, err2 := k8s.RunKubectlAndGetOutputE(t, options, "rollout", "restart",
-		"deployment", defaultK8ssandraOperatorReleaseName, "-n", namespace)
-
-	require.NoError(t, err2)
+	restartDeployment(t, options, namespace, "app.kubernetes.io/name=k8ssandra-operator",
+		defaultK8ssandraOperatorReleaseName)
 	time.Sleep(defaultTimeout)
 }
 
-func RestartCassOperator(t *testing.T, namespace string, options *k8s.KubectlOptions) {
-
+func RestartCassOperator(t testing.TestingT, namespace string, options *k8s.KubectlOptions) {
 	logger.Log(t, "\n\nK8ssandra: restarting k8ssandra-cass-operator")
-
-	// k get pods -n bootz -l app.kubernetes.io/name=cass-operator -o name
-	pod, err := k8s.RunKubectlAndGetOutputE(t, options, "get", "pod",
-		"-l", "app.kubernetes.io/name=cass-operator", "-n", namespace, "-o", "name")
-
-	if err == nil && pod != "" {
-		_, err := k8s.RunKubectlAndGetOutputE(t, options, "delete", pod, "-n", namespace)
-		if err != nil {
-			logger.Log(t, fmt.Sprintf("WARNING: attempt to delete pod: %s failed due to: %s", pod, err))
-		}
-	}
-
-	_, err2 := k8s.RunKubectlAndGetOutputE(t, options, "rollout", "restart",
-		"deployment", defaultCassandraOperatorName, "-n", namespace)
-	require.NoError(t, err2)
+	restartDeployment(t, options, namespace, "app.kubernetes.io/name=cass-operator", defaultCassandraOperatorName)
 	time.Sleep(defaultTimeout)
 }
 
-func WaitForEndpoint(t *testing.T, kubeConfig *k8s.KubectlOptions, name string) string {
-	out, err := k8s.RunKubectlAndGetOutputE(t, kubeConfig, "get", "ep", name, "-o=jsonpath='{.subsets[0].addresses[0].ip}'")
+// WaitForEndpoint provides the first address of the endpoints, empty while no address is ready.
+func WaitForEndpoint(t testing.TestingT, kubeConfig *k8s.KubectlOptions, name string) string {
+	endpoints, err := KubeClient(t, kubeConfig).CoreV1().Endpoints(kubeConfig.Namespace).Get(context.Background(),
+		name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return ""
+	}
 	require.NoError(t, err, "unexpected error when attempting to obtain endpoint ip availability")
-	return out
+
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			return address.IP
+		}
+	}
+	return ""
 }
 
-func IsPodRunning(t *testing.T, options *k8s.KubectlOptions, prefixName string) (bool, string) {
-	out, err := k8s.RunKubectlAndGetOutputE(t, options, "get", "pod", "--field-selector=status.phase=Running",
-		"--no-headers", "-l", "app.kubernetes.io/name=k8ssandra-operator", "-n", options.Namespace,
-		"-o", "custom-columns=\":metadata.name\"")
+// IsPodRunning indicates a k8ssandra-operator pod named with the prefix is running, providing its name.
+func IsPodRunning(t testing.TestingT, options *k8s.KubectlOptions, prefixName string) (bool, string) {
+	pods, err := KubeClient(t, options).CoreV1().Pods(options.Namespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: "app.kubernetes.io/name=k8ssandra-operator"})
 
 	if err != nil {
 		logger.Log(t, fmt.Sprintf("get pod by meta name returned error: %s", err.Error()))
-		return false, out
+		return false, ""
 	}
 
-	logger.Log(t, fmt.Sprintf("get running pod by meta name returned: %s", out))
-	return out == prefixName, out
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning && strings.HasPrefix(pod.Name, prefixName) {
+			logger.Log(t, fmt.Sprintf("get running pod by meta name returned: %s", pod.Name))
+			return true, pod.Name
+		}
+	}
+	return false, ""
 }
 
-func applyClientConfig(t *testing.T, options *k8s.KubectlOptions, clientConfigFile string, namespace string) {
-	_, err := k8s.RunKubectlAndGetOutputE(t, options, "-n", namespace, "apply", "-f", clientConfigFile)
+func applyClientConfig(t testing.TestingT, options *k8s.KubectlOptions, clientConfigFile string, namespace string) {
+	_, err := executor.RunKubectl(t, options, "-n", namespace, "apply", "-f", clientConfigFile)
 	require.NoError(t, err)
 }
@@ -17,20 +17,22 @@ limitations under the License.
 **/
 
 import (
+	"context"
 	"fmt"
 	"github.com/gruntwork-io/terratest/modules/helm"
 	"github.com/gruntwork-io/terratest/modules/k8s"
 	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
-	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/cloud/gcp"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	_ "k8s.io/client-go/tools/clientcmd/api/v1"
-	"k8s.io/utils/strings/slices"
 	"os"
 	"path"
 	"strconv"
 	"strings"
-	"testing"
 	"time"
 )
 
@@ -40,16 +42,21 @@ const (
 	defaultInterval     = time.Millisecond * 250
 
 	defaultK8ssandraSecret     = "k8s-contexts"
-	defaultIdentityDomain      = "@community-ecosystem.iam.gserviceaccount.com"
 	defaultControlPlaneKey     = "K8SSANDRA_CONTROL_PLANE"
 	defaultWebhookServiceName  = "webhook-service"
 	defaultControlPlaneLabel   = "control-plane"
 	defaultKubeConfigFileName  = "kubeconfig"
 	defaultTraefikResourceName = "traefik"
 	helmInstallDryRun          = "--dry-run"
+	defaultConfigFolder        = "../config/"
+
+	defaultUninstallTimeoutSecs = 600
 )
 
-func InstallK8ssandra(t *testing.T, readinessConfig model.ReadinessConfig, meta model.ProvisionMeta) {
+// operatorCrdGroups are the API groups of the CRDs installed by the k8ssandra-operator chart.
+var operatorCrdGroups = []string{"k8ssandra.io", "cassandra.datastax.com"}
+
+func InstallK8ssandra(t testing.TestingT, readinessConfig model.ReadinessConfig, meta model.ProvisionMeta) {
 
 	logger.Log(t, "\n\ninstallation started")
 	options := InstallSetup(t, meta, readinessConfig)
@@ -58,14 +65,90 @@ func InstallK8ssandra(t *testing.T, readinessConfig model.ReadinessConfig, meta
 	installDataPlaneOperators(t, meta, readinessConfig, options)
 
 	CreateClientConfigurations(t, meta, readinessConfig, options)
+	createMedusaSecrets(t, meta, readinessConfig, options)
 	installK8ssandraCluster(t, meta, readinessConfig, options)
 }
 
-func installDataPlaneOperators(t *testing.T, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig, ctxOptions map[string]model.ContextOption) {
+// UninstallK8ssandra removes the K8ssandraCluster of the control-plane, waiting for its datacenters to be
+// removed, then uninstalls the k8ssandra-operator of every context along with its CRDs, leaving the cert-manager
+// and Traefik installations in place. A later install phase is then a clean installation of its versions.
+func UninstallK8ssandra(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) bool {
+
+	clusterName := readinessConfig.ProvisionConfig.K8cConfig.ClusterName
+	if meta.Enable.Simulate {
+		logger.Log(t, fmt.Sprintf("SIMULATE uninstall of k8ssandra-cluster: %s and k8ssandra-operator", clusterName))
+		return true
+	}
+
+	identity := FetchEnv(t, meta.AdminIdentity)
+	var kubeConfigs = map[string]*k8s.KubectlOptions{}
+	for _, name := range sortedKeys(readinessConfig.Contexts) {
+		kubeConfigs[name] = ConnectContext(t, meta, identity, name, readinessConfig.Contexts[name])
+	}
+
+	for _, name := range sortedKeys(readinessConfig.Contexts) {
+		if !IsControlPlane(readinessConfig.Contexts[name]) || clusterName == "" {
+			continue
+		}
+		logger.Log(t, fmt.Sprintf("deleting k8ssandra-cluster: %s on control plane: %s", clusterName, name))
+		if _, err := executor.RunKubectl(t, kubeConfigs[name], "delete", "k8ssandracluster", clusterName,
+			"--ignore-not-found", "--wait=true", fmt.Sprintf("--timeout=%ds", defaultUninstallTimeoutSecs)); err != nil {
+			logger.Log(t, fmt.Sprintf("WARNING: unable to delete k8ssandra-cluster: %s error: %s", clusterName, err))
+			return false
+		}
+	}
+
+	var isUninstalled = true
+	for _, name := range sortedKeys(readinessConfig.Contexts) {
+		kubeConfig := kubeConfigs[name]
+		namespace := readinessConfig.Contexts[name].Namespace
+		helmOptions := createHelmOptions(kubeConfig, map[string]string{}, kubeConfig.Env, false)
+		release, err := fetchRelease(t, helmOptions, defaultK8ssandraOperatorReleaseName, namespace)
+		if err == nil && release != nil {
+			logger.Log(t, fmt.Sprintf("uninstalling k8ssandra-operator on: %s", name))
+			_, err = executor.RunHelm(t, helmOptions, "uninstall", defaultK8ssandraOperatorReleaseName,
+				"-n", namespace, "--wait")
+		}
+		if err == nil {
+			err = deleteOperatorCrds(t, kubeConfig)
+		}
+		if err != nil {
+			logger.Log(t, fmt.Sprintf("WARNING: unable to uninstall k8ssandra-operator on: %s error: %s", name, err))
+			isUninstalled = false
+		}
+	}
+	return isUninstalled
+}
+
+// deleteOperatorCrds deletes the CRDs of the k8ssandra-operator, kept by helm when uninstalling the release.
+func deleteOperatorCrds(t testing.TestingT, kubeConfig *k8s.KubectlOptions) error {
+	out, err := executor.RunKubectl(t, kubeConfig, "get", "crd", "-o", "name")
+	if err != nil {
+		return err
+	}
+
+	var crds []string
+	for _, crd := range strings.Fields(out) {
+		for _, group := range operatorCrdGroups {
+			if strings.HasSuffix(crd, "."+group) {
+				crds = append(crds, crd)
+				break
+			}
+		}
+	}
+	if len(crds) == 0 {
+		return nil
+	}
+	_, err = executor.RunKubectl(t, kubeConfig, append([]string{"delete", "--ignore-not-found"}, crds...)...)
+	return err
+}
+
+func installDataPlaneOperators(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig, ctxOptions map[string]model.ContextOption) {
 
 	logger.Log(t, "\n\ninstallation of data-plane")
 
 	var isClusterScoped = readinessConfig.ProvisionConfig.K8cConfig.ClusterScoped
+	var versions = ResolveVersions(readinessConfig.ProvisionConfig)
 
 	for name, ctxConfig := range readinessConfig.Contexts {
 
@@ -78,12 +161,13 @@ func installDataPlaneOperators(t *testing.T, meta model.ProvisionMeta, readiness
 				defaultControlPlaneKey: strconv.FormatBool(isControlPlane)}, kubeConfig.Env, meta.Enable.Simulate)
 
 			logger.Log(t, fmt.Sprintf("installing k8ssandra-operator on data-plane: %s", name))
-			installK8ssandraOperator(t, helmOptions, ctxConfig.Name, ctxConfig.Namespace, isClusterScoped, isControlPlane)
+			installK8ssandraOperator(t, helmOptions, ctxConfig.Name, ctxConfig.Namespace, versions.K8ssandraOperator,
+				isClusterScoped, isControlPlane)
 		}
 	}
 }
 
-func installK8ssandraCluster(t *testing.T, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig,
+func installK8ssandraCluster(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig,
 	ctxOptions map[string]model.ContextOption) {
 
 	logger.Log(t, "\n\ninstallation of cluster")
@@ -104,39 +188,55 @@ func installK8ssandraCluster(t *testing.T, meta model.ProvisionMeta, readinessCo
 			require.Eventually(t, func() bool {
 				endpointIP := WaitForEndpoint(t, kubeConfig, defaultK8ssandraOperatorReleaseName+"-"+defaultWebhookServiceName)
 				logger.Log(t, fmt.Sprintf("endpoint discovery on control-plane: %s", endpointIP))
-				return strings.TrimSpace(endpointIP) != "''"
+				return endpointIP != ""
 			}, time.Second*30, defaultInterval, "timeout waiting for endpoint ip to exist")
 
 			logger.Log(t, "\n\nK8ssandra: control-plane k8c cluster deployment underway ...")
-			deployK8ssandraCluster(t, readinessConfig, ctxConfig.Name, kubeConfig, ctxConfig.Namespace, meta.Enable.Simulate)
-
-			time.Sleep(defaultTimeout * 6)
+			deployK8ssandraCluster(t, meta, readinessConfig, ctxConfig.Name, kubeConfig, ctxConfig.Namespace)
 		}
 	}
 }
 
-func deployK8ssandraCluster(t *testing.T, config model.ReadinessConfig, contextName string,
-	options *k8s.KubectlOptions, namespace string, isSimulate bool) bool {
+func deployK8ssandraCluster(t testing.TestingT, meta model.ProvisionMeta, config model.ReadinessConfig, contextName string,
+	options *k8s.KubectlOptions, namespace string) bool {
 	logger.Log(t, fmt.Sprintf("deploying k8ssandra-cluster for context: [%s] namespace: [%s]",
 		contextName, namespace))
 
-	if isSimulate {
+	if meta.Enable.Simulate {
 		logger.Log(t, "\n\nSIMULATE deploy of k8ssandra-cluster")
 		return true
 	}
 
-	k8cConfig := config.ProvisionConfig.K8cConfig
-	_, err := k8s.RunKubectlAndGetOutputE(t, options, "apply", "-f",
-		path.Join("../config/", k8cConfig.ValuesFilePath), "-n", namespace)
+	manifestPath, err := K8ssandraClusterManifest(t, meta, config)
+	require.NoError(t, err, "expecting a K8ssandraCluster manifest")
 
+	_, err = executor.RunKubectl(t, options, "apply", "-f", manifestPath, "-n", namespace)
 	return err == nil
 }
 
-func installControlPlaneOperator(t *testing.T, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig,
+// K8ssandraClusterManifest provides the manifest applied, generated from the readiness configuration unless a
+// static manifest is referenced.
+func K8ssandraClusterManifest(t testing.TestingT, meta model.ProvisionMeta, config model.ReadinessConfig) (string, error) {
+	k8cConfig := config.ProvisionConfig.K8cConfig
+	if k8cConfig.ValuesFilePath != "" {
+		logger.Log(t, fmt.Sprintf("WARNING: static manifest: %s applied in place of the generated K8ssandraCluster",
+			k8cConfig.ValuesFilePath))
+		return path.Join(defaultConfigFolder, k8cConfig.ValuesFilePath), nil
+	}
+
+	manifestPath, err := WriteK8ssandraCluster(meta, config)
+	if err == nil {
+		logger.Log(t, fmt.Sprintf("generated K8ssandraCluster written to: %s", manifestPath))
+	}
+	return manifestPath, err
+}
+
+func installControlPlaneOperator(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig,
 	ctxOptions map[string]model.ContextOption) string {
 
 	logger.Log(t, "\n\ninstalling control-plane")
 	var isClusterScoped = readinessConfig.ProvisionConfig.K8cConfig.ClusterScoped
+	var versions = ResolveVersions(readinessConfig.ProvisionConfig)
 	var controlPlaneContextName = ""
 
 	for name, ctxConfig := range readinessConfig.Contexts {
@@ -152,38 +252,47 @@ func installControlPlaneOperator(t *testing.T, meta model.ProvisionMeta, readine
 			helmOptions := createHelmOptions(kubeConfig, map[string]string{
 				defaultControlPlaneKey: strconv.FormatBool(isControlPlane)}, kubeConfig.Env, meta.Enable.Simulate)
 
-			installK8ssandraOperator(t, helmOptions, ctxConfig.Name, ctxConfig.Namespace, isClusterScoped, isControlPlane)
+			installK8ssandraOperator(t, helmOptions, ctxConfig.Name, ctxConfig.Namespace, versions.K8ssandraOperator,
+				isClusterScoped, isControlPlane)
 			controlPlaneContextName = ctxOptions[name].FullName
 		}
 	}
 	return controlPlaneContextName
 }
 
-func installCertManager(t *testing.T, options *k8s.KubectlOptions, isSimulate bool) {
+func installCertManager(t testing.TestingT, options *k8s.KubectlOptions, component model.ComponentVersion, isSimulate bool) {
 
 	if isSimulate {
 		logger.Log(t, "SIMULATE install cert manager ...")
 		return
 	}
 
+	if component.Chart != "" {
+		helmOptions := createHelmOptions(options, map[string]string{}, map[string]string{}, false)
+		action, _, err := applyRelease(t, helmOptions, certManagerRelease(component))
+		require.NoError(t, err, "expecting that cert-manager can be installed")
+		logger.Log(t, fmt.Sprintf("cert-manager release action: %s", action))
+		return
+	}
+
 	// Necessary as the cert manager configuration currently used, specifies its own namespaces
 	withoutNamespace := &options
 	(*withoutNamespace).Namespace = ""
 	(*withoutNamespace).Env = map[string]string{"installCRDs": "true"}
 
-	_, err := k8s.RunKubectlAndGetOutputE(t, *withoutNamespace,
-		"apply", "-f", defaultCertManagerFile)
+	_, err := executor.RunKubectl(t, *withoutNamespace,
+		"apply", "-f", component.Manifest)
 
 	if err != nil {
 		logger.Log(t, "retrying install cert manager ...")
-		_, err2 := k8s.RunKubectlAndGetOutputE(t, *withoutNamespace,
-			"apply", "-f", defaultCertManagerFile)
+		_, err2 := executor.RunKubectl(t, *withoutNamespace,
+			"apply", "-f", component.Manifest)
 		require.NoError(t, err2)
 	}
 }
 
-func installK8ssandraOperator(t *testing.T, options *helm.Options, contextName string, namespace string,
-	isClusterScoped bool, isControlPlane bool) {
+func installK8ssandraOperator(t testing.TestingT, options *helm.Options, contextName string, namespace string,
+	component model.ComponentVersion, isClusterScoped bool, isControlPlane bool) {
 
 	options.KubectlOptions.Namespace = namespace
 
@@ -192,107 +301,149 @@ func installK8ssandraOperator(t *testing.T, options *helm.Options, contextName s
 	logger.Log(t, fmt.Sprintf("cluster scoped for k8ssandra-operator is set as: %s",
 		strconv.FormatBool(isClusterScoped)))
 
-	var result, err = helmInstall(t, options, defaultK8ssandraOperatorReleaseName, defaultK8ssandraOperatorChart, namespace)
-	if err != nil {
-		logger.Log(t, fmt.Sprintf("failed k8ssandra-operator install due to error: %s", err.Error()))
-		if strings.Contains(err.Error(), "cannot re-use a name") {
-			uninstallK8ssandraOperator(t, options)
-			result, err = helmInstall(t, options, defaultK8ssandraOperatorReleaseName, defaultK8ssandraOperatorChart, namespace)
-		}
-	}
+	action, result, err := applyRelease(t, options, componentRelease(defaultK8ssandraOperatorReleaseName,
+		namespace, component))
+	require.NoError(t, err, "unexpected error during k8ssandra-operator installation")
 
 	if !isControlPlane {
-		if slices.Contains(options.ExtraArgs["install"], helmInstallDryRun) {
+		if isDryRun(options) {
 			logger.Log(t, "SIMULATE checking pod availability for k8ssandra-operator along with patching K8SSANDRA_CONTROL_PLANE=false")
 		} else {
-			patchContent := "{\"spec\": {\"containers\": [{\"env\": [{\"name\":\"K8SSANDRA_CONTROL_PLANE\",\"value\":\"false\"}]}]}}"
-			/*
-				 CONTROL_PLANE: kind-k8ssandra-0
-				2022-04-21T21:43:24.2506565Z   DATA_PLANES: kind-k8ssandra-1,kind-k8ssandra-2
-			*/
-			isRunning, podName := IsPodRunning(t, options.KubectlOptions, "k8ssandra-operator")
-			if isRunning {
-				out, err := k8s.RunKubectlAndGetOutputE(t, options.KubectlOptions, "patch", podName, "-p", patchContent)
-				require.NoError(t, err, "failed to apply patch content on data-plane")
-
-				logger.Log(t, fmt.Sprintf("restarting operator, patch complete: %s", out))
-				RestartOperator(t, options.KubectlOptions.Namespace, options.KubectlOptions)
+			isRunning, podName := IsPodRunning(t, options.KubectlOptions, defaultK8ssandraOperatorReleaseName)
+			if !isRunning {
+				logger.Log(t, fmt.Sprintf("k8ssandra-operator pod is NOT available in namespace: %s", namespace))
+			} else if isDataPlaneOperator(t, options.KubectlOptions, namespace) {
+				logger.Log(t, fmt.Sprintf("k8ssandra-operator already set as data-plane, release action: %s, "+
+					"restart not required for pod: %s", action, podName))
 			} else {
-				logger.Log(t, fmt.Sprintf("k8ssandra-operator pod: %s is NOT available", podName))
+				patchDataPlaneOperator(t, options.KubectlOptions, namespace)
+				logger.Log(t, fmt.Sprintf("restarting operator, patch complete for pod: %s", podName))
+				RestartOperator(t, options.KubectlOptions.Namespace, options.KubectlOptions)
 			}
 		}
 	}
 
-	require.NoError(t, err, "unexpected error during k8ssandra-operator installation")
-	logger.Log(t, fmt.Sprintf("installation result: %s", result))
+	logger.Log(t, fmt.Sprintf("installation result of %s: %s", action, result))
+}
+
+// isDataPlaneOperator checks the K8SSANDRA_CONTROL_PLANE env of the k8ssandra-operator deployment is false.
+func isDataPlaneOperator(t testing.TestingT, options *k8s.KubectlOptions, namespace string) bool {
+	deployment, err := KubeClient(t, options).AppsV1().Deployments(namespace).Get(context.Background(),
+		defaultK8ssandraOperatorReleaseName, metav1.GetOptions{})
+	require.NoError(t, err, "expecting k8ssandra-operator deployment")
 
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name != defaultK8ssandraOperatorReleaseName {
+			continue
+		}
+		for _, env := range container.Env {
+			if env.Name == defaultControlPlaneKey {
+				return env.Value == strconv.FormatBool(false)
+			}
+		}
+	}
+	return false
+}
+
+// patchDataPlaneOperator sets the K8SSANDRA_CONTROL_PLANE env of the k8ssandra-operator deployment to false.
+func patchDataPlaneOperator(t testing.TestingT, options *k8s.KubectlOptions, namespace string) {
+	patch := fmt.Sprintf("{\"spec\":{\"template\":{\"spec\":{\"containers\":[{\"name\":\"%s\","+
+		"\"env\":[{\"name\":\"%s\",\"value\":\"false\"}]}]}}}}", defaultK8ssandraOperatorReleaseName,
+		defaultControlPlaneKey)
+
+	_, err := KubeClient(t, options).AppsV1().Deployments(namespace).Patch(context.Background(),
+		defaultK8ssandraOperatorReleaseName, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+	require.NoError(t, err, "failed to apply patch content on data-plane")
 }
 
-func InstallSetup(t *testing.T, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) map[string]model.ContextOption {
+func InstallSetup(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) map[string]model.ContextOption {
 
 	identity := FetchEnv(t, meta.AdminIdentity)
-	require.NotEmpty(t, identity, "expecting identity to be provided to apply preconditions")
+	if IsIdentityRequired(readinessConfig) {
+		require.NotEmpty(t, identity, "expecting identity to be provided to apply preconditions")
+	}
 
 	var contextConfigs = map[string]*k8s.KubectlOptions{}
 	var isRepoSetup = false
+	var versions = ResolveVersions(readinessConfig.ProvisionConfig)
 
 	for name, ctx := range readinessConfig.Contexts {
 
 		logger.Log(t, fmt.Sprintf("installation setup for: %s", name))
-
-		env := CreateIdentityEnv(meta.DefaultConfigPath, identity, ctx.CloudConfig.CredPath)
-		gcp.Switch(t, identity, env)
-
-		fullName := gcp.ConstructFullContextName(name, ctx.CloudConfig)
-		gcp.FetchCreds(t, ctx.CloudConfig, env, gcp.ConstructCloudClusterName(name, ctx.CloudConfig))
-		kubeConfig := k8s.NewKubectlOptions(fullName, meta.DefaultConfigPath,
-			readinessConfig.Contexts[name].Namespace)
-		SetCurrentContext(t, fullName, kubeConfig)
+		kubeConfig := ConnectContext(t, meta, identity, name, ctx)
 
 		helmOptions := createHelmOptions(kubeConfig, map[string]string{}, map[string]string{},
 			meta.Enable.Simulate)
 
 		if !isRepoSetup {
-			isRepoSetup = repoSetup(t, helmOptions)
+			isRepoSetup = repoSetup(t, helmOptions, versions)
 		}
 
-		installCertManager(t, kubeConfig, meta.Enable.Simulate)
+		installCertManager(t, kubeConfig, versions.CertManager, meta.Enable.Simulate)
 		contextConfigs[name] = kubeConfig
 
-		installTraefik(t, helmOptions, readinessConfig.Contexts[name], meta.Enable.Simulate)
+		installTraefik(t, helmOptions, versions.Traefik, readinessConfig.Contexts[name], meta.Enable.Simulate)
+
+		if readinessConfig.ProvisionConfig.K8cConfig.MedusaStandIn != nil {
+			installMedusaStandIn(t, helmOptions, versions.MinIO, readinessConfig.ProvisionConfig.K8cConfig,
+				ctx.Namespace, meta.Enable.Simulate)
+		}
 	}
 
 	return CreateContextOptions(t, readinessConfig, meta, contextConfigs)
 }
 
-func repoSetup(t *testing.T, helmOptions *helm.Options) bool {
-	logger.Log(t, "setting up repository entries")
+// ConnectContext provides the kubectl options of a context, fetching the cloud credentials of provisioned
+// clusters.
+func ConnectContext(t testing.TestingT, meta model.ProvisionMeta, identity string, name string,
+	ctx model.ContextConfig) *k8s.KubectlOptions {
 
-	rse1 := helm.RemoveRepoE(t, helmOptions, defaultCertManagerRepositoryName)
-	if rse1 != nil {
-		logger.Log(t, fmt.Sprintf("WARNING: failure encountered during attempted repo removal. %s", rse1.Error()))
-	}
-	helm.AddRepo(t, helmOptions, defaultCertManagerRepositoryName, defaultCertManagerRepositoryURL)
+	var kubeConfig *k8s.KubectlOptions
+	fullName := ConstructFullContextName(t, name, ctx)
+
+	if IsExistingCluster(ctx) {
+		logger.Log(t, fmt.Sprintf("existing cluster context: %s referenced, cloud identity not applicable", fullName))
+		kubeConfig = k8s.NewKubectlOptions(fullName, ExistingKubeConfigPath(meta, ctx), ctx.Namespace)
+	} else {
+		provider := FetchCloudProvider(t, ctx)
+		env := CreateIdentityEnv(t, meta.DefaultConfigPath, identity, ctx)
+		provider.Switch(t, identity, env)
 
-	rse2 := helm.RemoveRepoE(t, helmOptions, defaultK8ssandraRepositoryName)
-	if rse2 != nil {
-		logger.Log(t, fmt.Sprintf("WARNING: failure encountered during attempted repo removal. %s", rse2.Error()))
+		provider.FetchCreds(t, ctx.CloudConfig, env, provider.ConstructCloudClusterName(name, ctx.CloudConfig))
+		kubeConfig = k8s.NewKubectlOptions(fullName, meta.DefaultConfigPath, ctx.Namespace)
 	}
-	helm.AddRepo(t, helmOptions, defaultK8ssandraRepositoryName, defaultK8ssandraRepositoryURL)
+	SetCurrentContext(t, fullName, kubeConfig)
+	return kubeConfig
+}
+
+func repoSetup(t testing.TestingT, helmOptions *helm.Options, versions model.VersionsConfig) bool {
+	logger.Log(t, "setting up repository entries")
 
-	rse3 := helm.RemoveRepoE(t, helmOptions, defaultTraefikRepositoryName)
-	if rse3 != nil {
-		logger.Log(t, fmt.Sprintf("WARNING: failure encountered during attempted repo removal. %s", rse3.Error()))
+	for _, repository := range helmRepositories(versions) {
+		if err := removeRepo(t, helmOptions, repository[0]); err != nil {
+			logger.Log(t, fmt.Sprintf("WARNING: failure encountered during attempted repo removal. %s", err.Error()))
+		}
+		addRepo(t, helmOptions, repository[0], repository[1])
 	}
-	helm.AddRepo(t, helmOptions, defaultTraefikRepositoryName, defaultTraefikRepositoryURL)
 
-	_, err := helm.RunHelmCommandAndGetStdOutE(t, helmOptions, "repo", "update")
+	_, err := executor.RunHelm(t, helmOptions, "repo", "update")
 
 	require.NoError(t, err)
 	return true
 }
 
-func installTraefik(t *testing.T, helmOptions *helm.Options, config model.ContextConfig, isSimulate bool) {
+func addRepo(t testing.TestingT, helmOptions *helm.Options, name string, url string) {
+	_, err := executor.RunHelm(t, helmOptions, "repo", "add", name, url)
+	require.NoError(t, err, fmt.Sprintf("expecting helm repository: %s to be added", name))
+}
+
+func removeRepo(t testing.TestingT, helmOptions *helm.Options, name string) error {
+	_, err := executor.RunHelm(t, helmOptions, "repo", "remove", name)
+	return err
+}
+
+func installTraefik(t testing.TestingT, helmOptions *helm.Options, component model.ComponentVersion,
+	config model.ContextConfig, isSimulate bool) {
 
 	require.NotNil(t, helmOptions, "expecting helm options to install traefik")
 	require.NotNil(t, config, "expecting readiness config to install traefik")
@@ -305,63 +456,17 @@ func installTraefik(t *testing.T, helmOptions *helm.Options, config model.Contex
 	withoutNamespace := &helmOptions.KubectlOptions
 	(*withoutNamespace).Namespace = ""
 
-	DeleteResource(t, *withoutNamespace, "ClusterRoleBinding", defaultTraefikResourceName)
-	DeleteResource(t, *withoutNamespace, "ClusterRole", defaultTraefikResourceName)
-
-	_, _ = uninstallTraefik(t, helmOptions)
+	release := traefikRelease(component, config.NetworkConfig)
 
-	version := config.NetworkConfig.TraefikVersion
-	filePath := path.Join("../config/", config.NetworkConfig.TraefikValuesFile)
-	_, err := helmInstallFromFile(t, helmOptions, defaultTraefikRepositoryName, defaultTraefikChartName, version, filePath)
-
-	require.NoError(t, err, "expecting that Traefik can be installed")
-}
-
-func helmInstall(t *testing.T, options *helm.Options, releaseName string, chart string, namespace string) (string, error) {
-
-	if slices.Contains(options.ExtraArgs["install"], helmInstallDryRun) {
-		return helm.RunHelmCommandAndGetOutputE(t, options, "install", releaseName, chart,
-			"-n", namespace, "--create-namespace", options.ExtraArgs["install"][0], options.ExtraArgs["install"][1])
-	}
-	return helm.RunHelmCommandAndGetOutputE(t, options, "install", releaseName, chart,
-		"-n", namespace, "--create-namespace")
-}
-
-func helmInstallFromFile(t *testing.T, options *helm.Options, name string, chart string, version string,
-	filePath string) (string, error) {
-
-	if slices.Contains(options.ExtraArgs["install"], helmInstallDryRun) {
-		return helm.RunHelmCommandAndGetStdOutE(t, options, "install", name, chart,
-			"--version", version, "-f", filePath, options.ExtraArgs["install"][0], options.ExtraArgs["install"][1])
-	}
-	return helm.RunHelmCommandAndGetStdOutE(t, options, "install", name, chart,
-		"--version", version, "-f", filePath)
-}
-
-func uninstallTraefik(t *testing.T, helmOptions *helm.Options) (string, error) {
-
-	if slices.Contains(helmOptions.ExtraArgs["install"], helmInstallDryRun) {
-		return helm.RunHelmCommandAndGetOutputE(t, helmOptions, "uninstall", defaultTraefikRepositoryName,
-			helmOptions.ExtraArgs["install"][0], helmOptions.ExtraArgs["install"][1])
-	}
-	return helm.RunHelmCommandAndGetOutputE(t, helmOptions, "uninstall", defaultTraefikRepositoryName)
-}
-
-func uninstallK8ssandraOperator(t *testing.T, helmOptions *helm.Options) {
-
-	if slices.Contains(helmOptions.ExtraArgs["install"], helmInstallDryRun) {
-		var _, err = helm.RunHelmCommandAndGetStdOutE(t, helmOptions, "uninstall", defaultK8ssandraOperatorReleaseName,
-			"-n", helmOptions.KubectlOptions.Namespace, helmOptions.ExtraArgs["install"][0], helmOptions.ExtraArgs["install"][1])
-		if err != nil {
-			logger.Log(t, fmt.Sprintf("WARNING: failure encountered during attempted k8ssandra-operator uninstall. %s", err.Error()))
-		}
-
-	} else {
-		var _, err = helm.RunHelmCommandAndGetStdOutE(t, helmOptions, "uninstall", defaultK8ssandraOperatorReleaseName,
-			"-n", helmOptions.KubectlOptions.Namespace)
-		if err != nil {
-			logger.Log(t, fmt.Sprintf("WARNING: failure encountered during attempted k8ssandra-operator uninstall. %s", err.Error()))
-		}
+	// Cluster resources left behind by a removed release would conflict with a new installation.
+	deployed, err := fetchRelease(t, helmOptions, release.Name, release.Namespace)
+	require.NoError(t, err, "expecting Traefik release lookup")
+	if deployed == nil {
+		DeleteResource(t, *withoutNamespace, "ClusterRoleBinding", defaultTraefikResourceName)
+		DeleteResource(t, *withoutNamespace, "ClusterRole", defaultTraefikResourceName)
 	}
 
+	action, _, err := applyRelease(t, helmOptions, release)
+	require.NoError(t, err, "expecting that Traefik can be installed")
+	logger.Log(t, fmt.Sprintf("Traefik release action: %s", action))
 }
@@ -0,0 +1,77 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"errors"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestRepoSetup(t *testing.T) {
+	fake := executor.NewFake().Respond(executor.Helm, []string{"repo", "remove"}, "", errors.New("no repo named"))
+	kubeConfig := k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz")
+
+	require.True(t, repoSetup(executor.With(t, fake), createHelmOptions(kubeConfig, map[string]string{}, map[string]string{}, false),
+		ResolveVersions(model.ProvisionConfig{})))
+
+	added := fake.CommandsOf(executor.Helm, "repo", "add")
+	require.Len(t, added, 3)
+	require.Equal(t, []string{"repo", "add", defaultCertManagerRepositoryName, defaultCertManagerRepositoryURL},
+		added[0].Args)
+	require.Equal(t, "kind-k8ssandra-0", added[0].Context)
+	require.Len(t, fake.CommandsOf(executor.Helm, "repo", "update"), 1)
+}
+
+func TestDeleteResource(t *testing.T) {
+	fake := executor.NewFake()
+
+	DeleteResource(executor.With(t, fake), k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz"), "secret", "medusa-key")
+
+	commands := fake.CommandsOf(executor.Kubectl, "delete")
+	require.Len(t, commands, 1)
+	require.Equal(t, []string{"delete", "secret", "medusa-key"}, commands[0].Args)
+	require.Equal(t, "bootz", commands[0].Namespace)
+}
+
+func TestCollectDiagnostics(t *testing.T) {
+	fake := executor.NewFake().Respond(executor.Kubectl, []string{"get", "pods"}, "k8ssandra-operator-0 Running", nil)
+	t.Setenv(DefaultAdminIdentifier, "admin")
+
+	contexts := validContexts()
+	delete(contexts, "central")
+	meta := model.ProvisionMeta{ArtifactsRootDir: t.TempDir(), AdminIdentity: DefaultAdminIdentifier,
+		DefaultConfigPath: "/home/tester/.kube/config"}
+
+	CollectDiagnostics(executor.With(t, fake), meta, model.ReadinessConfig{Contexts: contexts})
+
+	commands := fake.CommandsOf(executor.Kubectl, "get")
+	require.Len(t, commands, len(diagnosticCommands("bootz")))
+	for _, command := range commands {
+		require.Equal(t, "kind-k8ssandra-0", command.Context)
+		require.Equal(t, "/home/tester/.kube/config", command.ConfigPath)
+	}
+
+	report, err := os.ReadFile(path.Join(meta.ArtifactsRootDir, defaultDiagnosticsFolder, "kind.log"))
+	require.NoError(t, err)
+	require.Contains(t, string(report), "k8ssandra-operator-0 Running")
+}
@@ -0,0 +1,133 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"context"
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultRestartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// ClientFactory provides the typed Kubernetes client of the context referenced by the kubectl options.
+type ClientFactory func(t testing.TestingT, options *k8s.KubectlOptions) (kubernetes.Interface, error)
+
+var (
+	clientFactoryMutex sync.RWMutex
+	clientFactory      ClientFactory = func(t testing.TestingT, options *k8s.KubectlOptions) (kubernetes.Interface, error) {
+		return k8s.GetKubernetesClientFromOptionsE(t, options)
+	}
+)
+
+// UseClientFactory replaces the client factory, e.g. with a fake clientset, providing a function restoring the
+// prior factory.
+func UseClientFactory(factory ClientFactory) func() {
+	clientFactoryMutex.Lock()
+	defer clientFactoryMutex.Unlock()
+
+	prior := clientFactory
+	clientFactory = factory
+	return func() {
+		clientFactoryMutex.Lock()
+		defer clientFactoryMutex.Unlock()
+		clientFactory = prior
+	}
+}
+
+// TransportClientFactory provides a client factory of the kube config contexts whose API transport is wrapped,
+// e.g. to record its requests.
+func TransportClientFactory(wrap func(context string, transport http.RoundTripper) http.RoundTripper) ClientFactory {
+	return func(t testing.TestingT, options *k8s.KubectlOptions) (kubernetes.Interface, error) {
+		configPath, err := options.GetConfigPath(t)
+		if err != nil {
+			return nil, err
+		}
+		config, err := k8s.LoadApiClientConfigE(configPath, options.ContextName)
+		if err != nil {
+			return nil, err
+		}
+		config.Wrap(func(transport http.RoundTripper) http.RoundTripper {
+			return wrap(options.ContextName, transport)
+		})
+		return kubernetes.NewForConfig(config)
+	}
+}
+
+// ReplayClientFactory provides a client factory serving every request from the transport of the context,
+// without requiring a kube config or a reachable API server.
+func ReplayClientFactory(transport func(context string) http.RoundTripper) ClientFactory {
+	return func(t testing.TestingT, options *k8s.KubectlOptions) (kubernetes.Interface, error) {
+		return kubernetes.NewForConfig(&rest.Config{Host: "https://replay.invalid",
+			Transport: transport(options.ContextName)})
+	}
+}
+
+// KubeClient provides the typed client of the context referenced by the kubectl options, its requests recorded
+// or replayed by the cassette carried by t.
+func KubeClient(t testing.TestingT, options *k8s.KubectlOptions) kubernetes.Interface {
+	clientFactoryMutex.RLock()
+	factory := clientFactory
+	clientFactoryMutex.RUnlock()
+
+	switch cassette := executor.For(t).(type) {
+	case *executor.Recorder:
+		factory = TransportClientFactory(cassette.Transport)
+	case *executor.Replayer:
+		factory = ReplayClientFactory(cassette.Transport)
+	}
+
+	client, err := factory(t, options)
+	require.NoError(t, err, fmt.Sprintf("expecting a kubernetes client for context: %s", options.ContextName))
+	return client
+}
+
+// restartDeployment deletes the pods of the deployment, then restarts its rollout in the same way as
+// kubectl rollout restart.
+func restartDeployment(t testing.TestingT, options *k8s.KubectlOptions, namespace string, selector string,
+	deployment string) {
+
+	client := KubeClient(t, options)
+	pods, err := client.CoreV1().Pods(namespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: selector})
+
+	if err == nil {
+		for _, pod := range pods.Items {
+			deleteErr := client.CoreV1().Pods(namespace).Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+			if deleteErr != nil {
+				logger.Log(t, fmt.Sprintf("WARNING: attempt to delete pod: %s failed due to: %s", pod.Name, deleteErr))
+			}
+		}
+	}
+
+	patch := fmt.Sprintf("{\"spec\":{\"template\":{\"metadata\":{\"annotations\":{\"%s\":\"%s\"}}}}}",
+		defaultRestartedAtAnnotation, time.Now().Format(time.RFC3339))
+	_, patchErr := client.AppsV1().Deployments(namespace).Patch(context.Background(), deployment,
+		types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+	require.NoError(t, patchErr, fmt.Sprintf("expecting restart of deployment: %s", deployment))
+}
@@ -0,0 +1,170 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"context"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"os"
+	"path"
+	"testing"
+)
+
+// useFakeClient routes the typed client calls of the test to a fake clientset seeded with the objects.
+func useFakeClient(t *testing.T, objects ...runtime.Object) *fake.Clientset {
+	client := fake.NewSimpleClientset(objects...)
+	t.Cleanup(UseClientFactory(func(t terratesting.TestingT, options *k8s.KubectlOptions) (kubernetes.Interface, error) {
+		return client, nil
+	}))
+	return client
+}
+
+func operatorDeployment() *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: defaultK8ssandraOperatorReleaseName, Namespace: "bootz"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: defaultK8ssandraOperatorReleaseName,
+				Env: []corev1.EnvVar{{Name: "WATCH_NAMESPACE", Value: "bootz"}}}},
+		}}},
+	}
+}
+
+func operatorPod(name string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "bootz",
+			Labels: map[string]string{"app.kubernetes.io/name": "k8ssandra-operator"}},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestFetchServiceAccountSecret(t *testing.T) {
+	useFakeClient(t,
+		&corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: defaultK8ssandraOperatorReleaseName, Namespace: "bootz"},
+			Secrets:    []corev1.ObjectReference{{Name: "k8ssandra-operator-token-x7z"}},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "k8ssandra-operator-token-x7z", Namespace: "bootz"},
+			Data: map[string][]byte{
+				corev1.ServiceAccountTokenKey:  []byte("token-value"),
+				corev1.ServiceAccountRootCAKey: []byte("ca-value"),
+			},
+		})
+	options := k8s.NewKubectlOptions("kind-k8ssandra-0", "", "")
+
+	secret := FetchSecret(t, options, defaultK8ssandraOperatorReleaseName, "bootz")
+	require.Equal(t, "k8ssandra-operator-token-x7z", secret)
+	require.Equal(t, "bootz", options.Namespace)
+	require.Equal(t, "token-value", FetchToken(t, options, secret, "bootz"))
+
+	cert, err := FetchCertificate(t, options, secret, "bootz")
+	require.NoError(t, err)
+	require.Equal(t, []byte("ca-value"), cert)
+
+	_, err = FetchCertificate(t, options, "missing", "bootz")
+	require.Error(t, err)
+}
+
+func TestCreateGenericSecret(t *testing.T) {
+	client := useFakeClient(t)
+	configPath := path.Join(t.TempDir(), defaultKubeConfigFileName)
+	require.NoError(t, os.WriteFile(configPath, []byte("apiVersion: v1"), defaultTempFilePerm))
+	options := k8s.NewKubectlOptions("kind-k8ssandra-0", configPath, "")
+
+	CreateGenericSecret(t, "bootz", options)
+	require.NoError(t, os.WriteFile(configPath, []byte("apiVersion: v2"), defaultTempFilePerm))
+	CreateGenericSecret(t, "bootz", options)
+
+	secret, err := client.CoreV1().Secrets("bootz").Get(context.Background(), defaultK8ssandraSecret, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{defaultKubeConfigFileName: []byte("apiVersion: v2")}, secret.Data,
+		"expecting an existing secret to be replaced")
+}
+
+func TestWaitForEndpoint(t *testing.T) {
+	client := useFakeClient(t)
+	options := k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz")
+	name := defaultK8ssandraOperatorReleaseName + "-" + defaultWebhookServiceName
+
+	require.Empty(t, WaitForEndpoint(t, options, name), "expecting no address for a missing endpoint")
+
+	_, err := client.CoreV1().Endpoints("bootz").Create(context.Background(), &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "bootz"},
+		Subsets:    []corev1.EndpointSubset{{NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.1.0.8"}}}},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.Empty(t, WaitForEndpoint(t, options, name), "expecting no address while not ready")
+
+	_, err = client.CoreV1().Endpoints("bootz").Update(context.Background(), &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "bootz"},
+		Subsets:    []corev1.EndpointSubset{{Addresses: []corev1.EndpointAddress{{IP: "10.1.0.9"}}}},
+	}, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "10.1.0.9", WaitForEndpoint(t, options, name))
+}
+
+func TestIsPodRunning(t *testing.T) {
+	useFakeClient(t,
+		operatorPod("k8ssandra-operator-5d9c-pending", corev1.PodPending),
+		operatorPod("k8ssandra-operator-5d9c-abc12", corev1.PodRunning))
+	options := k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz")
+
+	isRunning, podName := IsPodRunning(t, options, defaultK8ssandraOperatorReleaseName)
+	require.True(t, isRunning)
+	require.Equal(t, "k8ssandra-operator-5d9c-abc12", podName)
+
+	isRunning, _ = IsPodRunning(t, k8s.NewKubectlOptions("kind-k8ssandra-0", "", "other"),
+		defaultK8ssandraOperatorReleaseName)
+	require.False(t, isRunning)
+}
+
+func TestRestartDeployment(t *testing.T) {
+	client := useFakeClient(t, operatorDeployment(), operatorPod("k8ssandra-operator-5d9c-abc12", corev1.PodRunning))
+	options := k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz")
+
+	restartDeployment(t, options, "bootz", "app.kubernetes.io/name=k8ssandra-operator",
+		defaultK8ssandraOperatorReleaseName)
+
+	pods, err := client.CoreV1().Pods("bootz").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, pods.Items)
+
+	deployment, err := client.AppsV1().Deployments("bootz").Get(context.Background(),
+		defaultK8ssandraOperatorReleaseName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NotEmpty(t, deployment.Spec.Template.Annotations[defaultRestartedAtAnnotation])
+}
+
+func TestPatchDataPlaneOperator(t *testing.T) {
+	client := useFakeClient(t, operatorDeployment())
+
+	patchDataPlaneOperator(t, k8s.NewKubectlOptions("kind-k8ssandra-1", "", "bootz"), "bootz")
+
+	deployment, err := client.AppsV1().Deployments("bootz").Get(context.Background(),
+		defaultK8ssandraOperatorReleaseName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []corev1.EnvVar{{Name: "WATCH_NAMESPACE", Value: "bootz"},
+		{Name: defaultControlPlaneKey, Value: "false"}}, deployment.Spec.Template.Spec.Containers[0].Env)
+}
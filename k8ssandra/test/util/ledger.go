@@ -0,0 +1,175 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	DefaultProvisionIdKey = "K8C_PROVISION_ID"
+	defaultLedgerFileName = "run-ledger.json"
+)
+
+// DefaultArtifactsRootDir provides the artifacts root directory of a provision identifier.
+func DefaultArtifactsRootDir(provisionId string) string {
+	return path.Join(os.TempDir(), prefixFolderName+provisionId)
+}
+
+// LoadLedger provides the run ledger of the provisioning, empty when not yet persisted.
+func LoadLedger(meta model.ProvisionMeta) (model.RunLedger, error) {
+	var ledger = model.RunLedger{
+		ProvisionId:      meta.ProvisionId,
+		ArtifactsRootDir: meta.ArtifactsRootDir,
+	}
+	if meta.ArtifactsRootDir == "" {
+		return ledger, nil
+	}
+
+	content, err := ioutil.ReadFile(ledgerPath(meta.ArtifactsRootDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return ledger, nil
+	} else if err != nil {
+		return ledger, err
+	}
+
+	err = json.Unmarshal(content, &ledger)
+	return ledger, err
+}
+
+// SaveLedger persists the run ledger in the artifacts root directory.
+func SaveLedger(meta model.ProvisionMeta, ledger model.RunLedger) error {
+	if meta.ArtifactsRootDir == "" {
+		return errors.New("an artifacts root directory is required to save the run ledger")
+	}
+	if err := os.MkdirAll(meta.ArtifactsRootDir, defaultTempFilePerm); err != nil {
+		return err
+	}
+
+	ledger.ProvisionId = meta.ProvisionId
+	ledger.ArtifactsRootDir = meta.ArtifactsRootDir
+	ledger.UpdatedAt = time.Now().UTC()
+
+	content, err := json.MarshalIndent(ledger, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(ledgerPath(meta.ArtifactsRootDir), content, defaultTempFilePerm)
+}
+
+// UpdateLedger applies the update to the persisted run ledger, the ledger is not kept when simulating.
+func UpdateLedger(t testing.TestingT, meta model.ProvisionMeta, update func(ledger *model.RunLedger)) {
+	if meta.Enable.Simulate || meta.ArtifactsRootDir == "" {
+		return
+	}
+
+	ledger, err := LoadLedger(meta)
+	require.NoError(t, err, fmt.Sprintf("unable to load the run ledger in: %s", meta.ArtifactsRootDir))
+	if ledger.Contexts == nil {
+		ledger.Contexts = map[string]model.LedgerContext{}
+	}
+
+	update(&ledger)
+	require.NoError(t, SaveLedger(meta, ledger), fmt.Sprintf("unable to save the run ledger in: %s",
+		meta.ArtifactsRootDir))
+}
+
+// RecordLedgerError adds an error to the run ledger, logging a warning rather than failing when the ledger
+// is unavailable as the test may already be failing.
+func RecordLedgerError(t testing.TestingT, meta model.ProvisionMeta, phase model.Phase, context string, message string) {
+	if meta.Enable.Simulate || meta.ArtifactsRootDir == "" {
+		return
+	}
+
+	ledger, err := LoadLedger(meta)
+	if err == nil {
+		ledger.Errors = append(ledger.Errors, model.LedgerError{
+			Phase:      phase,
+			Context:    context,
+			Message:    message,
+			OccurredAt: time.Now().UTC(),
+		})
+		err = SaveLedger(meta, ledger)
+	}
+	if err != nil {
+		logger.Log(t, fmt.Sprintf("WARNING: unable to record the error: %s in the run ledger: %s",
+			message, err.Error()))
+	}
+}
+
+// RecordProvisionResults adds an error to the run ledger for every failed provisioning result.
+func RecordProvisionResults(t testing.TestingT, meta model.ProvisionMeta, results []model.ProvisionResult) {
+	for _, result := range results {
+		if result.Success {
+			continue
+		}
+		logger.Log(t, fmt.Sprintf("provision: %s failed in step: %s classified as: %s", result.Context,
+			result.Step, result.Classification))
+		UpdateLedger(t, meta, func(ledger *model.RunLedger) {
+			ledger.Errors = append(ledger.Errors, model.LedgerError{
+				Phase:          result.Phase,
+				Context:        result.Context,
+				Message:        fmt.Sprintf("%s failed: %s", result.Step, result.Error),
+				Classification: result.Classification,
+				OccurredAt:     time.Now().UTC(),
+			})
+		})
+	}
+}
+
+// FindLedgers provides the run ledgers of the artifacts root directories in the parent directory, most
+// recently updated first.
+func FindLedgers(parentDir string) ([]model.RunLedger, error) {
+	paths, err := filepath.Glob(path.Join(parentDir, prefixFolderName+"*", defaultLedgerFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var ledgers []model.RunLedger
+	for _, ledgerFile := range paths {
+		content, err := ioutil.ReadFile(ledgerFile)
+		if err != nil {
+			return nil, err
+		}
+		var ledger model.RunLedger
+		if err := json.Unmarshal(content, &ledger); err != nil {
+			return nil, fmt.Errorf("%s: %w", ledgerFile, err)
+		}
+		ledgers = append(ledgers, ledger)
+	}
+
+	sort.SliceStable(ledgers, func(i, j int) bool {
+		return ledgers[i].UpdatedAt.After(ledgers[j].UpdatedAt)
+	})
+	return ledgers, nil
+}
+
+func ledgerPath(artifactsRootDir string) string {
+	return path.Join(artifactsRootDir, defaultLedgerFileName)
+}
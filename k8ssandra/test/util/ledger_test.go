@@ -0,0 +1,105 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestLedgerPersistence(t *testing.T) {
+	meta := model.ProvisionMeta{ProvisionId: "k8c-test", ArtifactsRootDir: path.Join(t.TempDir(), "cloud-k8c-test")}
+
+	ledger, err := LoadLedger(meta)
+	require.NoError(t, err)
+	require.Equal(t, meta.ProvisionId, ledger.ProvisionId)
+	require.Empty(t, ledger.CompletedPhases)
+
+	UpdateLedger(t, meta, func(ledger *model.RunLedger) {
+		ledger.Contexts["central"] = model.LedgerContext{ModulesFolder: "/tmp/TestK8cSmoke1234",
+			KubeConfigPath: "/home/tester/.kube/config"}
+		ledger.CompletedPhases = []model.Phase{model.PhaseProvision}
+	})
+	RecordLedgerError(t, meta, model.PhaseInstall, "central", "install failed")
+
+	ledger, err = LoadLedger(meta)
+	require.NoError(t, err)
+	require.Equal(t, meta.ArtifactsRootDir, ledger.ArtifactsRootDir)
+	require.Equal(t, "/tmp/TestK8cSmoke1234", ledger.Contexts["central"].ModulesFolder)
+	require.Equal(t, []model.Phase{model.PhaseProvision}, ledger.CompletedPhases)
+	require.Len(t, ledger.Errors, 1)
+	require.Equal(t, model.PhaseInstall, ledger.Errors[0].Phase)
+	require.Equal(t, "install failed", ledger.Errors[0].Message)
+	require.False(t, ledger.UpdatedAt.IsZero())
+
+	require.Error(t, SaveLedger(model.ProvisionMeta{}, ledger))
+}
+
+func TestLedgerNotKeptWhenSimulating(t *testing.T) {
+	meta := model.ProvisionMeta{Enable: model.EnableConfig{Simulate: true}, ProvisionId: "k8c-test",
+		ArtifactsRootDir: path.Join(t.TempDir(), "cloud-k8c-test")}
+
+	UpdateLedger(t, meta, func(ledger *model.RunLedger) {
+		ledger.CompletedPhases = []model.Phase{model.PhaseProvision}
+	})
+	RecordLedgerError(t, meta, model.PhaseProvision, "", "provision failed")
+
+	_, err := os.Stat(meta.ArtifactsRootDir)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestFindLedgers(t *testing.T) {
+	parentDir := t.TempDir()
+	older := model.ProvisionMeta{ProvisionId: "older", ArtifactsRootDir: path.Join(parentDir, "cloud-k8c-older")}
+	newer := model.ProvisionMeta{ProvisionId: "newer", ArtifactsRootDir: path.Join(parentDir, "cloud-k8c-newer")}
+
+	require.NoError(t, SaveLedger(older, model.RunLedger{}))
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, SaveLedger(newer, model.RunLedger{}))
+	require.NoError(t, os.MkdirAll(path.Join(parentDir, "cloud-k8c-empty"), defaultTempFilePerm))
+
+	ledgers, err := FindLedgers(parentDir)
+	require.NoError(t, err)
+	require.Len(t, ledgers, 2)
+	require.Equal(t, "newer", ledgers[0].ProvisionId)
+	require.Equal(t, "older", ledgers[1].ProvisionId)
+}
+
+func TestDefaultArtifactsRootDir(t *testing.T) {
+	require.Equal(t, path.Join(os.TempDir(), "cloud-k8c-whwimk"), DefaultArtifactsRootDir("whwimk"))
+}
+
+func TestRecordProvisionResults(t *testing.T) {
+	meta := model.ProvisionMeta{ProvisionId: "k8c-test", ArtifactsRootDir: path.Join(t.TempDir(), "cloud-k8c-test")}
+
+	RecordProvisionResults(t, meta, []model.ProvisionResult{
+		{Success: true, Context: "central", Phase: model.PhaseProvision, Step: defaultApplyStep},
+		{Context: "east", Phase: model.PhaseProvision, Step: defaultPlanStep, Error: "exit status 1",
+			Classification: model.FailureConfig},
+	})
+
+	ledger, err := LoadLedger(meta)
+	require.NoError(t, err)
+	require.Len(t, ledger.Errors, 1)
+	require.Equal(t, "east", ledger.Errors[0].Context)
+	require.Equal(t, model.FailureConfig, ledger.Errors[0].Classification)
+	require.Equal(t, "plan failed: exit status 1", ledger.Errors[0].Message)
+}
@@ -0,0 +1,174 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"fmt"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	DefaultReadinessConfigKey = "K8C_READINESS_CONFIG"
+
+	defaultRetries     = 30
+	defaultSleepSecs   = 20
+	defaultTimeoutSecs = 240
+)
+
+// envPattern matches ${NAME} and ${NAME:-default} references.
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?}`)
+
+// LoadReadinessConfig reads the provision meta and readiness configuration, including contexts, from a YAML or JSON file.
+func LoadReadinessConfig(t testing.TestingT, filePath string) (model.ProvisionMeta, model.ReadinessConfig) {
+	logger.Log(t, fmt.Sprintf("loading readiness configuration from: %s", filePath))
+	meta, config, err := ParseReadinessConfig(filePath)
+	require.NoError(t, err, fmt.Sprintf("expecting readiness configuration to be loaded from: %s", filePath))
+	return meta, config
+}
+
+// ParseReadinessConfig reads the file, interpolates ${ENV} references of the string values and applies defaults.
+// The references are interpolated once parsed, an environment value is never read as YAML.
+func ParseReadinessConfig(filePath string) (model.ProvisionMeta, model.ReadinessConfig, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return model.ProvisionMeta{}, model.ReadinessConfig{}, err
+	}
+
+	file, err := parser.ParseBytes(content, 0)
+	if err != nil {
+		return model.ProvisionMeta{}, model.ReadinessConfig{}, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	var readinessFile model.ReadinessFile
+	if len(file.Docs) > 0 && file.Docs[0].Body != nil {
+		body := file.Docs[0].Body
+		if err := InterpolateEnvValues(body); err != nil {
+			return model.ProvisionMeta{}, model.ReadinessConfig{}, fmt.Errorf("%s: %w", filePath, err)
+		}
+		if err := yaml.NodeToValue(body, &readinessFile, yaml.Strict()); err != nil {
+			return model.ProvisionMeta{}, model.ReadinessConfig{}, fmt.Errorf("%s: %w", filePath, err)
+		}
+	}
+
+	if err := ApplyDefaults(&readinessFile.ProvisionMeta, &readinessFile.ReadinessConfig); err != nil {
+		return model.ProvisionMeta{}, model.ReadinessConfig{}, err
+	}
+	return readinessFile.ProvisionMeta, readinessFile.ReadinessConfig, nil
+}
+
+// InterpolateEnv replaces ${NAME} with the environment value, or the default of ${NAME:-default} when unset.
+func InterpolateEnv(content string) (string, error) {
+	var missing []string
+	result := interpolateEnv(content, &missing)
+	if err := missingEnvError(missing); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// InterpolateEnvValues interpolates the ${ENV} references of every string value of the parsed YAML node.
+func InterpolateEnvValues(node ast.Node) error {
+	visitor := &envVisitor{}
+	ast.Walk(visitor, node)
+	return missingEnvError(visitor.missing)
+}
+
+type envVisitor struct {
+	missing []string
+}
+
+func (v *envVisitor) Visit(node ast.Node) ast.Visitor {
+	if value, isString := node.(*ast.StringNode); isString {
+		value.Value = interpolateEnv(value.Value, &v.missing)
+	}
+	return v
+}
+
+func interpolateEnv(content string, missing *[]string) string {
+	return envPattern.ReplaceAllStringFunc(content, func(reference string) string {
+		groups := envPattern.FindStringSubmatch(reference)
+		if value, found := os.LookupEnv(groups[1]); found {
+			return value
+		}
+		if groups[2] != "" {
+			return groups[3]
+		}
+		*missing = append(*missing, groups[1])
+		return reference
+	})
+}
+
+func missingEnvError(missing []string) error {
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	return fmt.Errorf("environment variables referenced but not set: %s", strings.Join(missing, ", "))
+}
+
+// ApplyDefaults sets the provision defaults for any value not provided.
+func ApplyDefaults(meta *model.ProvisionMeta, config *model.ReadinessConfig) error {
+
+	provisionConfig := &config.ProvisionConfig
+	if provisionConfig.DefaultRetries == 0 {
+		provisionConfig.DefaultRetries = defaultRetries
+	}
+	if provisionConfig.DefaultSleepSecs == 0 {
+		provisionConfig.DefaultSleepSecs = defaultSleepSecs
+	}
+	if provisionConfig.DefaultTimeoutSecs == 0 {
+		provisionConfig.DefaultTimeoutSecs = defaultTimeoutSecs
+	}
+
+	if config.UniqueId == "" {
+		config.UniqueId = strings.ToLower(random.UniqueId())
+	}
+
+	for name, ctx := range config.Contexts {
+		if ctx.Name == "" {
+			ctx.Name = name
+			config.Contexts[name] = ctx
+		}
+	}
+
+	if meta.AdminIdentity == "" {
+		meta.AdminIdentity = DefaultAdminIdentifier
+	}
+	if meta.ProvisionId != "" && meta.ArtifactsRootDir == "" {
+		meta.ArtifactsRootDir = DefaultArtifactsRootDir(meta.ProvisionId)
+	}
+	if meta.DefaultConfigPath == "" {
+		home, configPath, err := defaultKubeConfigPath()
+		if err != nil {
+			return fmt.Errorf("unable to locate home directory for config path: %w", err)
+		}
+		meta.DefaultConfigDir = home
+		meta.DefaultConfigPath = configPath
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInterpolateEnv(t *testing.T) {
+	t.Setenv("K8C_TEST_PROJECT", "community-ecosystem")
+
+	out, err := InterpolateEnv("project: ${K8C_TEST_PROJECT}, id: ${K8C_TEST_UNSET:-k8c-abc}, empty: ${K8C_TEST_UNSET:-}")
+	require.NoError(t, err)
+	require.Equal(t, "project: community-ecosystem, id: k8c-abc, empty: ", out)
+
+	_, err = InterpolateEnv("cred: ${K8C_TEST_UNSET_B}/${K8C_TEST_UNSET_A}")
+	require.EqualError(t, err, "environment variables referenced but not set: K8C_TEST_UNSET_A, K8C_TEST_UNSET_B")
+}
+
+func TestParseReadinessConfig(t *testing.T) {
+	t.Setenv("HOME", "/home/tester")
+	t.Setenv("K8C_PROVISION_ID", "k8c-test01")
+
+	meta, config, err := ParseReadinessConfig(filepath.Join("..", "testdata", "scenario_1", "readiness-config.yaml"))
+	require.NoError(t, err)
+
+	require.Equal(t, []model.Phase{model.PhaseProvision, model.PhasePreInstall, model.PhaseInstall,
+		model.PhaseValidate}, meta.Enable.Phases)
+	require.Equal(t, "k8c-test01", meta.ProvisionId)
+	require.Equal(t, filepath.Join(os.TempDir(), "cloud-k8c-k8c-test01"), meta.ArtifactsRootDir)
+	require.Equal(t, "/home/tester/.kube/kubeconfig", meta.DefaultConfigPath)
+
+	require.Len(t, config.Contexts, 2)
+	central := config.Contexts["rio-c1walle100"]
+	require.Equal(t, "rio-c1walle100", central.Name)
+	require.Equal(t, "/home/tester/.config/gcloud/application_default_credentials.json", central.CloudConfig.CredPath)
+	require.Len(t, central.CloudConfig.PoolRackConfigs, 3)
+	require.Equal(t, "10.5.32.0/16", central.NetworkConfig.SubnetCidrBlock)
+	require.True(t, IsControlPlane(central))
+	require.NotEmpty(t, config.UniqueId)
+}
+
+func TestParseReadinessConfigSpecialValues(t *testing.T) {
+	value := "pa:ss #word \"quoted\" 'single'\nunknown_setting: true"
+	t.Setenv("K8C_TEST_PROJECT", value)
+	filePath := filepath.Join(t.TempDir(), "readiness.yaml")
+	content := "readiness_config:\n  contexts:\n    kind-0:\n      namespace: bootz\n" +
+		"      cloud_config:\n        project: ${K8C_TEST_PROJECT}\n        cred_path: \"${HOME}/${K8C_TEST_PROJECT}\"\n"
+	require.NoError(t, os.WriteFile(filePath, []byte(content), defaultTempFilePerm))
+
+	_, config, err := ParseReadinessConfig(filePath)
+	require.NoError(t, err)
+	require.Equal(t, value, config.Contexts["kind-0"].CloudConfig.Project)
+	require.Equal(t, os.Getenv("HOME")+"/"+value, config.Contexts["kind-0"].CloudConfig.CredPath)
+
+	require.NoError(t, os.WriteFile(filePath, []byte("# ${K8C_TEST_UNSET} is not referenced\n"+
+		"readiness_config:\n  unique_id: ${K8C_TEST_UNSET_A}\n"), defaultTempFilePerm))
+	_, _, err = ParseReadinessConfig(filePath)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "environment variables referenced but not set: K8C_TEST_UNSET_A")
+}
+
+func TestParseReadinessConfigDefaults(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "readiness.json")
+	content := `{"readiness_config": {"contexts": {"kind-0": {"namespace": "bootz"}}}}`
+	require.NoError(t, os.WriteFile(filePath, []byte(content), defaultTempFilePerm))
+
+	meta, config, err := ParseReadinessConfig(filePath)
+	require.NoError(t, err)
+
+	require.Equal(t, DefaultAdminIdentifier, meta.AdminIdentity)
+	require.Equal(t, defaultRetries, config.ProvisionConfig.DefaultRetries)
+	require.Equal(t, defaultSleepSecs, config.ProvisionConfig.DefaultSleepSecs)
+	require.Equal(t, defaultTimeoutSecs, config.ProvisionConfig.DefaultTimeoutSecs)
+	require.Equal(t, "kind-0", config.Contexts["kind-0"].Name)
+}
+
+func TestParseReadinessConfigUnknownField(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "readiness.yaml")
+	require.NoError(t, os.WriteFile(filePath, []byte("readiness_config:\n  unknown_setting: true\n"), defaultTempFilePerm))
+
+	_, _, err := ParseReadinessConfig(filePath)
+	require.Error(t, err)
+}
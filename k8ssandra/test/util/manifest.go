@@ -0,0 +1,249 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"errors"
+	"fmt"
+	"github.com/goccy/go-yaml"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/cloud"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	"os"
+	"path"
+)
+
+const (
+	defaultK8ssandraClusterApiVersion = "k8ssandra.io/v1alpha1"
+	defaultK8ssandraClusterKind       = "K8ssandraCluster"
+	defaultCassandraVersion           = "4.0.1"
+	defaultZoneLabel                  = "topology.kubernetes.io/zone"
+	defaultManifestFileName           = "k8ssandra-cluster.yaml"
+)
+
+// GenerateK8ssandraCluster builds the K8ssandraCluster of the readiness configuration, with a datacenter per context
+// and a rack per pool of the context.
+func GenerateK8ssandraCluster(readinessConfig model.ReadinessConfig) (model.K8ssandraCluster, error) {
+
+	k8cConfig := readinessConfig.ProvisionConfig.K8cConfig
+	if k8cConfig.ClusterName == "" {
+		return model.K8ssandraCluster{}, errors.New("a cluster name is required to generate the K8ssandraCluster")
+	}
+
+	cluster := model.K8ssandraCluster{
+		ApiVersion: defaultK8ssandraClusterApiVersion,
+		Kind:       defaultK8ssandraClusterKind,
+		Metadata:   model.ObjectMeta{Name: k8cConfig.ClusterName},
+		Spec: model.K8ssandraClusterSpec{Cassandra: model.CassandraClusterSpec{
+			ServerVersion: k8cConfig.CassandraVersion,
+		}},
+	}
+	if cluster.Spec.Cassandra.ServerVersion == "" {
+		cluster.Spec.Cassandra.ServerVersion = defaultCassandraVersion
+	}
+
+	var storage *model.MedusaStorage
+	for _, name := range sortedContextNames(readinessConfig) {
+		ctx := readinessConfig.Contexts[name]
+
+		datacenter := model.CassandraDatacenterSpec{Metadata: model.ObjectMeta{Name: name}}
+		var provider cloud.CloudProvider
+		if IsExistingCluster(ctx) {
+			datacenter.K8sContext = ctx.ExistingCluster.ContextName
+		} else {
+			var err error
+			provider, err = cloud.Lookup(ctx.CloudConfig.Type)
+			if err != nil {
+				return model.K8ssandraCluster{}, err
+			}
+			datacenter.K8sContext = provider.ConstructFullContextName(name, ctx.CloudConfig)
+
+			if storage == nil || IsControlPlane(ctx) {
+				medusaStorage := provider.MedusaStorage(readinessConfig, name, ctx)
+				storage = &medusaStorage
+			}
+		}
+
+		for _, pool := range ctx.CloudConfig.PoolRackConfigs {
+			zone := pool.Location
+			if provider != nil && pool.Location != "" {
+				zone = provider.NodeZone(pool.Location, ctx.CloudConfig)
+			}
+			rack, err := generateRack(pool, zone)
+			if err != nil {
+				return model.K8ssandraCluster{}, fmt.Errorf("context: %s %w", name, err)
+			}
+			datacenter.Racks = append(datacenter.Racks, rack)
+		}
+
+		// A node per rack unless sized, the operator requiring at least a node.
+		datacenter.Size = k8cConfig.DatacenterSize
+		if datacenter.Size == 0 {
+			datacenter.Size = len(datacenter.Racks)
+		}
+		if datacenter.Size == 0 {
+			datacenter.Size = 1
+		}
+		cluster.Spec.Cassandra.Datacenters = append(cluster.Spec.Cassandra.Datacenters, datacenter)
+	}
+
+	if k8cConfig.MedusaSecretName != "" {
+		properties := model.MedusaStorageProperties{
+			StorageSecretRef: corev1.LocalObjectReference{Name: k8cConfig.MedusaSecretName},
+		}
+		if k8cConfig.MedusaStandIn != nil {
+			properties = standInStorage(k8cConfig)
+		} else if storage != nil {
+			properties.StorageProvider = storage.StorageProvider
+			properties.BucketName = storage.BucketName
+			properties.Region = storage.Region
+		}
+		cluster.Spec.Medusa = &model.MedusaClusterSpec{StorageProperties: properties}
+	}
+	return cluster, nil
+}
+
+// RenderK8ssandraCluster renders the manifest, merging the overlay file when provided.
+func RenderK8ssandraCluster(cluster model.K8ssandraCluster, overlayPath string) ([]byte, error) {
+
+	content, err := yaml.Marshal(&cluster)
+	if err != nil || overlayPath == "" {
+		return content, err
+	}
+
+	overlayContent, err := ioutil.ReadFile(overlayPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the overlay: %s, %w", overlayPath, err)
+	}
+
+	var base, overlay map[string]interface{}
+	if err := yaml.Unmarshal(content, &base); err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(overlayContent, &overlay); err != nil {
+		return nil, fmt.Errorf("unable to parse the overlay: %s, %w", overlayPath, err)
+	}
+	return yaml.Marshal(mergeOverlay(base, overlay))
+}
+
+// WriteK8ssandraCluster writes the generated manifest to the artifacts root, providing the manifest path.
+func WriteK8ssandraCluster(meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) (string, error) {
+	if meta.ArtifactsRootDir == "" {
+		return "", errors.New("an artifacts root directory is required to write the K8ssandraCluster")
+	}
+
+	cluster, err := GenerateK8ssandraCluster(readinessConfig)
+	if err != nil {
+		return "", err
+	}
+	content, err := RenderK8ssandraCluster(cluster, OverlayPath(readinessConfig))
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(meta.ArtifactsRootDir, defaultTempFilePerm); err != nil {
+		return "", err
+	}
+	manifestPath := path.Join(meta.ArtifactsRootDir, defaultManifestFileName)
+	return manifestPath, ioutil.WriteFile(manifestPath, content, defaultTempFilePerm)
+}
+
+// OverlayPath provides the overlay file of the K8ssandraCluster, relative paths resolved from the config folder.
+func OverlayPath(readinessConfig model.ReadinessConfig) string {
+	return configFilePath(readinessConfig.ProvisionConfig.K8cConfig.OverlayFilePath)
+}
+
+// generateRack pins the rack to its nodes by the rack label, or else by the zone label of its location.
+func generateRack(pool model.PoolRackConfig, zone string) (model.CassandraRack, error) {
+	rack := model.CassandraRack{Name: pool.Name}
+	if pool.Label == "" {
+		if zone != "" {
+			rack.NodeAffinityLabels = map[string]string{defaultZoneLabel: zone}
+		}
+		return rack, nil
+	}
+
+	key, value, err := splitRackLabel(pool.Label)
+	if err != nil {
+		return rack, fmt.Errorf("rack: %s %w", pool.Name, err)
+	}
+	rack.NodeAffinityLabels = map[string]string{key: value}
+	return rack, nil
+}
+
+// mergeOverlay merges the overlay into the base. Maps are merged recursively, lists of named entries such as
+// the datacenters and racks are merged by name, any other value of the overlay replaces the base value.
+func mergeOverlay(base map[string]interface{}, overlay map[string]interface{}) map[string]interface{} {
+	for key, overlayValue := range overlay {
+		switch value := overlayValue.(type) {
+		case map[string]interface{}:
+			if baseValue, ok := base[key].(map[string]interface{}); ok {
+				base[key] = mergeOverlay(baseValue, value)
+				continue
+			}
+		case []interface{}:
+			if baseValue, ok := base[key].([]interface{}); ok && isNamedList(baseValue) && isNamedList(value) {
+				base[key] = mergeNamedList(baseValue, value)
+				continue
+			}
+		}
+		base[key] = overlayValue
+	}
+	return base
+}
+
+func mergeNamedList(base []interface{}, overlay []interface{}) []interface{} {
+	for _, overlayEntry := range overlay {
+		overlayMap := overlayEntry.(map[string]interface{})
+		var isMerged = false
+		for i, baseEntry := range base {
+			baseMap := baseEntry.(map[string]interface{})
+			if entryName(baseMap) == entryName(overlayMap) {
+				base[i] = mergeOverlay(baseMap, overlayMap)
+				isMerged = true
+			}
+		}
+		if !isMerged {
+			base = append(base, overlayMap)
+		}
+	}
+	return base
+}
+
+func isNamedList(entries []interface{}) bool {
+	for _, entry := range entries {
+		entryMap, ok := entry.(map[string]interface{})
+		if !ok || entryName(entryMap) == "" {
+			return false
+		}
+	}
+	return len(entries) > 0
+}
+
+// entryName provides the name of a list entry, e.g. the metadata name of a datacenter or the name of a rack.
+func entryName(entry map[string]interface{}) string {
+	if metadata, ok := entry["metadata"].(map[string]interface{}); ok {
+		if name, ok := metadata["name"].(string); ok {
+			return name
+		}
+	}
+	if name, ok := entry["name"].(string); ok {
+		return name
+	}
+	return ""
+}
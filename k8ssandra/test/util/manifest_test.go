@@ -0,0 +1,185 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"github.com/goccy/go-yaml"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func manifestConfig() model.ReadinessConfig {
+	contexts := validContexts()
+	central := contexts["central"]
+	central.CloudConfig.Project = "community-ecosystem"
+	central.CloudConfig.PoolRackConfigs = []model.PoolRackConfig{
+		{Name: "rack1", Label: "k8ssandra.io/rack=rack1", Location: "us-central1-a"},
+		{Name: "rack2", Location: "us-central1-b"},
+	}
+	contexts["central"] = central
+
+	config := model.ReadinessConfig{Contexts: contexts}
+	config.ProvisionConfig.K8cConfig = model.K8cConfig{ClusterName: "bootz-k8c-cluster",
+		MedusaSecretName: "dev-k8ssandra-medusa-key"}
+	return config
+}
+
+func TestGenerateK8ssandraCluster(t *testing.T) {
+	cluster, err := GenerateK8ssandraCluster(manifestConfig())
+	require.NoError(t, err)
+
+	require.Equal(t, "bootz-k8c-cluster", cluster.Metadata.Name)
+	require.Equal(t, defaultCassandraVersion, cluster.Spec.Cassandra.ServerVersion)
+	require.Len(t, cluster.Spec.Cassandra.Datacenters, 2)
+
+	central := cluster.Spec.Cassandra.Datacenters[0]
+	require.Equal(t, "central", central.Metadata.Name)
+	require.Equal(t, "gke_community-ecosystem_us-central1_dev-central", central.K8sContext)
+	require.Equal(t, 2, central.Size)
+	require.Equal(t, []model.CassandraRack{
+		{Name: "rack1", NodeAffinityLabels: map[string]string{"k8ssandra.io/rack": "rack1"}},
+		{Name: "rack2", NodeAffinityLabels: map[string]string{defaultZoneLabel: "us-central1-b"}},
+	}, central.Racks)
+
+	kind := cluster.Spec.Cassandra.Datacenters[1]
+	require.Equal(t, "kind-k8ssandra-0", kind.K8sContext)
+	require.Equal(t, 1, kind.Size)
+	require.Empty(t, kind.Racks)
+
+	require.NotNil(t, cluster.Spec.Medusa)
+	properties := cluster.Spec.Medusa.StorageProperties
+	require.Equal(t, "dev-k8ssandra-medusa-key", properties.StorageSecretRef.Name)
+	require.Equal(t, "google_storage", properties.StorageProvider)
+	require.Equal(t, "dev-central-storage-bucket", properties.BucketName)
+}
+
+func TestGenerateK8ssandraClusterAzureZones(t *testing.T) {
+	config := manifestConfig()
+	central := config.Contexts["central"]
+	central.CloudConfig = model.CloudConfig{Type: "azure", Region: "eastus", Environment: "dev",
+		PoolRackConfigs: []model.PoolRackConfig{
+			{Name: "rack1", Label: "k8ssandra.io/rack=rack1", Location: "1"},
+			{Name: "rack2", Location: "2"},
+		}}
+	config.Contexts["central"] = central
+
+	cluster, err := GenerateK8ssandraCluster(config)
+	require.NoError(t, err)
+	require.Equal(t, []model.CassandraRack{
+		{Name: "rack1", NodeAffinityLabels: map[string]string{"k8ssandra.io/rack": "rack1"}},
+		{Name: "rack2", NodeAffinityLabels: map[string]string{defaultZoneLabel: "eastus-2"}},
+	}, cluster.Spec.Cassandra.Datacenters[0].Racks, "expecting the zone label of an AKS node")
+}
+
+func TestGenerateK8ssandraClusterInvalid(t *testing.T) {
+	config := manifestConfig()
+	config.ProvisionConfig.K8cConfig.ClusterName = ""
+	_, err := GenerateK8ssandraCluster(config)
+	require.Error(t, err)
+
+	config = manifestConfig()
+	central := config.Contexts["central"]
+	central.CloudConfig.PoolRackConfigs = []model.PoolRackConfig{{Name: "rack1", Label: "rack1"}}
+	config.Contexts["central"] = central
+	_, err = GenerateK8ssandraCluster(config)
+	require.Error(t, err)
+}
+
+func TestRenderK8ssandraClusterOverlay(t *testing.T) {
+	overlayPath := path.Join(t.TempDir(), "overlay.yaml")
+	require.NoError(t, os.WriteFile(overlayPath, []byte(`
+spec:
+  auth: false
+  cassandra:
+    serverVersion: "4.0.6"
+    storageConfig:
+      cassandraDataVolumeClaimSpec:
+        storageClassName: standard
+    datacenters:
+      - metadata:
+          name: central
+        size: 3
+        racks:
+          - name: rack2
+            nodeAffinityLabels:
+              topology.kubernetes.io/zone: us-central1-c
+`), defaultTempFilePerm))
+
+	config := manifestConfig()
+	config.ProvisionConfig.K8cConfig.MedusaSecretName = ""
+	cluster, err := GenerateK8ssandraCluster(config)
+	require.NoError(t, err)
+
+	content, err := RenderK8ssandraCluster(cluster, overlayPath)
+	require.NoError(t, err)
+
+	var rendered map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(content, &rendered))
+	spec := rendered["spec"].(map[string]interface{})
+	require.Equal(t, false, spec["auth"])
+	require.NotContains(t, spec, "medusa")
+
+	cassandra := spec["cassandra"].(map[string]interface{})
+	require.Equal(t, "4.0.6", cassandra["serverVersion"])
+	require.Contains(t, cassandra, "storageConfig")
+
+	datacenters := cassandra["datacenters"].([]interface{})
+	require.Len(t, datacenters, 2, "expecting the overlay datacenter merged by name")
+	central := datacenters[0].(map[string]interface{})
+	require.Equal(t, "gke_community-ecosystem_us-central1_dev-central", central["k8sContext"])
+	require.EqualValues(t, 3, central["size"])
+
+	racks := central["racks"].([]interface{})
+	require.Len(t, racks, 2)
+	require.Equal(t, map[string]interface{}{"k8ssandra.io/rack": "rack1"},
+		racks[0].(map[string]interface{})["nodeAffinityLabels"])
+	require.Equal(t, map[string]interface{}{defaultZoneLabel: "us-central1-c"},
+		racks[1].(map[string]interface{})["nodeAffinityLabels"])
+
+	_, err = RenderK8ssandraCluster(cluster, path.Join(t.TempDir(), "missing.yaml"))
+	require.Error(t, err)
+}
+
+func TestWriteK8ssandraCluster(t *testing.T) {
+	meta := model.ProvisionMeta{ArtifactsRootDir: t.TempDir()}
+
+	manifestPath, err := WriteK8ssandraCluster(meta, manifestConfig())
+	require.NoError(t, err)
+	require.Equal(t, path.Join(meta.ArtifactsRootDir, defaultManifestFileName), manifestPath)
+
+	content, err := ioutil.ReadFile(manifestPath)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "kind: K8ssandraCluster")
+
+	_, err = WriteK8ssandraCluster(model.ProvisionMeta{}, manifestConfig())
+	require.Error(t, err)
+}
+
+func TestOverlayPath(t *testing.T) {
+	config := manifestConfig()
+	require.Empty(t, OverlayPath(config))
+
+	config.ProvisionConfig.K8cConfig.OverlayFilePath = "k8c-multi-dc-overlay.yaml"
+	require.Equal(t, "../config/k8c-multi-dc-overlay.yaml", OverlayPath(config))
+
+	config.ProvisionConfig.K8cConfig.OverlayFilePath = "/etc/k8c/overlay.yaml"
+	require.Equal(t, "/etc/k8c/overlay.yaml", OverlayPath(config))
+}
@@ -0,0 +1,297 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMatrixFileName      = "compatibility-matrix.json"
+	defaultMatrixTableFileName = "compatibility-matrix.md"
+	defaultMatrixVersion       = "default"
+)
+
+// matrixCellPhases are applied to every cell, on the infrastructure provisioned for its Kubernetes version.
+var matrixCellPhases = []model.Phase{model.PhasePreInstall, model.PhaseInstall, model.PhaseValidate}
+
+// ApplyMatrix validates the readiness configuration and runs its compatibility matrix, writing the
+// compatibility table to the artifacts root of the matrix.
+func ApplyMatrix(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) []model.MatrixResult {
+
+	logger.Log(t, fmt.Sprintf("SIMULATE mode: %t", meta.Enable.Simulate))
+	readinessConfig = PlanNetworks(t, readinessConfig)
+	RequireValid(t, readinessConfig)
+
+	t, finishCassette := UseCassette(t, meta)
+	defer finishCassette()
+
+	if meta.ProvisionId == "" {
+		meta.ProvisionId = strings.ToLower(random.UniqueId())
+	}
+	if meta.ArtifactsRootDir == "" {
+		meta.ArtifactsRootDir = DefaultArtifactsRootDir(meta.ProvisionId)
+	}
+
+	results := RunMatrix(t, meta, readinessConfig)
+	logger.Log(t, "\n"+FormatCompatibilityTable(results))
+
+	tablePath, err := WriteCompatibilityMatrix(meta, results)
+	if err != nil {
+		logger.Log(t, fmt.Sprintf("WARNING: unable to write the compatibility matrix: %s", err.Error()))
+	} else {
+		logger.Log(t, fmt.Sprintf("compatibility matrix written to: %s", tablePath))
+	}
+	return results
+}
+
+// RunMatrix runs every cell of the matrix as a subtest. The infrastructure is provisioned once per Kubernetes
+// version, shared by the cells of that version, and cleaned up before the next version is provisioned. The
+// installation of a cell is removed before the next cell of the version, so that every cell is a clean install.
+func RunMatrix(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) []model.MatrixResult {
+
+	var results []model.MatrixResult
+	for index, cells := range groupMatrixCells(ExpandMatrix(readinessConfig.Matrix)) {
+		groupMeta := meta
+		groupMeta.ProvisionId = fmt.Sprintf("%s-%d", meta.ProvisionId, index)
+		groupMeta.ArtifactsRootDir = DefaultArtifactsRootDir(groupMeta.ProvisionId)
+		groupConfig := ApplyMatrixCell(readinessConfig, cells[0])
+		kubernetesVersion := matrixVersion(cells[0].KubernetesVersion)
+
+		isProvisioned := true
+		if IsIdentityRequired(groupConfig) {
+			isProvisioned = runSubtest(t, "provision kubernetes "+kubernetesVersion, func(t testing.TestingT) {
+				runMatrixPhases(t, groupMeta, groupConfig, []model.Phase{model.PhaseProvision})
+			})
+		}
+
+		for position, cell := range cells {
+			if !isProvisioned {
+				results = append(results, model.MatrixResult{Cell: cell, ProvisionId: groupMeta.ProvisionId,
+					Status: model.MatrixSkipped, FailedPhase: model.PhaseProvision,
+					Error: fmt.Sprintf("provisioning of kubernetes version: %s failed", kubernetesVersion)})
+				continue
+			}
+			if position > 0 && !runSubtest(t, matrixCellName(cell)+" uninstall prior", func(t testing.TestingT) {
+				require.True(t, UninstallK8ssandra(t, groupMeta, groupConfig),
+					"expecting the installation of the prior cell to be removed")
+			}) {
+				results = append(results, model.MatrixResult{Cell: cell, ProvisionId: groupMeta.ProvisionId,
+					Status: model.MatrixSkipped, FailedPhase: model.PhaseInstall,
+					Error: "uninstall of the installation of the prior cell failed"})
+				continue
+			}
+			results = append(results, runMatrixCell(t, groupMeta, ApplyMatrixCell(readinessConfig, cell), cell))
+		}
+
+		if IsIdentityRequired(groupConfig) {
+			runSubtest(t, "cleanup kubernetes "+kubernetesVersion, func(t testing.TestingT) {
+				runMatrixPhases(t, groupMeta, groupConfig, []model.Phase{model.PhaseCleanup})
+			})
+		}
+	}
+	return results
+}
+
+// runMatrixCell installs and validates a cell, collecting the diagnostics of a failing cell.
+func runMatrixCell(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig,
+	cell model.MatrixCell) model.MatrixResult {
+
+	result := model.MatrixResult{Cell: cell, ProvisionId: meta.ProvisionId, Status: model.MatrixPassed}
+	priorErrors := len(loadMatrixLedger(meta).Errors)
+	started := time.Now()
+
+	isPassed := runSubtest(t, matrixCellName(cell), func(t testing.TestingT) {
+		runMatrixPhases(t, meta, readinessConfig, matrixCellPhases)
+	})
+	result.Duration = time.Since(started).Round(time.Second).String()
+	if isPassed {
+		return result
+	}
+
+	result.Status = model.MatrixFailed
+	if ledgerErrors := loadMatrixLedger(meta).Errors; len(ledgerErrors) > priorErrors {
+		result.FailedPhase = ledgerErrors[priorErrors].Phase
+		result.Error = ledgerErrors[priorErrors].Message
+	}
+
+	runSubtest(t, matrixCellName(cell)+" diagnose", func(t testing.TestingT) {
+		runMatrixPhases(t, meta, readinessConfig, []model.Phase{model.PhaseDiagnose})
+	})
+	return result
+}
+
+func runMatrixPhases(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig,
+	phases []model.Phase) {
+	logger.Log(t, fmt.Sprintf("applying phases: %v for provision identifier: %s", phases, meta.ProvisionId))
+	RunPipeline(t, meta, readinessConfig, phases)
+}
+
+func loadMatrixLedger(meta model.ProvisionMeta) model.RunLedger {
+	ledger, _ := LoadLedger(meta)
+	return ledger
+}
+
+// ExpandMatrix provides every combination of the matrix versions, ordered by Kubernetes version so that
+// the cells sharing the provisioned infrastructure are adjacent.
+func ExpandMatrix(matrix model.MatrixConfig) []model.MatrixCell {
+	var cells []model.MatrixCell
+	for _, kubernetesVersion := range versionsOrDefault(matrix.KubernetesVersions) {
+		for _, operatorVersion := range versionsOrDefault(matrix.OperatorVersions) {
+			for _, certManagerVersion := range versionsOrDefault(matrix.CertManagerVersions) {
+				cells = append(cells, model.MatrixCell{KubernetesVersion: kubernetesVersion,
+					OperatorVersion: operatorVersion, CertManagerVersion: certManagerVersion})
+			}
+		}
+	}
+	return cells
+}
+
+// ApplyMatrixCell provides the readiness configuration with the versions of the cell, the Kubernetes
+// version applying to the provisioned contexts only.
+func ApplyMatrixCell(readinessConfig model.ReadinessConfig, cell model.MatrixCell) model.ReadinessConfig {
+
+	versions := &readinessConfig.ProvisionConfig.Versions
+	if cell.OperatorVersion != "" {
+		versions.K8ssandraOperator.Version = cell.OperatorVersion
+	}
+	if cell.CertManagerVersion != "" {
+		versions.CertManager.Version = cell.CertManagerVersion
+		versions.CertManager.Manifest = ""
+	}
+
+	var contexts = map[string]model.ContextConfig{}
+	for name, ctx := range readinessConfig.Contexts {
+		if cell.KubernetesVersion != "" && !IsExistingCluster(ctx) {
+			ctx.CloudConfig.KubernetesVersion = cell.KubernetesVersion
+		}
+		contexts[name] = ctx
+	}
+	readinessConfig.Contexts = contexts
+	return readinessConfig
+}
+
+// FormatCompatibilityTable renders the results as a markdown table.
+func FormatCompatibilityTable(results []model.MatrixResult) string {
+	var builder strings.Builder
+	builder.WriteString("| Kubernetes | k8ssandra-operator | cert-manager | Result | Failed phase | Duration | Provision id |\n")
+	builder.WriteString("|---|---|---|---|---|---|---|\n")
+	for _, result := range results {
+		builder.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s | %s |\n",
+			matrixVersion(result.Cell.KubernetesVersion), matrixVersion(result.Cell.OperatorVersion),
+			matrixVersion(result.Cell.CertManagerVersion), result.Status, result.FailedPhase, result.Duration,
+			result.ProvisionId))
+	}
+	return builder.String()
+}
+
+// WriteCompatibilityMatrix writes the results as JSON and as a markdown table to the artifacts root, providing
+// the path of the table.
+func WriteCompatibilityMatrix(meta model.ProvisionMeta, results []model.MatrixResult) (string, error) {
+	if meta.ArtifactsRootDir == "" {
+		return "", errors.New("an artifacts root directory is required to write the compatibility matrix")
+	}
+	if err := os.MkdirAll(meta.ArtifactsRootDir, defaultTempFilePerm); err != nil {
+		return "", err
+	}
+
+	content, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(path.Join(meta.ArtifactsRootDir, defaultMatrixFileName), content,
+		defaultTempFilePerm); err != nil {
+		return "", err
+	}
+
+	tablePath := path.Join(meta.ArtifactsRootDir, defaultMatrixTableFileName)
+	return tablePath, ioutil.WriteFile(tablePath, []byte(FormatCompatibilityTable(results)), defaultTempFilePerm)
+}
+
+// validateMatrix expects distinct versions, and a provisioned context for the Kubernetes versions to apply to.
+func validateMatrix(readinessConfig model.ReadinessConfig) []ValidationError {
+
+	var validationErrors []ValidationError
+	matrix := readinessConfig.Matrix
+	for _, field := range []struct {
+		name     string
+		versions []string
+	}{
+		{"matrix.kubernetes_versions", matrix.KubernetesVersions},
+		{"matrix.operator_versions", matrix.OperatorVersions},
+		{"matrix.cert_manager_versions", matrix.CertManagerVersions},
+	} {
+		var seen = map[string]bool{}
+		for _, version := range field.versions {
+			if version == "" || seen[version] {
+				validationErrors = append(validationErrors, ValidationError{Field: field.name,
+					Message: fmt.Sprintf("expecting distinct non-empty versions, found: [%s]",
+						strings.Join(field.versions, ", "))})
+				break
+			}
+			seen[version] = true
+		}
+	}
+
+	if len(matrix.KubernetesVersions) > 0 && !IsIdentityRequired(readinessConfig) {
+		validationErrors = append(validationErrors, ValidationError{Field: "matrix.kubernetes_versions",
+			Message: "kubernetes versions only apply to provisioned contexts, every context is an existing cluster"})
+	}
+	return validationErrors
+}
+
+// groupMatrixCells groups the adjacent cells of the same Kubernetes version.
+func groupMatrixCells(cells []model.MatrixCell) [][]model.MatrixCell {
+	var groups [][]model.MatrixCell
+	for _, cell := range cells {
+		last := len(groups) - 1
+		if last >= 0 && groups[last][0].KubernetesVersion == cell.KubernetesVersion {
+			groups[last] = append(groups[last], cell)
+			continue
+		}
+		groups = append(groups, []model.MatrixCell{cell})
+	}
+	return groups
+}
+
+func matrixCellName(cell model.MatrixCell) string {
+	return fmt.Sprintf("kubernetes %s operator %s cert-manager %s", matrixVersion(cell.KubernetesVersion),
+		matrixVersion(cell.OperatorVersion), matrixVersion(cell.CertManagerVersion))
+}
+
+func matrixVersion(version string) string {
+	return stringOrDefault(version, defaultMatrixVersion)
+}
+
+func versionsOrDefault(versions []string) []string {
+	if len(versions) == 0 {
+		return []string{""}
+	}
+	return versions
+}
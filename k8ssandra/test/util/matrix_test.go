@@ -0,0 +1,208 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/random"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// stubPhases replaces the activity of the phases with a recording of the phase, provision identifier and
+// versions it is applied with.
+func stubPhases(t *testing.T, phases ...model.Phase) *[]string {
+	var applied []string
+	for _, phase := range phases {
+		phase := phase
+		definition := phaseDefinitions[phase]
+		t.Cleanup(func() { phaseDefinitions[phase] = definition })
+
+		phaseDefinitions[phase] = phaseDefinition{prerequisites: definition.prerequisites,
+			run: func(t terratesting.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) model.ProvisionMeta {
+				applied = append(applied, fmt.Sprintf("%s %s k8s=%s operator=%s", phase, meta.ProvisionId,
+					readinessConfig.Contexts["central"].CloudConfig.KubernetesVersion,
+					readinessConfig.ProvisionConfig.Versions.K8ssandraOperator.Version))
+				return meta
+			}}
+	}
+	return &applied
+}
+
+func TestExpandMatrix(t *testing.T) {
+	require.Equal(t, []model.MatrixCell{{}}, ExpandMatrix(model.MatrixConfig{}))
+
+	cells := ExpandMatrix(model.MatrixConfig{
+		KubernetesVersions:  []string{"1.22", "1.23"},
+		OperatorVersions:    []string{"0.38.0", "1.0.0"},
+		CertManagerVersions: []string{"v1.7.1"},
+	})
+	require.Len(t, cells, 4)
+	require.Equal(t, model.MatrixCell{KubernetesVersion: "1.22", OperatorVersion: "1.0.0",
+		CertManagerVersion: "v1.7.1"}, cells[1])
+
+	groups := groupMatrixCells(cells)
+	require.Len(t, groups, 2)
+	require.Equal(t, "1.23", groups[1][0].KubernetesVersion)
+	require.Len(t, groups[1], 2)
+}
+
+func TestApplyMatrixCell(t *testing.T) {
+	config := model.ReadinessConfig{Contexts: validContexts()}
+	config.ProvisionConfig.Versions.CertManager.Manifest = "https://example.com/cert-manager.yaml"
+
+	cellConfig := ApplyMatrixCell(config, model.MatrixCell{KubernetesVersion: "1.23", OperatorVersion: "1.0.0",
+		CertManagerVersion: "v1.7.1"})
+
+	require.Equal(t, "1.23", cellConfig.Contexts["central"].CloudConfig.KubernetesVersion)
+	require.Empty(t, cellConfig.Contexts["kind"].CloudConfig.KubernetesVersion, "expecting existing clusters unchanged")
+	require.Empty(t, config.Contexts["central"].CloudConfig.KubernetesVersion, "expecting the scenario unchanged")
+
+	versions := ResolveVersions(cellConfig.ProvisionConfig)
+	require.Equal(t, "1.0.0", versions.K8ssandraOperator.Version)
+	require.Equal(t, "https://github.com/jetstack/cert-manager/releases/download/v1.7.1/cert-manager.yaml",
+		versions.CertManager.Manifest)
+
+	unchanged := ApplyMatrixCell(config, model.MatrixCell{})
+	require.Equal(t, "https://example.com/cert-manager.yaml", unchanged.ProvisionConfig.Versions.CertManager.Manifest)
+}
+
+func TestValidateMatrix(t *testing.T) {
+	config := model.ReadinessConfig{Contexts: validContexts()}
+	config.Matrix = model.MatrixConfig{KubernetesVersions: []string{"1.22", "1.23"},
+		OperatorVersions: []string{"1.0.0", "1.0.0"}}
+
+	validationErrors := validateMatrix(config)
+	require.Len(t, validationErrors, 1)
+	require.Equal(t, "matrix.operator_versions", validationErrors[0].Field)
+
+	delete(config.Contexts, "central")
+	config.Matrix.OperatorVersions = nil
+	validationErrors = validateMatrix(config)
+	require.Len(t, validationErrors, 1)
+	require.Equal(t, "matrix.kubernetes_versions", validationErrors[0].Field)
+}
+
+func TestRunMatrix(t *testing.T) {
+	applied := stubPhases(t, model.PhaseProvision, model.PhasePreInstall, model.PhaseInstall,
+		model.PhaseValidate, model.PhaseCleanup)
+
+	matrixId := "k8c-matrix-" + strings.ToLower(random.UniqueId())
+	for _, groupId := range []string{matrixId + "-0", matrixId + "-1"} {
+		artifactsRootDir := DefaultArtifactsRootDir(groupId)
+		t.Cleanup(func() { _ = os.RemoveAll(artifactsRootDir) })
+	}
+
+	t.Setenv(DefaultAdminIdentifier, "admin")
+	fake := executor.NewFake().
+		Respond(executor.Helm, []string{"list"}, deployedOperator, nil).
+		Respond(executor.Kubectl, []string{"get", "crd"},
+			"customresourcedefinition.apiextensions.k8s.io/k8ssandraclusters.k8ssandra.io\n"+
+				"customresourcedefinition.apiextensions.k8s.io/certificates.cert-manager.io", nil)
+
+	meta := model.ProvisionMeta{ProvisionId: matrixId, ArtifactsRootDir: t.TempDir(),
+		AdminIdentity: DefaultAdminIdentifier, DefaultConfigPath: path.Join(t.TempDir(), "config")}
+	config := model.ReadinessConfig{Contexts: validContexts(), Matrix: model.MatrixConfig{
+		KubernetesVersions: []string{"1.22", "1.23"},
+		OperatorVersions:   []string{"0.38.0", "1.0.0"},
+	}}
+	config.ProvisionConfig.K8cConfig.ClusterName = "bootz-k8c-cluster"
+
+	results := RunMatrix(executor.With(t, fake), meta, config)
+
+	require.Len(t, results, 4)
+	for _, result := range results {
+		require.Equal(t, model.MatrixPassed, result.Status)
+	}
+	require.Equal(t, matrixId+"-1", results[3].ProvisionId)
+
+	group := matrixId + "-0"
+	require.Equal(t, []string{
+		"provision " + group + " k8s=1.22 operator=0.38.0",
+		"pre-install " + group + " k8s=1.22 operator=0.38.0",
+		"install " + group + " k8s=1.22 operator=0.38.0",
+		"validate " + group + " k8s=1.22 operator=0.38.0",
+		"pre-install " + group + " k8s=1.22 operator=1.0.0",
+		"install " + group + " k8s=1.22 operator=1.0.0",
+		"validate " + group + " k8s=1.22 operator=1.0.0",
+		"cleanup " + group + " k8s=1.22 operator=0.38.0",
+	}, (*applied)[:8], "expecting the infrastructure provisioned once per kubernetes version")
+	require.Len(t, *applied, 16)
+
+	// The second cell of each kubernetes version is installed once the first cell is uninstalled.
+	deleted := fake.CommandsOf(executor.Kubectl, "delete", "k8ssandracluster", "bootz-k8c-cluster")
+	require.Len(t, deleted, 2)
+	require.Equal(t, "bootz", deleted[0].Namespace)
+	require.Len(t, fake.CommandsOf(executor.Helm, "uninstall", defaultK8ssandraOperatorReleaseName), 4,
+		"expecting the operator uninstalled on every context")
+	crds := fake.CommandsOf(executor.Kubectl, "delete", "--ignore-not-found")
+	require.Len(t, crds, 4)
+	require.Equal(t, []string{"delete", "--ignore-not-found",
+		"customresourcedefinition.apiextensions.k8s.io/k8ssandraclusters.k8ssandra.io"}, crds[0].Args)
+
+	tablePath, err := WriteCompatibilityMatrix(meta, results)
+	require.NoError(t, err)
+	require.FileExists(t, path.Join(meta.ArtifactsRootDir, defaultMatrixFileName))
+	table, err := os.ReadFile(tablePath)
+	require.NoError(t, err)
+	require.Contains(t, string(table), "| 1.23 | 1.0.0 | default | passed |")
+}
+
+func TestRunMatrixCleanup(t *testing.T) {
+	stubPhases(t, model.PhasePreInstall, model.PhaseInstall, model.PhaseValidate)
+	t.Setenv(DefaultAdminIdentifier, "admin")
+	fake := executor.NewFake()
+
+	matrixId := "k8c-matrix-" + strings.ToLower(random.UniqueId())
+	artifactsRootDir := DefaultArtifactsRootDir(matrixId + "-0")
+	t.Cleanup(func() { _ = os.RemoveAll(artifactsRootDir) })
+
+	meta := model.ProvisionMeta{ProvisionId: matrixId, AdminIdentity: DefaultAdminIdentifier,
+		DefaultConfigPath: path.Join(t.TempDir(), "config")}
+	config := model.ReadinessConfig{Contexts: validContexts(), Matrix: model.MatrixConfig{
+		KubernetesVersions: []string{"1.22"}}}
+	config.ProvisionConfig.TFConfig.ModuleFolder = "./provision/gcp"
+
+	results := RunMatrix(executor.With(t, fake), meta, config)
+	require.Len(t, results, 1)
+	require.Equal(t, model.MatrixPassed, results[0].Status)
+	require.NotEmpty(t, fake.CommandsOf(executor.Terraform, "destroy"), "expecting the cloud resources cleaned up")
+
+	applied := fake.CommandsOf(executor.Terraform, "apply")
+	require.Len(t, applied, 1)
+	modulesFolder := path.Dir(applied[0].WorkingDir)
+	require.True(t, strings.HasPrefix(modulesFolder, path.Join(os.TempDir(), prefixModulesFolderName)), modulesFolder)
+	require.NoDirExists(t, modulesFolder, "expecting the copied modules removed by the matrix cleanup")
+	require.NoDirExists(t, artifactsRootDir, "expecting the artifacts of the matrix group removed")
+}
+
+func TestFormatCompatibilityTable(t *testing.T) {
+	table := FormatCompatibilityTable([]model.MatrixResult{
+		{Cell: model.MatrixCell{OperatorVersion: "1.0.0"}, ProvisionId: "k8c-0", Status: model.MatrixFailed,
+			FailedPhase: model.PhaseValidate, Duration: "12m0s"},
+	})
+
+	lines := strings.Split(strings.TrimSpace(table), "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, "| default | 1.0.0 | default | failed | validate | 12m0s | k8c-0 |", lines[2])
+}
@@ -0,0 +1,318 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/goccy/go-yaml"
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	CheckMedusaBackupRestore = "medusa-backup-restore"
+
+	defaultMedusaApiVersion     = "medusa.k8ssandra.io/v1alpha1"
+	defaultMedusaSecretKey      = "credentials"
+	defaultMedusaFolder         = "medusa"
+	defaultMedusaTable          = "backup"
+	defaultMinIOReleaseName     = "minio"
+	defaultMinIORepositoryName  = "bitnami"
+	defaultMinIORepositoryURL   = "https://charts.bitnami.com/bitnami"
+	defaultMinIOChart           = "bitnami/minio"
+	defaultMinIOVersion         = "11.10.3"
+	defaultMinIOPort            = 9000
+	defaultMinIOStorageProvider = "s3_compatible"
+	defaultStandInBucketName    = "k8ssandra-medusa"
+	defaultStandInAccessKey     = "k8ssandra-medusa"
+	defaultStandInSecretKey     = "k8ssandra-medusa-key"
+)
+
+// ResolveMedusaStandIn completes the MinIO stand-in of the configuration with the defaults.
+func ResolveMedusaStandIn(k8cConfig model.K8cConfig) model.MedusaStandInConfig {
+	var standIn model.MedusaStandInConfig
+	if k8cConfig.MedusaStandIn != nil {
+		standIn = *k8cConfig.MedusaStandIn
+	}
+	standIn.BucketName = stringOrDefault(standIn.BucketName, defaultStandInBucketName)
+	standIn.AccessKey = stringOrDefault(standIn.AccessKey, defaultStandInAccessKey)
+	standIn.SecretKey = stringOrDefault(standIn.SecretKey, defaultStandInSecretKey)
+	return standIn
+}
+
+// standInStorage provides the Medusa storage of the MinIO stand-in, reached through the service of the namespace
+// of every datacenter.
+func standInStorage(k8cConfig model.K8cConfig) model.MedusaStorageProperties {
+	isSecure := false
+	return model.MedusaStorageProperties{
+		StorageProvider:  defaultMinIOStorageProvider,
+		StorageSecretRef: corev1.LocalObjectReference{Name: k8cConfig.MedusaSecretName},
+		BucketName:       ResolveMedusaStandIn(k8cConfig).BucketName,
+		Host:             defaultMinIOReleaseName,
+		Port:             defaultMinIOPort,
+		Secure:           &isSecure,
+	}
+}
+
+// minioRelease provides the release of the MinIO stand-in, creating its bucket, the values of the component
+// taking precedence.
+func minioRelease(component model.ComponentVersion, standIn model.MedusaStandInConfig, namespace string) helmRelease {
+	release := componentRelease(defaultMinIOReleaseName, namespace, component)
+	release.Values = map[string]string{
+		"auth.rootUser":       standIn.AccessKey,
+		"auth.rootPassword":   standIn.SecretKey,
+		"defaultBuckets":      standIn.BucketName,
+		"persistence.enabled": "false",
+	}
+	for key, value := range component.Values {
+		release.Values[key] = value
+	}
+	return release
+}
+
+func installMedusaStandIn(t testing.TestingT, helmOptions *helm.Options, component model.ComponentVersion,
+	k8cConfig model.K8cConfig, namespace string, isSimulate bool) {
+
+	if isSimulate {
+		logger.Log(t, fmt.Sprintf("SIMULATE install of the MinIO stand-in for Medusa in namespace: %s", namespace))
+		return
+	}
+
+	action, _, err := applyRelease(t, helmOptions, minioRelease(component, ResolveMedusaStandIn(k8cConfig), namespace))
+	require.NoError(t, err, "expecting that the MinIO stand-in can be installed")
+	logger.Log(t, fmt.Sprintf("MinIO stand-in release action: %s", action))
+}
+
+// medusaSecretData provides the storage credentials of Medusa, generated for the MinIO stand-in or read from the
+// MedusaSecretFromFile, keyed by the MedusaSecretFromFileKey.
+func medusaSecretData(k8cConfig model.K8cConfig) (map[string][]byte, error) {
+	key := stringOrDefault(k8cConfig.MedusaSecretFromFileKey, defaultMedusaSecretKey)
+
+	if k8cConfig.MedusaStandIn != nil {
+		standIn := ResolveMedusaStandIn(k8cConfig)
+		credentials := fmt.Sprintf("[default]\naws_access_key_id = %s\naws_secret_access_key = %s\n",
+			standIn.AccessKey, standIn.SecretKey)
+		return map[string][]byte{key: []byte(credentials)}, nil
+	}
+
+	if k8cConfig.MedusaSecretFromFile == "" {
+		return nil, errors.New("a medusa secret file is required unless the MinIO stand-in is used")
+	}
+	content, err := ioutil.ReadFile(configFilePath(k8cConfig.MedusaSecretFromFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the medusa secret file: %s, %w", k8cConfig.MedusaSecretFromFile, err)
+	}
+	return map[string][]byte{key: content}, nil
+}
+
+// CreateMedusaSecret creates or replaces the Medusa storage secret in the namespace.
+func CreateMedusaSecret(t testing.TestingT, kubeConfig *k8s.KubectlOptions, namespace string, k8cConfig model.K8cConfig) {
+	data, err := medusaSecretData(k8cConfig)
+	require.NoError(t, err, fmt.Sprintf("expecting the content of medusa secret: %s", k8cConfig.MedusaSecretName))
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: k8cConfig.MedusaSecretName, Namespace: namespace},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       data,
+	}
+
+	secrets := KubeClient(t, kubeConfig).CoreV1().Secrets(namespace)
+	_, err = secrets.Create(context.Background(), secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(context.Background(), secret, metav1.UpdateOptions{})
+	}
+	require.NoError(t, err, fmt.Sprintf("expecting medusa secret: %s to be created", k8cConfig.MedusaSecretName))
+	logger.Log(t, fmt.Sprintf("medusa secret: %s created in namespace: %s", k8cConfig.MedusaSecretName, namespace))
+}
+
+// createMedusaSecrets creates the Medusa storage secret in the namespace of every context, when Medusa is configured.
+func createMedusaSecrets(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig,
+	ctxOptions map[string]model.ContextOption) {
+
+	k8cConfig := readinessConfig.ProvisionConfig.K8cConfig
+	if k8cConfig.MedusaSecretName == "" {
+		return
+	}
+
+	for _, name := range sortedContextNames(readinessConfig) {
+		ctx := readinessConfig.Contexts[name]
+		if meta.Enable.Simulate {
+			logger.Log(t, fmt.Sprintf("SIMULATE creation of medusa secret: %s for: %s", k8cConfig.MedusaSecretName,
+				name))
+			continue
+		}
+		CreateMedusaSecret(t, ctxOptions[name].KubectlOptions, ctx.Namespace, k8cConfig)
+	}
+}
+
+// checkMedusaBackupRestore writes rows, backs up every datacenter with a MedusaBackupJob, truncates the table,
+// restores every datacenter with a MedusaRestoreJob and expects the rows to be read back from every datacenter.
+func checkMedusaBackupRestore(t testing.TestingT, meta model.ProvisionMeta, clusterName string, datacenterNames []string,
+	targets map[string]datacenterTarget, sizes map[string]int, timeoutSecs int) []model.CheckResult {
+
+	writer := datacenterNames[0]
+	token := strings.ToLower(random.UniqueId())
+	failed := func(dcName string, message string) []model.CheckResult {
+		return []model.CheckResult{checkResult(targets[dcName].contextName, CheckMedusaBackupRestore, dcName, false,
+			message)}
+	}
+
+	out, err := runCqlsh(t, clusterName, targets[writer], consistencyWriteStatements(defaultMedusaTable,
+		datacenterNames, sizes, token))
+	if err != nil {
+		return failed(writer, fmt.Sprintf("unable to write the rows to back up, %s %s", err.Error(), out))
+	}
+
+	backups := map[string]string{}
+	for _, dcName := range datacenterNames {
+		backups[dcName] = medusaJobName("backup", token, dcName)
+		backup := model.MedusaBackupJob{ApiVersion: defaultMedusaApiVersion, Kind: "MedusaBackupJob",
+			Metadata: model.ObjectMeta{Name: backups[dcName], Namespace: targets[dcName].namespace},
+			Spec:     model.MedusaBackupJobSpec{CassandraDatacenter: dcName}}
+		if err := applyMedusaJob(t, meta, targets[dcName], "medusabackupjob", backups[dcName], backup,
+			timeoutSecs); err != nil {
+			return failed(dcName, fmt.Sprintf("backup: %s failed, %s", backups[dcName], err.Error()))
+		}
+	}
+
+	out, err = runCqlsh(t, clusterName, targets[writer], fmt.Sprintf("CONSISTENCY ALL; TRUNCATE %s.%s; %s",
+		defaultConsistencyKeyspace, defaultMedusaTable, consistencyReadStatements(defaultMedusaTable)))
+	if err != nil {
+		return failed(writer, fmt.Sprintf("unable to truncate the backed up table, %s %s", err.Error(), out))
+	}
+	if read := countRows(out, token); read != 0 {
+		return failed(writer, fmt.Sprintf("truncate left %d rows", read))
+	}
+
+	for _, dcName := range datacenterNames {
+		name := medusaJobName("restore", token, dcName)
+		restore := model.MedusaRestoreJob{ApiVersion: defaultMedusaApiVersion, Kind: "MedusaRestoreJob",
+			Metadata: model.ObjectMeta{Name: name, Namespace: targets[dcName].namespace},
+			Spec:     model.MedusaRestoreJobSpec{CassandraDatacenter: dcName, Backup: backups[dcName]}}
+		if err := applyMedusaJob(t, meta, targets[dcName], "medusarestorejob", name, restore,
+			timeoutSecs); err != nil {
+			return failed(dcName, fmt.Sprintf("restore of backup: %s failed, %s", backups[dcName], err.Error()))
+		}
+	}
+
+	var results []model.CheckResult
+	for _, dcName := range datacenterNames {
+		target := targets[dcName]
+		ready := waitForCondition(t, target.kubeConfig, target.contextName, CheckDatacenterReady,
+			"cassandradatacenter/"+dcName, target.namespace, defaultDatacenterCondition, timeoutSecs)
+		if !ready.Passed {
+			results = append(results, failed(dcName, "not ready after the restore, "+ready.Message)...)
+			continue
+		}
+
+		out, err := runCqlsh(t, clusterName, target, consistencyReadStatements(defaultMedusaTable))
+		if err != nil {
+			results = append(results, failed(dcName, fmt.Sprintf("unable to read the restored rows, %s %s",
+				err.Error(), out))...)
+			continue
+		}
+		read := countRows(out, token)
+		results = append(results, checkResult(target.contextName, CheckMedusaBackupRestore, dcName,
+			read == defaultConsistencyRows, fmt.Sprintf("read %d of %d rows restored from backup: %s", read,
+				defaultConsistencyRows, backups[dcName])))
+	}
+	return results
+}
+
+func medusaJobName(kind string, token string, dcName string) string {
+	return strings.ToLower(strings.ReplaceAll(fmt.Sprintf("readiness-%s-%s-%s", kind, token, dcName), "_", "-"))
+}
+
+// applyMedusaJob writes the job manifest to the medusa folder of the artifacts root, applies it in the context of
+// the datacenter and waits for the job to finish.
+func applyMedusaJob(t testing.TestingT, meta model.ProvisionMeta, target datacenterTarget, resource string, name string,
+	job interface{}, timeoutSecs int) error {
+
+	content, err := yaml.Marshal(job)
+	if err != nil {
+		return err
+	}
+	medusaDir := path.Join(meta.ArtifactsRootDir, defaultMedusaFolder)
+	if err := os.MkdirAll(medusaDir, defaultTempFilePerm); err != nil {
+		return err
+	}
+	manifestPath := path.Join(medusaDir, name+".yaml")
+	if err := ioutil.WriteFile(manifestPath, content, defaultTempFilePerm); err != nil {
+		return err
+	}
+
+	if _, err := executor.RunKubectl(t, target.kubeConfig, "apply", "-f", manifestPath, "-n",
+		target.namespace); err != nil {
+		return err
+	}
+	return waitForMedusaJob(t, target.kubeConfig, resource, name, target.namespace, timeoutSecs)
+}
+
+// waitForMedusaJob polls the finish time of the job, up to the timeout. A job finished with failed pods is an
+// error naming them.
+func waitForMedusaJob(t testing.TestingT, kubeConfig *k8s.KubectlOptions, resource string, name string, namespace string,
+	timeoutSecs int) error {
+
+	deadline := time.Now().Add(time.Duration(timeoutSecs) * time.Second)
+	for {
+		out, err := executor.RunKubectl(t, kubeConfig, "get", resource, name, "-n", namespace, "-o",
+			"jsonpath={.status.finishTime}|{.status.failed[*]}")
+		finishTime, failedPods, _ := strings.Cut(strings.TrimSpace(out), "|")
+		if err == nil && finishTime != "" {
+			if failedPods = strings.TrimSpace(failedPods); failedPods != "" {
+				return fmt.Errorf("%s: %s finished at: %s with failed pods: %s", resource, name, finishTime,
+					strings.Join(strings.Fields(failedPods), ", "))
+			}
+			logger.Log(t, fmt.Sprintf("%s: %s finished at: %s", resource, name, finishTime))
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for %s: %s to finish after %ds", resource, name, timeoutSecs)
+		}
+		time.Sleep(defaultTimeout)
+	}
+}
+
+// validateMedusa expects the content of the Medusa secret to be known, from a file or the MinIO stand-in.
+func validateMedusa(readinessConfig model.ReadinessConfig) []ValidationError {
+	k8cConfig := readinessConfig.ProvisionConfig.K8cConfig
+	if k8cConfig.MedusaSecretName == "" && k8cConfig.MedusaStandIn != nil {
+		return []ValidationError{{Field: "k8c_config.medusa_secret_name",
+			Message: "a medusa secret name is required by the MinIO stand-in"}}
+	}
+	if k8cConfig.MedusaSecretName != "" && k8cConfig.MedusaStandIn == nil && k8cConfig.MedusaSecretFromFile == "" {
+		return []ValidationError{{Field: "k8c_config.medusa_secret_from_file",
+			Message: fmt.Sprintf("a file is required to create medusa secret: %s", k8cConfig.MedusaSecretName)}}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"context"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestResolveMedusaStandIn(t *testing.T) {
+	require.Equal(t, model.MedusaStandInConfig{BucketName: defaultStandInBucketName, AccessKey: defaultStandInAccessKey,
+		SecretKey: defaultStandInSecretKey}, ResolveMedusaStandIn(model.K8cConfig{}))
+
+	standIn := ResolveMedusaStandIn(model.K8cConfig{MedusaStandIn: &model.MedusaStandInConfig{BucketName: "backups"}})
+	require.Equal(t, "backups", standIn.BucketName)
+	require.Equal(t, defaultStandInAccessKey, standIn.AccessKey)
+}
+
+func TestResolveVersionsMinIO(t *testing.T) {
+	config := model.ProvisionConfig{Versions: model.VersionsConfig{MinIO: model.ComponentVersion{Version: "11.9.0"}}}
+	require.Empty(t, ResolveVersions(config).MinIO, "expecting MinIO only for the stand-in")
+	require.Len(t, helmRepositories(ResolveVersions(config)), 3)
+
+	config.K8cConfig.MedusaStandIn = &model.MedusaStandInConfig{}
+	minio := ResolveVersions(config).MinIO
+	require.Equal(t, model.ComponentVersion{RepositoryName: defaultMinIORepositoryName,
+		RepositoryURL: defaultMinIORepositoryURL, Chart: defaultMinIOChart, Version: "11.9.0"}, minio)
+	require.Equal(t, []string{defaultMinIORepositoryName, defaultMinIORepositoryURL},
+		helmRepositories(ResolveVersions(config))[3])
+}
+
+func TestMinioRelease(t *testing.T) {
+	component := model.ComponentVersion{Chart: defaultMinIOChart, Version: defaultMinIOVersion,
+		Values: map[string]string{"persistence.enabled": "true"}}
+
+	release := minioRelease(component, ResolveMedusaStandIn(model.K8cConfig{}), "bootz")
+	require.Equal(t, defaultMinIOReleaseName, release.Name)
+	require.Equal(t, "bootz", release.Namespace)
+	require.Equal(t, map[string]string{
+		"auth.rootUser":       defaultStandInAccessKey,
+		"auth.rootPassword":   defaultStandInSecretKey,
+		"defaultBuckets":      defaultStandInBucketName,
+		"persistence.enabled": "true",
+	}, release.Values)
+}
+
+func TestMedusaSecretData(t *testing.T) {
+	data, err := medusaSecretData(model.K8cConfig{MedusaStandIn: &model.MedusaStandInConfig{}})
+	require.NoError(t, err)
+	require.Equal(t, "[default]\naws_access_key_id = k8ssandra-medusa\naws_secret_access_key = k8ssandra-medusa-key\n",
+		string(data[defaultMedusaSecretKey]))
+
+	keyFile := path.Join(t.TempDir(), "medusa_gcp_key.json")
+	require.NoError(t, os.WriteFile(keyFile, []byte(`{"type": "service_account"}`), defaultTempFilePerm))
+	data, err = medusaSecretData(model.K8cConfig{MedusaSecretFromFile: keyFile, MedusaSecretFromFileKey: "medusa_gcp_key"})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{"medusa_gcp_key": []byte(`{"type": "service_account"}`)}, data)
+
+	_, err = medusaSecretData(model.K8cConfig{})
+	require.Error(t, err)
+	_, err = medusaSecretData(model.K8cConfig{MedusaSecretFromFile: path.Join(t.TempDir(), "missing.json")})
+	require.Error(t, err)
+}
+
+func TestCreateMedusaSecret(t *testing.T) {
+	client := useFakeClient(t)
+	options := k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz")
+	k8cConfig := model.K8cConfig{MedusaSecretName: "dev-k8ssandra-medusa-key", MedusaStandIn: &model.MedusaStandInConfig{}}
+
+	CreateMedusaSecret(t, options, "bootz", k8cConfig)
+	k8cConfig.MedusaStandIn.AccessKey = "rotated"
+	CreateMedusaSecret(t, options, "bootz", k8cConfig)
+
+	secret, err := client.CoreV1().Secrets("bootz").Get(context.Background(), "dev-k8ssandra-medusa-key",
+		metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Contains(t, string(secret.Data[defaultMedusaSecretKey]), "aws_access_key_id = rotated",
+		"expecting an existing secret to be replaced")
+}
+
+func TestGenerateK8ssandraClusterStandIn(t *testing.T) {
+	config := manifestConfig()
+	config.ProvisionConfig.K8cConfig.MedusaStandIn = &model.MedusaStandInConfig{}
+
+	cluster, err := GenerateK8ssandraCluster(config)
+	require.NoError(t, err)
+	properties := cluster.Spec.Medusa.StorageProperties
+	require.Equal(t, defaultMinIOStorageProvider, properties.StorageProvider)
+	require.Equal(t, "dev-k8ssandra-medusa-key", properties.StorageSecretRef.Name)
+	require.Equal(t, defaultStandInBucketName, properties.BucketName)
+	require.Equal(t, defaultMinIOReleaseName, properties.Host)
+	require.Equal(t, defaultMinIOPort, properties.Port)
+	require.False(t, *properties.Secure)
+}
+
+func TestCheckMedusaBackupRestore(t *testing.T) {
+	fake := executor.NewFake().
+		Respond(executor.Kubectl, []string{"get", "medusabackupjob"}, "2022-10-17T10:00:00Z", nil).
+		Respond(executor.Kubectl, []string{"get", "medusarestorejob"}, "2022-10-17T10:05:00Z", nil).
+		RespondFunc(func(command executor.Command) bool {
+			return strings.Contains(strings.Join(command.Args, " "), "SELECT token")
+		}, "\n token\n--------\n\n(0 rows)", nil)
+	useFakeClient(t, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bootz-k8c-cluster-superuser", Namespace: "bootz"},
+		Data:       map[string][]byte{"username": []byte("bootz-k8c-cluster-superuser"), "password": []byte("s3cr3t")},
+	})
+	meta := model.ProvisionMeta{ArtifactsRootDir: t.TempDir()}
+	targets := map[string]datacenterTarget{
+		"kind": {contextName: "kind", namespace: "bootz", podName: "k8c-kind-default-sts-0",
+			kubeConfig: k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz")},
+	}
+
+	results := checkMedusaBackupRestore(executor.With(t, fake), meta, "bootz-k8c-cluster", []string{"kind"}, targets,
+		map[string]int{"kind": 1}, 60)
+	require.Equal(t, []string{"medusa-backup-restore/kind/failed"}, checkNames(results))
+	require.True(t, strings.HasPrefix(results[0].Message, "read 0 of 10 rows restored from backup: readiness-backup-"))
+
+	applied := fake.CommandsOf(executor.Kubectl, "apply")
+	require.Len(t, applied, 2)
+	backupPath := applied[0].Args[2]
+	require.Equal(t, path.Join(meta.ArtifactsRootDir, defaultMedusaFolder), path.Dir(backupPath))
+
+	content, err := os.ReadFile(backupPath)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "kind: MedusaBackupJob")
+	require.Contains(t, string(content), "cassandraDatacenter: kind")
+
+	content, err = os.ReadFile(applied[1].Args[2])
+	require.NoError(t, err)
+	require.Contains(t, string(content), "backup: "+strings.TrimSuffix(path.Base(backupPath), ".yaml"))
+}
+
+func TestCheckMedusaBackupFailed(t *testing.T) {
+	fake := executor.NewFake().
+		Respond(executor.Kubectl, []string{"get", "medusabackupjob"},
+			"2022-10-17T10:00:00Z|k8c-kind-default-sts-0 k8c-kind-default-sts-1", nil)
+	useFakeClient(t, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bootz-k8c-cluster-superuser", Namespace: "bootz"},
+		Data:       map[string][]byte{"username": []byte("bootz-k8c-cluster-superuser"), "password": []byte("s3cr3t")},
+	})
+	targets := map[string]datacenterTarget{
+		"kind": {contextName: "kind", namespace: "bootz", podName: "k8c-kind-default-sts-0",
+			kubeConfig: k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz")},
+	}
+
+	results := checkMedusaBackupRestore(executor.With(t, fake), model.ProvisionMeta{ArtifactsRootDir: t.TempDir()}, "bootz-k8c-cluster",
+		[]string{"kind"}, targets, map[string]int{"kind": 1}, 60)
+	require.Equal(t, []string{"medusa-backup-restore/kind/failed"}, checkNames(results))
+	require.Contains(t, results[0].Message, "with failed pods: k8c-kind-default-sts-0, k8c-kind-default-sts-1")
+	require.Empty(t, fake.CommandsOf(executor.Kubectl, "get", "medusarestorejob"),
+		"expecting a failed backup not to be restored")
+	require.Len(t, fake.CommandsOf(executor.Kubectl, "apply"), 1)
+}
+
+func TestValidateMedusa(t *testing.T) {
+	config := model.ReadinessConfig{Contexts: validContexts()}
+	config.ProvisionConfig.K8cConfig.MedusaStandIn = &model.MedusaStandInConfig{}
+	require.Equal(t, []string{"/k8c_config.medusa_secret_name"}, fields(Validate(config)))
+
+	config.ProvisionConfig.K8cConfig.MedusaSecretName = "dev-k8ssandra-medusa-key"
+	require.Empty(t, Validate(config))
+
+	config.ProvisionConfig.K8cConfig.MedusaStandIn = nil
+	require.Equal(t, []string{"/k8c_config.medusa_secret_from_file"}, fields(Validate(config)))
+}
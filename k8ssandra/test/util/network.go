@@ -0,0 +1,227 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/cloud"
+	"github.com/stretchr/testify/require"
+	"net"
+)
+
+const (
+	defaultSubnetPrefixLength    = 20
+	defaultSecondaryPrefixLength = 20
+	defaultMasterPrefixLength    = 28
+
+	subnetCidrField    = "network_config.subnetCidrBlock"
+	secondaryCidrField = "network_config.secondary_cidr_block"
+	masterCidrField    = "network_config.master_ipv_4_cidr_block"
+)
+
+// CidrAssignment is a CIDR block assigned to a network config field of a context.
+type CidrAssignment struct {
+	Context string
+	Field   string
+	Block   *net.IPNet
+}
+
+// CidrOverlap identifies two assignments sharing addresses.
+type CidrOverlap struct {
+	First  CidrAssignment
+	Second CidrAssignment
+}
+
+// PlanNetworks fills empty CIDR blocks of every cloud context from the network plan supernet, logging the overlaps
+// of the planned blocks, which the validation of the configuration rejects.
+func PlanNetworks(t testing.TestingT, readinessConfig model.ReadinessConfig) model.ReadinessConfig {
+	planned, assigned, err := AllocateCidrBlocks(readinessConfig)
+	require.NoError(t, err, "expecting CIDR blocks to be allocated from the network plan")
+
+	for _, assignment := range assigned {
+		logger.Log(t, fmt.Sprintf("network plan assigned context: %s %s: %s",
+			assignment.Context, assignment.Field, assignment.Block))
+	}
+
+	for _, overlap := range DetectCidrOverlaps(planned) {
+		logger.Log(t, fmt.Sprintf("CIDR overlap, context: %s %s: %s overlaps context: %s %s: %s",
+			overlap.First.Context, overlap.First.Field, overlap.First.Block,
+			overlap.Second.Context, overlap.Second.Field, overlap.Second.Block))
+	}
+	return planned
+}
+
+// CollectCidrBlocks parses every configured block across all contexts, in context name order.
+func CollectCidrBlocks(readinessConfig model.ReadinessConfig) ([]CidrAssignment, []ValidationError) {
+
+	var assignments []CidrAssignment
+	var validationErrors []ValidationError
+	for _, name := range sortedContextNames(readinessConfig) {
+		networkConfig := readinessConfig.Contexts[name].NetworkConfig
+		for _, field := range networkFields(&networkConfig) {
+			if *field.value == "" {
+				continue
+			}
+			_, block, err := net.ParseCIDR(*field.value)
+			if err != nil {
+				validationErrors = append(validationErrors, ValidationError{Context: name, Field: field.name,
+					Message: fmt.Sprintf("%s is not a valid CIDR block", *field.value)})
+				continue
+			}
+			assignments = append(assignments, CidrAssignment{Context: name, Field: field.name, Block: block})
+		}
+	}
+	return assignments, validationErrors
+}
+
+// DetectCidrOverlaps compares every subnet, secondary and master block with all others, across all contexts.
+func DetectCidrOverlaps(readinessConfig model.ReadinessConfig) []CidrOverlap {
+
+	assignments, _ := CollectCidrBlocks(readinessConfig)
+	var overlaps []CidrOverlap
+	for i := 0; i < len(assignments); i++ {
+		for j := i + 1; j < len(assignments); j++ {
+			if isOverlapping(assignments[i].Block, assignments[j].Block) {
+				overlaps = append(overlaps, CidrOverlap{First: assignments[i], Second: assignments[j]})
+			}
+		}
+	}
+	return overlaps
+}
+
+// AllocateCidrBlocks assigns the first free block of the configured prefix length within the supernet to each
+// empty field of the cloud contexts, skipping the master block of providers managing the control plane network.
+// Blocks already configured are kept and never reused.
+func AllocateCidrBlocks(readinessConfig model.ReadinessConfig) (model.ReadinessConfig, []CidrAssignment, error) {
+
+	plan := readinessConfig.NetworkPlan
+	if plan.Supernet == "" {
+		return readinessConfig, nil, nil
+	}
+
+	_, supernet, err := net.ParseCIDR(plan.Supernet)
+	if err != nil || supernet.IP.To4() == nil {
+		return readinessConfig, nil, fmt.Errorf("network plan supernet: %s is not a valid IPv4 CIDR block", plan.Supernet)
+	}
+
+	existing, validationErrors := CollectCidrBlocks(readinessConfig)
+	if len(validationErrors) > 0 {
+		return readinessConfig, nil, validationErrors[0]
+	}
+
+	var used []*net.IPNet
+	for _, assignment := range existing {
+		used = append(used, assignment.Block)
+	}
+
+	prefixLengths := map[string]int{
+		subnetCidrField:    valueOrDefault(plan.SubnetPrefixLength, defaultSubnetPrefixLength),
+		secondaryCidrField: valueOrDefault(plan.SecondaryPrefixLength, defaultSecondaryPrefixLength),
+		masterCidrField:    valueOrDefault(plan.MasterPrefixLength, defaultMasterPrefixLength),
+	}
+
+	planned := readinessConfig
+	planned.Contexts = map[string]model.ContextConfig{}
+	var assigned []CidrAssignment
+
+	for _, name := range sortedContextNames(readinessConfig) {
+		ctx := readinessConfig.Contexts[name]
+		if !IsExistingCluster(ctx) {
+			for _, field := range networkFields(&ctx.NetworkConfig) {
+				if *field.value != "" || !isNetworkFieldUsed(ctx, field.name) {
+					continue
+				}
+				block, err := nextFreeBlock(supernet, prefixLengths[field.name], used)
+				if err != nil {
+					return readinessConfig, nil, fmt.Errorf("context %s, %s: %w", name, field.name, err)
+				}
+				used = append(used, block)
+				assigned = append(assigned, CidrAssignment{Context: name, Field: field.name, Block: block})
+				*field.value = block.String()
+			}
+		}
+		planned.Contexts[name] = ctx
+	}
+	return planned, assigned, nil
+}
+
+type networkField struct {
+	name  string
+	value *string
+}
+
+func networkFields(networkConfig *model.NetworkConfig) []networkField {
+	return []networkField{
+		{subnetCidrField, &networkConfig.SubnetCidrBlock},
+		{secondaryCidrField, &networkConfig.SecondaryCidrBlock},
+		{masterCidrField, &networkConfig.MasterIpv4CidrBlock},
+	}
+}
+
+// isNetworkFieldUsed indicates the provider of the context maps the network config field to its Terraform variables.
+func isNetworkFieldUsed(ctx model.ContextConfig, field string) bool {
+	if field != masterCidrField {
+		return true
+	}
+	provider, err := cloud.Lookup(ctx.CloudConfig.Type)
+	return err != nil || provider.HasMasterCidrBlock()
+}
+
+func nextFreeBlock(supernet *net.IPNet, prefixLength int, used []*net.IPNet) (*net.IPNet, error) {
+
+	supernetOnes, bits := supernet.Mask.Size()
+	if prefixLength < supernetOnes || prefixLength > bits {
+		return nil, fmt.Errorf("prefix length /%d does not fit within supernet %s", prefixLength, supernet)
+	}
+
+	start := binary.BigEndian.Uint32(supernet.IP.To4())
+	size := uint64(1) << uint(bits-prefixLength)
+	end := uint64(start) + (uint64(1) << uint(bits-supernetOnes))
+
+	for candidate := uint64(start); candidate+size <= end; candidate += size {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, uint32(candidate))
+		block := &net.IPNet{IP: ip, Mask: net.CIDRMask(prefixLength, bits)}
+
+		isFree := true
+		for _, usedBlock := range used {
+			if isOverlapping(block, usedBlock) {
+				isFree = false
+				break
+			}
+		}
+		if isFree {
+			return block, nil
+		}
+	}
+	return nil, fmt.Errorf("supernet %s has no free /%d block remaining", supernet, prefixLength)
+}
+
+func isOverlapping(first *net.IPNet, second *net.IPNet) bool {
+	return first.Contains(second.IP) || second.Contains(first.IP)
+}
+
+func valueOrDefault(value int, defaultValue int) int {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,98 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDetectCidrOverlaps(t *testing.T) {
+	config := model.ReadinessConfig{Contexts: map[string]model.ContextConfig{
+		"central": {NetworkConfig: model.NetworkConfig{SubnetCidrBlock: "10.5.32.0/16",
+			SecondaryCidrBlock: "10.7.32.0/20", MasterIpv4CidrBlock: "10.0.0.0/21"}},
+		"east": {NetworkConfig: model.NetworkConfig{SubnetCidrBlock: "10.6.32.0/16",
+			SecondaryCidrBlock: "10.5.16.0/20", MasterIpv4CidrBlock: "10.0.0.0/21"}},
+	}}
+
+	overlaps := DetectCidrOverlaps(config)
+	require.Len(t, overlaps, 2)
+	require.Equal(t, "central", overlaps[0].First.Context)
+	require.Equal(t, subnetCidrField, overlaps[0].First.Field)
+	require.Equal(t, secondaryCidrField, overlaps[0].Second.Field)
+	require.Equal(t, masterCidrField, overlaps[1].First.Field)
+	require.Equal(t, "east", overlaps[1].Second.Context)
+}
+
+func TestAllocateCidrBlocks(t *testing.T) {
+	config := model.ReadinessConfig{
+		NetworkPlan: model.NetworkPlanConfig{Supernet: "10.0.0.0/16"},
+		Contexts: map[string]model.ContextConfig{
+			"central": {NetworkConfig: model.NetworkConfig{SubnetCidrBlock: "10.0.0.0/20"}},
+			"east":    {},
+			"kind":    {ExistingCluster: &model.ExistingClusterConfig{ContextName: "kind-k8ssandra-0"}},
+		},
+	}
+
+	planned, assigned, err := AllocateCidrBlocks(config)
+	require.NoError(t, err)
+	require.Len(t, assigned, 5)
+
+	central := planned.Contexts["central"].NetworkConfig
+	require.Equal(t, "10.0.0.0/20", central.SubnetCidrBlock)
+	require.Equal(t, "10.0.16.0/20", central.SecondaryCidrBlock)
+	require.Equal(t, "10.0.32.0/28", central.MasterIpv4CidrBlock)
+
+	east := planned.Contexts["east"].NetworkConfig
+	require.Equal(t, "10.0.48.0/20", east.SubnetCidrBlock)
+	require.Equal(t, "10.0.64.0/20", east.SecondaryCidrBlock)
+	require.Equal(t, "10.0.32.16/28", east.MasterIpv4CidrBlock)
+
+	require.Empty(t, planned.Contexts["kind"].NetworkConfig.SubnetCidrBlock)
+	require.Empty(t, DetectCidrOverlaps(planned))
+	require.Empty(t, config.Contexts["east"].NetworkConfig.SubnetCidrBlock, "expecting the source config unchanged")
+}
+
+func TestAllocateCidrBlocksAws(t *testing.T) {
+	config := model.ReadinessConfig{
+		NetworkPlan: model.NetworkPlanConfig{Supernet: "10.0.0.0/16"},
+		Contexts: map[string]model.ContextConfig{
+			"east": {CloudConfig: model.CloudConfig{Type: "aws"}},
+		},
+	}
+
+	planned, assigned, err := AllocateCidrBlocks(config)
+	require.NoError(t, err)
+	require.Len(t, assigned, 2)
+
+	east := planned.Contexts["east"].NetworkConfig
+	require.Equal(t, "10.0.0.0/20", east.SubnetCidrBlock)
+	require.Equal(t, "10.0.16.0/20", east.SecondaryCidrBlock)
+	require.Empty(t, east.MasterIpv4CidrBlock, "expecting no master block for the managed EKS control plane")
+}
+
+func TestAllocateCidrBlocksExhausted(t *testing.T) {
+	config := model.ReadinessConfig{
+		NetworkPlan: model.NetworkPlanConfig{Supernet: "10.0.0.0/20"},
+		Contexts:    map[string]model.ContextConfig{"central": {}, "east": {}},
+	}
+
+	_, _, err := AllocateCidrBlocks(config)
+	require.EqualError(t, err, "context central, network_config.secondary_cidr_block: "+
+		"supernet 10.0.0.0/20 has no free /20 block remaining")
+}
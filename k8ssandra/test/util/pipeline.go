@@ -0,0 +1,281 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	"strings"
+)
+
+// phaseDefinition declares the phases required to have completed before a phase and its activity.
+type phaseDefinition struct {
+	prerequisites []model.Phase
+	run           func(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) model.ProvisionMeta
+}
+
+// PhaseOrder is the natural order of the pipeline phases.
+var PhaseOrder = []model.Phase{
+	model.PhaseProvision,
+	model.PhasePreInstall,
+	model.PhaseInstall,
+	model.PhaseValidate,
+	model.PhaseUpgrade,
+	model.PhaseDiagnose,
+	model.PhaseCleanup,
+}
+
+var phaseDefinitions = map[model.Phase]phaseDefinition{
+	model.PhaseProvision: {
+		run: func(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) model.ProvisionMeta {
+			meta, results := ProvisionMultiCluster(t, readinessConfig, meta)
+			require.NotEmpty(t, meta.ProvisionId, "expected provision step to occur.")
+			RecordProvisionResults(t, meta, results)
+			return meta
+		},
+	},
+	model.PhasePreInstall: {
+		prerequisites: []model.Phase{model.PhaseProvision},
+		run: func(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) model.ProvisionMeta {
+			PreInstallSetup(t, meta, readinessConfig)
+			return meta
+		},
+	},
+	model.PhaseInstall: {
+		prerequisites: []model.Phase{model.PhaseProvision},
+		run: func(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) model.ProvisionMeta {
+			InstallK8ssandra(t, readinessConfig, meta)
+			return meta
+		},
+	},
+	model.PhaseValidate: {
+		prerequisites: []model.Phase{model.PhaseInstall},
+		run: func(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) model.ProvisionMeta {
+			VerifyInstallation(t, meta, readinessConfig)
+			return meta
+		},
+	},
+	model.PhaseUpgrade: {
+		prerequisites: []model.Phase{model.PhaseInstall},
+		run: func(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) model.ProvisionMeta {
+			UpgradeOperators(t, meta, readinessConfig)
+			return meta
+		},
+	},
+	model.PhaseDiagnose: {
+		prerequisites: []model.Phase{model.PhaseProvision},
+		run: func(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) model.ProvisionMeta {
+			CollectDiagnostics(t, meta, readinessConfig)
+			return meta
+		},
+	},
+	model.PhaseCleanup: {
+		run: func(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) model.ProvisionMeta {
+			RemoveProvisioningArtifacts(t, meta, readinessConfig, true)
+			return meta
+		},
+	},
+}
+
+// ResolvePhases provides the requested phases, mapping the enablement flags when no phases are listed.
+func ResolvePhases(enable model.EnableConfig) []model.Phase {
+	if len(enable.Phases) > 0 {
+		return enable.Phases
+	}
+
+	if enable.RemoveAll {
+		return []model.Phase{model.PhaseCleanup}
+	}
+
+	var phases []model.Phase
+	if enable.ProvisionInfra {
+		phases = append(phases, model.PhaseProvision)
+	}
+	if enable.PreInstallSetup {
+		phases = append(phases, model.PhasePreInstall)
+	}
+	if enable.Install {
+		phases = append(phases, model.PhaseInstall)
+	}
+	return phases
+}
+
+// ParsePhases parses a comma separated list of phase names.
+func ParsePhases(value string) ([]model.Phase, error) {
+	var phases []model.Phase
+	for _, name := range strings.Split(value, ",") {
+		phase := model.Phase(strings.TrimSpace(name))
+		if phase == "" {
+			continue
+		}
+		if _, found := phaseDefinitions[phase]; !found {
+			return nil, fmt.Errorf("unknown phase: %s, expected one of: %v", phase, PhaseOrder)
+		}
+		phases = append(phases, phase)
+	}
+	if len(phases) == 0 {
+		return nil, errors.New("at least one phase is required")
+	}
+	return phases, nil
+}
+
+// RunPipeline applies each phase in the order provided, requiring the prerequisites of a phase to be
+// completed earlier in the pipeline or by a prior run of the same provisioning.  When adopting, unmet
+// prerequisites are recorded as completed instead, e.g. for a run provisioned before the ledger existed.
+func RunPipeline(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig,
+	phases []model.Phase) model.ProvisionMeta {
+
+	require.NoError(t, checkPhases(phases), "expecting a valid list of phases")
+	if meta.Enable.Adopt {
+		require.NotEmpty(t, meta.ProvisionId, "expecting the provision identifier of the run to adopt")
+	}
+
+	if meta.Enable.Simulate && meta.ProvisionId == "" {
+		meta.ProvisionId = strings.ToLower(random.UniqueId())
+	}
+	if meta.ProvisionId != "" && meta.ArtifactsRootDir == "" {
+		meta.ArtifactsRootDir = DefaultArtifactsRootDir(meta.ProvisionId)
+	}
+	if meta.Enable.Simulate {
+		simulatePlan(t, meta, readinessConfig, phases)
+	}
+
+	ledger, err := LoadLedger(meta)
+	require.NoError(t, err, fmt.Sprintf("unable to load the run ledger in: %s", meta.ArtifactsRootDir))
+	completed := ledger.CompletedPhases
+
+	for _, phase := range phases {
+		definition := phaseDefinitions[phase]
+
+		if unmet := unmetPrerequisites(definition, completed, readinessConfig); len(unmet) > 0 {
+			message := fmt.Sprintf("phase: %s requires the completion of phases: %v for provision "+
+				"identifier: %s", phase, unmet, meta.ProvisionId)
+			switch {
+			case meta.Enable.Adopt:
+				logger.Log(t, fmt.Sprintf("adopting phases: %v as completed for provision identifier: %s",
+					unmet, meta.ProvisionId))
+				completed = append(completed, unmet...)
+			case !meta.Enable.Simulate:
+				RecordLedgerError(t, meta, phase, "", message)
+				require.FailNow(t, message)
+			default:
+				logger.Log(t, "WARNING: "+message)
+			}
+		}
+
+		logger.Log(t, fmt.Sprintf("phase: %s started for provision identifier: %s", phase, meta.ProvisionId))
+		if !applyPhase(t, &meta, readinessConfig, phase) {
+			logger.Log(t, fmt.Sprintf("phase: %s failed, remaining phases are not applied", phase))
+			return meta
+		}
+		logger.Log(t, fmt.Sprintf("phase: %s completed for provision identifier: %s", phase, meta.ProvisionId))
+
+		if phase == model.PhaseCleanup {
+			completed = nil
+			if files.IsExistingDir(meta.ArtifactsRootDir) {
+				UpdateLedger(t, meta, func(ledger *model.RunLedger) {
+					ledger.CompletedPhases = nil
+				})
+			}
+			continue
+		}
+
+		if !containsPhase(completed, phase) {
+			completed = append(completed, phase)
+		}
+		UpdateLedger(t, meta, func(ledger *model.RunLedger) {
+			ledger.CompletedPhases = completed
+		})
+	}
+	return meta
+}
+
+// applyPhase runs the activity of a phase, recording a failure in the run ledger.
+func applyPhase(t testing.TestingT, meta *model.ProvisionMeta, readinessConfig model.ReadinessConfig,
+	phase model.Phase) bool {
+
+	defer func() {
+		if isFailed(t) {
+			RecordLedgerError(t, *meta, phase, "", fmt.Sprintf("phase: %s failed", phase))
+		}
+	}()
+
+	*meta = phaseDefinitions[phase].run(t, *meta, readinessConfig)
+	return !isFailed(t)
+}
+
+// simulatePlan reports the execution plan of the simulated phases, writing it to the artifacts root.
+func simulatePlan(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig,
+	phases []model.Phase) {
+
+	plan, err := BuildExecutionPlan(meta, readinessConfig, phases)
+	require.NoError(t, err, "expecting an execution plan of the simulated phases")
+	logger.Log(t, "SIMULATE "+FormatExecutionPlan(plan))
+
+	planPath, writeErr := WriteExecutionPlan(meta, plan)
+	if writeErr != nil {
+		logger.Log(t, fmt.Sprintf("WARNING: unable to write the execution plan: %s", writeErr.Error()))
+		return
+	}
+	logger.Log(t, fmt.Sprintf("SIMULATE execution plan written to: %s", planPath))
+}
+
+func checkPhases(phases []model.Phase) error {
+	var seen = map[model.Phase]bool{}
+	for _, phase := range phases {
+		if _, found := phaseDefinitions[phase]; !found {
+			return fmt.Errorf("unknown phase: %s", phase)
+		}
+		if seen[phase] {
+			return fmt.Errorf("phase: %s is requested more than once", phase)
+		}
+		seen[phase] = true
+	}
+	return nil
+}
+
+// unmetPrerequisites provides the prerequisites not completed, provisioning is not required when only
+// existing clusters are referenced.
+func unmetPrerequisites(definition phaseDefinition, completed []model.Phase,
+	readinessConfig model.ReadinessConfig) []model.Phase {
+
+	var unmet []model.Phase
+	for _, prerequisite := range definition.prerequisites {
+		if prerequisite == model.PhaseProvision && !IsIdentityRequired(readinessConfig) {
+			continue
+		}
+		if !containsPhase(completed, prerequisite) {
+			unmet = append(unmet, prerequisite)
+		}
+	}
+	return unmet
+}
+
+func containsPhase(phases []model.Phase, phase model.Phase) bool {
+	for _, p := range phases {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
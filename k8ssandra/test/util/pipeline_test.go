@@ -0,0 +1,115 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	"path"
+	"testing"
+)
+
+func TestResolvePhasesFromEnablement(t *testing.T) {
+	require.Equal(t, []model.Phase{model.PhaseCleanup},
+		ResolvePhases(model.EnableConfig{RemoveAll: true, ProvisionInfra: true}))
+	require.Equal(t, []model.Phase{model.PhaseProvision, model.PhasePreInstall, model.PhaseInstall},
+		ResolvePhases(model.EnableConfig{ProvisionInfra: true, PreInstallSetup: true, Install: true}))
+	require.Equal(t, []model.Phase{model.PhaseProvision, model.PhaseInstall},
+		ResolvePhases(model.EnableConfig{ProvisionInfra: true, Install: true}))
+	require.Empty(t, ResolvePhases(model.EnableConfig{Simulate: true}))
+
+	phases := []model.Phase{model.PhaseValidate, model.PhaseDiagnose}
+	require.Equal(t, phases, ResolvePhases(model.EnableConfig{Install: true, Phases: phases}))
+}
+
+func TestParsePhases(t *testing.T) {
+	phases, err := ParsePhases("provision, install,validate")
+	require.NoError(t, err)
+	require.Equal(t, []model.Phase{model.PhaseProvision, model.PhaseInstall, model.PhaseValidate}, phases)
+
+	_, err = ParsePhases("provision,deploy")
+	require.Error(t, err)
+
+	_, err = ParsePhases(" ")
+	require.Error(t, err)
+}
+
+func TestCheckPhases(t *testing.T) {
+	require.NoError(t, checkPhases([]model.Phase{model.PhaseInstall, model.PhaseProvision}))
+	require.Error(t, checkPhases([]model.Phase{model.PhaseInstall, model.PhaseInstall}))
+	require.Error(t, checkPhases([]model.Phase{"deploy"}))
+}
+
+func TestUnmetPrerequisites(t *testing.T) {
+	config := model.ReadinessConfig{Contexts: validContexts()}
+
+	install := phaseDefinitions[model.PhaseInstall]
+	require.Equal(t, []model.Phase{model.PhaseProvision}, unmetPrerequisites(install, nil, config))
+	require.Empty(t, unmetPrerequisites(install, []model.Phase{model.PhaseProvision}, config))
+
+	validate := phaseDefinitions[model.PhaseValidate]
+	require.Equal(t, []model.Phase{model.PhaseInstall},
+		unmetPrerequisites(validate, []model.Phase{model.PhaseProvision}, config))
+
+	require.Empty(t, unmetPrerequisites(phaseDefinitions[model.PhaseCleanup], nil, config))
+}
+
+func TestUnmetPrerequisitesExistingClusters(t *testing.T) {
+	contexts := validContexts()
+	delete(contexts, "central")
+	config := model.ReadinessConfig{Contexts: contexts}
+
+	require.Empty(t, unmetPrerequisites(phaseDefinitions[model.PhaseInstall], nil, config))
+	require.Equal(t, []model.Phase{model.PhaseInstall},
+		unmetPrerequisites(phaseDefinitions[model.PhaseValidate], nil, config))
+}
+
+func TestRunPipelineSimulated(t *testing.T) {
+	meta := model.ProvisionMeta{
+		Enable:           model.EnableConfig{Simulate: true},
+		ProvisionId:      "k8c-test",
+		ArtifactsRootDir: t.TempDir(),
+		AdminIdentity:    DefaultAdminIdentifier,
+	}
+	config := model.ReadinessConfig{Contexts: validContexts()}
+
+	RunPipeline(t, meta, config, []model.Phase{model.PhasePreInstall, model.PhaseValidate, model.PhaseDiagnose})
+
+	ledger, err := LoadLedger(meta)
+	require.NoError(t, err)
+	require.Empty(t, ledger.CompletedPhases, "expecting simulated phases not to be persisted")
+	require.FileExists(t, path.Join(meta.ArtifactsRootDir, defaultPlanFileName))
+	require.FileExists(t, path.Join(meta.ArtifactsRootDir, defaultPlanTextFileName))
+}
+
+func TestRunPipelineAdopt(t *testing.T) {
+	applied := stubPhases(t, model.PhaseInstall)
+	meta := model.ProvisionMeta{
+		Enable:           model.EnableConfig{Adopt: true},
+		ProvisionId:      "k8c-test",
+		ArtifactsRootDir: t.TempDir(),
+	}
+	config := model.ReadinessConfig{Contexts: validContexts()}
+
+	RunPipeline(t, meta, config, []model.Phase{model.PhaseInstall})
+
+	require.Equal(t, []string{"install k8c-test k8s= operator="}, *applied)
+	ledger, err := LoadLedger(meta)
+	require.NoError(t, err)
+	require.Equal(t, []model.Phase{model.PhaseProvision, model.PhaseInstall}, ledger.CompletedPhases,
+		"expecting the adopted provisioning to be recorded")
+}
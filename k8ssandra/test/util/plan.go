@@ -0,0 +1,434 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/cloud"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPlanFileName     = "execution-plan.json"
+	defaultPlanTextFileName = "execution-plan.txt"
+)
+
+// BuildExecutionPlan describes every action the phases would apply, without invoking any command.
+func BuildExecutionPlan(meta model.ProvisionMeta, readinessConfig model.ReadinessConfig,
+	phases []model.Phase) (model.ExecutionPlan, error) {
+
+	if err := checkPhases(phases); err != nil {
+		return model.ExecutionPlan{}, err
+	}
+
+	artifactsRootDir := meta.ArtifactsRootDir
+	if artifactsRootDir == "" && meta.ProvisionId != "" {
+		artifactsRootDir = DefaultArtifactsRootDir(meta.ProvisionId)
+	}
+
+	var plan = model.ExecutionPlan{
+		ProvisionId:      meta.ProvisionId,
+		ArtifactsRootDir: artifactsRootDir,
+		Phases:           phases,
+		CreatedAt:        time.Now().UTC(),
+	}
+
+	for _, name := range sortedContextNames(readinessConfig) {
+		ctx := readinessConfig.Contexts[name]
+		contextPlan := model.ContextPlan{
+			Name:            name,
+			Namespace:       ctx.Namespace,
+			ControlPlane:    IsControlPlane(ctx),
+			ExistingCluster: IsExistingCluster(ctx),
+		}
+
+		if contextPlan.ExistingCluster {
+			contextPlan.FullContextName = ctx.ExistingCluster.ContextName
+		} else {
+			provider, err := cloud.Lookup(ctx.CloudConfig.Type)
+			if err != nil {
+				return model.ExecutionPlan{}, err
+			}
+			contextPlan.FullContextName = provider.ConstructFullContextName(name, ctx.CloudConfig)
+		}
+		plan.Contexts = append(plan.Contexts, contextPlan)
+	}
+
+	for _, phase := range phases {
+		switch phase {
+		case model.PhaseProvision:
+			for i := range plan.Contexts {
+				if !plan.Contexts[i].ExistingCluster {
+					action, err := terraformAction(meta, readinessConfig, plan.Contexts[i].Name, phase, "apply")
+					if err != nil {
+						return model.ExecutionPlan{}, err
+					}
+					plan.Contexts[i].Actions = append(plan.Contexts[i].Actions, action)
+				}
+			}
+		case model.PhasePreInstall:
+			planSetup(&plan, readinessConfig, phase)
+		case model.PhaseInstall:
+			planSetup(&plan, readinessConfig, phase)
+			planInstall(&plan, readinessConfig)
+		case model.PhaseValidate:
+			for i := range plan.Contexts {
+				plan.Contexts[i].Actions = append(plan.Contexts[i].Actions, model.PlanAction{
+					Phase: phase, Kind: model.PlanDeployment, Operation: "verify rollout",
+					Name: defaultK8ssandraOperatorReleaseName, Namespace: plan.Contexts[i].Namespace,
+				})
+			}
+			planChecks(&plan, readinessConfig)
+		case model.PhaseUpgrade:
+			planUpgrade(&plan, readinessConfig, artifactsRootDir)
+		case model.PhaseDiagnose:
+			for i := range plan.Contexts {
+				plan.Contexts[i].Actions = append(plan.Contexts[i].Actions, model.PlanAction{
+					Phase: phase, Kind: model.PlanArtifact, Operation: "collect",
+					Name:      path.Join(artifactsRootDir, defaultDiagnosticsFolder, plan.Contexts[i].Name+".log"),
+					Namespace: plan.Contexts[i].Namespace,
+				})
+			}
+		case model.PhaseCleanup:
+			for i := range plan.Contexts {
+				if !plan.Contexts[i].ExistingCluster {
+					action, err := terraformAction(meta, readinessConfig, plan.Contexts[i].Name, phase, "destroy")
+					if err != nil {
+						return model.ExecutionPlan{}, err
+					}
+					plan.Contexts[i].Actions = append(plan.Contexts[i].Actions, action)
+				}
+			}
+			plan.Actions = append(plan.Actions, model.PlanAction{Phase: phase, Kind: model.PlanArtifact,
+				Operation: "remove", Name: artifactsRootDir})
+		}
+	}
+	return plan, nil
+}
+
+// FormatExecutionPlan renders the execution plan for review, one line per action.
+func FormatExecutionPlan(plan model.ExecutionPlan) string {
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("execution plan for provision identifier: %s\n", plan.ProvisionId))
+	out.WriteString(fmt.Sprintf("phases: %v\n", plan.Phases))
+
+	if len(plan.Actions) > 0 {
+		out.WriteString("\nshared:\n")
+		writePlanActions(&out, plan.Actions)
+	}
+
+	for _, contextPlan := range plan.Contexts {
+		var labels []string
+		if contextPlan.ControlPlane {
+			labels = append(labels, "control-plane")
+		}
+		if contextPlan.ExistingCluster {
+			labels = append(labels, "existing-cluster")
+		}
+		out.WriteString(fmt.Sprintf("\ncontext: %s (%s) namespace: %s %v\n", contextPlan.Name,
+			contextPlan.FullContextName, contextPlan.Namespace, labels))
+		writePlanActions(&out, contextPlan.Actions)
+	}
+	return out.String()
+}
+
+// WriteExecutionPlan writes the JSON and text forms of the plan to the artifacts root, providing the JSON path.
+func WriteExecutionPlan(meta model.ProvisionMeta, plan model.ExecutionPlan) (string, error) {
+	if meta.ArtifactsRootDir == "" {
+		return "", errors.New("an artifacts root directory is required to write the execution plan")
+	}
+	if err := os.MkdirAll(meta.ArtifactsRootDir, defaultTempFilePerm); err != nil {
+		return "", err
+	}
+
+	plan.ProvisionId = meta.ProvisionId
+	plan.ArtifactsRootDir = meta.ArtifactsRootDir
+
+	content, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	planPath := path.Join(meta.ArtifactsRootDir, defaultPlanFileName)
+	if err := ioutil.WriteFile(planPath, content, defaultTempFilePerm); err != nil {
+		return "", err
+	}
+	textPath := path.Join(meta.ArtifactsRootDir, defaultPlanTextFileName)
+	return planPath, ioutil.WriteFile(textPath, []byte(FormatExecutionPlan(plan)), defaultTempFilePerm)
+}
+
+func terraformAction(meta model.ProvisionMeta, readinessConfig model.ReadinessConfig, name string,
+	phase model.Phase, operation string) (model.PlanAction, error) {
+
+	ctx := readinessConfig.Contexts[name]
+	provider, err := cloud.Lookup(ctx.CloudConfig.Type)
+	if err != nil {
+		return model.PlanAction{}, err
+	}
+
+	action := model.PlanAction{
+		Phase:     phase,
+		Kind:      model.PlanTerraformModule,
+		Operation: operation,
+		Name:      provider.ConstructCloudClusterName(name, ctx.CloudConfig),
+		Source:    path.Join(readinessConfig.ProvisionConfig.TFConfig.ModuleFolder, defaultTestSubFolder),
+	}
+
+	// The variables are reported for the modules applied, a destroy reuses the variables of the apply.
+	if phase == model.PhaseProvision {
+		action.Values = map[string]string{}
+		for key, value := range provider.TerraformVars(meta, readinessConfig, name, ctx, meta.DefaultConfigPath) {
+			action.Values[key] = fmt.Sprintf("%v", value)
+		}
+	}
+	return action, nil
+}
+
+// planSetup adds the repositories, cert-manager and Traefik installed by the installation setup.
+func planSetup(plan *model.ExecutionPlan, readinessConfig model.ReadinessConfig, phase model.Phase) {
+
+	var isRepoPlanned = false
+	for _, action := range plan.Actions {
+		if action.Kind == model.PlanHelmRepository {
+			isRepoPlanned = true
+		}
+	}
+	versions := ResolveVersions(readinessConfig.ProvisionConfig)
+	if !isRepoPlanned {
+		for _, repository := range helmRepositories(versions) {
+			plan.Actions = append(plan.Actions, model.PlanAction{Phase: phase, Kind: model.PlanHelmRepository,
+				Operation: "add", Name: repository[0], Source: repository[1]})
+		}
+	}
+
+	for i := range plan.Contexts {
+		certManagerAction := model.PlanAction{Phase: phase, Kind: model.PlanKubectlApply, Operation: "apply",
+			Name: defaultCertManagerReleaseName, Source: versions.CertManager.Manifest}
+		if versions.CertManager.Chart != "" {
+			certManagerAction = planRelease(phase, certManagerRelease(versions.CertManager))
+		}
+
+		traefikAction := planRelease(phase, traefikRelease(versions.Traefik,
+			readinessConfig.Contexts[plan.Contexts[i].Name].NetworkConfig))
+		plan.Contexts[i].Actions = append(plan.Contexts[i].Actions, certManagerAction, traefikAction)
+
+		if readinessConfig.ProvisionConfig.K8cConfig.MedusaStandIn != nil {
+			plan.Contexts[i].Actions = append(plan.Contexts[i].Actions, planRelease(phase,
+				minioRelease(versions.MinIO, ResolveMedusaStandIn(readinessConfig.ProvisionConfig.K8cConfig),
+					plan.Contexts[i].Namespace)))
+		}
+	}
+}
+
+// planRelease describes the installation of a helm release.
+func planRelease(phase model.Phase, release helmRelease) model.PlanAction {
+	var values = map[string]string{}
+	if release.ValuesFile != "" {
+		values["values_file"] = release.ValuesFile
+	}
+	for key, value := range release.Values {
+		values[key] = value
+	}
+
+	action := model.PlanAction{Phase: phase, Kind: model.PlanHelmRelease, Operation: "install",
+		Name: release.Name, Namespace: release.Namespace, Source: release.Chart, Version: release.Version}
+	if len(values) > 0 {
+		action.Values = values
+	}
+	return action
+}
+
+// planChecks adds the post-install checks of every context, the datacenter of a context named after it.
+func planChecks(plan *model.ExecutionPlan, readinessConfig model.ReadinessConfig) {
+
+	phase := model.PhaseValidate
+	for i := range plan.Contexts {
+		contextPlan := &plan.Contexts[i]
+		if contextPlan.ControlPlane {
+			contextPlan.Actions = append(contextPlan.Actions, model.PlanAction{
+				Phase: phase, Kind: model.PlanCheck, Operation: "wait", Name: CheckK8ssandraClusterReady,
+				Namespace: contextPlan.Namespace,
+				Source:    "k8ssandracluster/" + readinessConfig.ProvisionConfig.K8cConfig.ClusterName,
+			})
+		}
+		datacenter := "cassandradatacenter/" + contextPlan.Name
+		contextPlan.Actions = append(contextPlan.Actions,
+			model.PlanAction{Phase: phase, Kind: model.PlanCheck, Operation: "wait", Name: CheckDatacenterReady,
+				Namespace: contextPlan.Namespace, Source: datacenter},
+			model.PlanAction{Phase: phase, Kind: model.PlanCheck, Operation: "count", Name: CheckRackPods,
+				Namespace: contextPlan.Namespace, Source: datacenter},
+			model.PlanAction{Phase: phase, Kind: model.PlanCheck, Operation: "exec", Name: CheckNodetoolStatus,
+				Namespace: contextPlan.Namespace, Source: datacenter},
+			model.PlanAction{Phase: phase, Kind: model.PlanCheck, Operation: "exec", Name: CheckCqlConsistency,
+				Namespace: contextPlan.Namespace, Source: datacenter},
+		)
+		if readinessConfig.ProvisionConfig.K8cConfig.MedusaSecretName != "" {
+			contextPlan.Actions = append(contextPlan.Actions, model.PlanAction{Phase: phase, Kind: model.PlanCheck,
+				Operation: "backup and restore", Name: CheckMedusaBackupRestore, Namespace: contextPlan.Namespace,
+				Source: datacenter})
+		}
+	}
+}
+
+// planUpgrade adds the k8ssandra-operator upgrade of every context, numbered in the upgrade order, and the
+// upgrade report written.
+func planUpgrade(plan *model.ExecutionPlan, readinessConfig model.ReadinessConfig, artifactsRootDir string) {
+
+	phase := model.PhaseUpgrade
+	component := ResolveVersions(readinessConfig.ProvisionConfig).K8ssandraOperator
+	component.Version = readinessConfig.ProvisionConfig.Upgrade.ToVersion
+
+	for position, name := range ResolveUpgradeOrder(readinessConfig) {
+		for i := range plan.Contexts {
+			contextPlan := &plan.Contexts[i]
+			if contextPlan.Name != name {
+				continue
+			}
+			action := planRelease(phase, componentRelease(defaultK8ssandraOperatorReleaseName,
+				contextPlan.Namespace, component))
+			action.Operation = "upgrade"
+			if action.Values == nil {
+				action.Values = map[string]string{}
+			}
+			action.Values[defaultControlPlaneKey] = strconv.FormatBool(contextPlan.ControlPlane)
+			action.Values["upgrade_order"] = strconv.Itoa(position + 1)
+			contextPlan.Actions = append(contextPlan.Actions, action, model.PlanAction{
+				Phase: phase, Kind: model.PlanDeployment, Operation: "verify rollout",
+				Name: defaultK8ssandraOperatorReleaseName, Namespace: contextPlan.Namespace,
+			})
+		}
+	}
+	plan.Actions = append(plan.Actions, model.PlanAction{Phase: phase, Kind: model.PlanArtifact,
+		Operation: "write", Name: path.Join(artifactsRootDir, defaultUpgradeReportFileName)})
+}
+
+// planInstall adds the operators, the client configurations of every context and the K8ssandraCluster
+// deployed on the control plane.
+func planInstall(plan *model.ExecutionPlan, readinessConfig model.ReadinessConfig) {
+
+	phase := model.PhaseInstall
+	versions := ResolveVersions(readinessConfig.ProvisionConfig)
+	for i := range plan.Contexts {
+		contextPlan := &plan.Contexts[i]
+		action := planRelease(phase, componentRelease(defaultK8ssandraOperatorReleaseName, contextPlan.Namespace,
+			versions.K8ssandraOperator))
+		if action.Values == nil {
+			action.Values = map[string]string{}
+		}
+		action.Values[defaultControlPlaneKey] = strconv.FormatBool(contextPlan.ControlPlane)
+		contextPlan.Actions = append(contextPlan.Actions, action)
+	}
+
+	var clientConfigNames []string
+	for _, contextPlan := range plan.Contexts {
+		clientConfigNames = append(clientConfigNames, strings.ReplaceAll(contextPlan.FullContextName, "_", "-"))
+	}
+	sort.Strings(clientConfigNames)
+
+	for i := range plan.Contexts {
+		contextPlan := &plan.Contexts[i]
+		roleKind, roleName := "Role", defaultClientServiceAccountName
+		if readinessConfig.ProvisionConfig.K8cConfig.ClusterScoped {
+			roleKind, roleName = "ClusterRole", defaultClientServiceAccountName+"-"+contextPlan.Namespace
+		}
+		contextPlan.Actions = append(contextPlan.Actions,
+			model.PlanAction{Phase: phase, Kind: model.PlanServiceAccount, Operation: "create",
+				Name: defaultClientServiceAccountName, Namespace: contextPlan.Namespace},
+			model.PlanAction{Phase: phase, Kind: model.PlanRole, Operation: "apply",
+				Name: roleName, Namespace: contextPlan.Namespace, Values: map[string]string{"kind": roleKind}},
+		)
+		contextPlan.Actions = append(contextPlan.Actions, model.PlanAction{
+			Phase: phase, Kind: model.PlanSecret, Operation: "create",
+			Name: defaultK8ssandraSecret, Namespace: contextPlan.Namespace,
+		})
+		for _, clientConfigName := range clientConfigNames {
+			contextPlan.Actions = append(contextPlan.Actions, model.PlanAction{
+				Phase: phase, Kind: model.PlanClientConfig, Operation: "apply",
+				Name: clientConfigName, Namespace: contextPlan.Namespace,
+			})
+		}
+		for _, deployment := range []string{defaultK8ssandraOperatorReleaseName, defaultCassandraOperatorName} {
+			contextPlan.Actions = append(contextPlan.Actions, model.PlanAction{
+				Phase: phase, Kind: model.PlanDeployment, Operation: "restart",
+				Name: deployment, Namespace: contextPlan.Namespace,
+			})
+		}
+	}
+
+	for i := range plan.Contexts {
+		contextPlan := &plan.Contexts[i]
+		if medusaSecret := readinessConfig.ProvisionConfig.K8cConfig.MedusaSecretName; medusaSecret != "" {
+			contextPlan.Actions = append(contextPlan.Actions, model.PlanAction{
+				Phase: phase, Kind: model.PlanSecret, Operation: "create",
+				Name: medusaSecret, Namespace: contextPlan.Namespace,
+			})
+		}
+		if contextPlan.ControlPlane {
+			contextPlan.Actions = append(contextPlan.Actions, model.PlanAction{
+				Phase: phase, Kind: model.PlanKubectlApply, Operation: "apply",
+				Name: "k8ssandra-cluster", Namespace: contextPlan.Namespace,
+				Source: planManifestSource(plan.ArtifactsRootDir, readinessConfig),
+			})
+		}
+	}
+}
+
+// planManifestSource provides the static manifest when referenced, otherwise the generated manifest along with
+// its overlay.
+func planManifestSource(artifactsRootDir string, readinessConfig model.ReadinessConfig) string {
+	if valuesFilePath := readinessConfig.ProvisionConfig.K8cConfig.ValuesFilePath; valuesFilePath != "" {
+		return path.Join(defaultConfigFolder, valuesFilePath)
+	}
+	if overlayPath := OverlayPath(readinessConfig); overlayPath != "" {
+		return path.Join(artifactsRootDir, defaultManifestFileName) + " with overlay " + overlayPath
+	}
+	return path.Join(artifactsRootDir, defaultManifestFileName)
+}
+
+func writePlanActions(out *strings.Builder, actions []model.PlanAction) {
+	for i, action := range actions {
+		out.WriteString(fmt.Sprintf("  %d. [%s] %s %s: %s", i+1, action.Phase, action.Operation, action.Kind,
+			action.Name))
+		if action.Namespace != "" {
+			out.WriteString(" -n " + action.Namespace)
+		}
+		if action.Source != "" {
+			out.WriteString(" from " + action.Source)
+		}
+		if action.Version != "" {
+			out.WriteString(" version " + action.Version)
+		}
+		var keys []string
+		for key := range action.Values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			out.WriteString(fmt.Sprintf(" %s=%s", key, action.Values[key]))
+		}
+		out.WriteString("\n")
+	}
+}
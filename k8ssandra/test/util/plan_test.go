@@ -0,0 +1,143 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"encoding/json"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func planActions(actions []model.PlanAction) []string {
+	var result []string
+	for _, action := range actions {
+		result = append(result, string(action.Phase)+"/"+action.Operation+"/"+string(action.Kind)+"/"+action.Name)
+	}
+	return result
+}
+
+func TestBuildExecutionPlan(t *testing.T) {
+	meta := model.ProvisionMeta{ProvisionId: "k8c-test"}
+	config := model.ReadinessConfig{Contexts: validContexts()}
+	config.ProvisionConfig.K8cConfig.ClusterName = "bootz-k8c-cluster"
+
+	plan, err := BuildExecutionPlan(meta, config, []model.Phase{model.PhaseProvision, model.PhaseInstall})
+	require.NoError(t, err)
+	require.Equal(t, DefaultArtifactsRootDir("k8c-test"), plan.ArtifactsRootDir)
+	require.Len(t, plan.Actions, 3, "expecting the helm repositories to be added once")
+
+	require.Len(t, plan.Contexts, 2)
+	central := plan.Contexts[0]
+	require.Equal(t, "central", central.Name)
+	require.Equal(t, "gke__us-central1_dev-central", central.FullContextName)
+	require.True(t, central.ControlPlane)
+	require.Equal(t, []string{
+		"provision/apply/terraform-module/dev-central",
+		"install/apply/kubectl-apply/cert-manager",
+		"install/install/helm-release/traefik",
+		"install/install/helm-release/k8ssandra-operator",
+		"install/create/service-account/k8ssandra-client",
+		"install/apply/role/k8ssandra-client",
+		"install/create/secret/k8s-contexts",
+		"install/apply/client-config/gke--us-central1-dev-central",
+		"install/apply/client-config/kind-k8ssandra-0",
+		"install/restart/deployment/k8ssandra-operator",
+		"install/restart/deployment/k8ssandra-operator-cass-operator",
+		"install/apply/kubectl-apply/k8ssandra-cluster",
+	}, planActions(central.Actions))
+	require.Equal(t, "k8c-test", central.Actions[0].Values["provision_id"])
+	require.Equal(t, "true", central.Actions[3].Values[defaultControlPlaneKey])
+	require.Equal(t, path.Join(DefaultArtifactsRootDir("k8c-test"), defaultManifestFileName),
+		central.Actions[len(central.Actions)-1].Source, "expecting the generated manifest to be applied")
+
+	kind := plan.Contexts[1]
+	require.True(t, kind.ExistingCluster)
+	require.Equal(t, "kind-k8ssandra-0", kind.FullContextName)
+	require.Equal(t, "install/apply/kubectl-apply/cert-manager", planActions(kind.Actions)[0],
+		"expecting no terraform module for an existing cluster")
+	require.Equal(t, "false", kind.Actions[2].Values[defaultControlPlaneKey])
+	require.Len(t, kind.Actions, 10, "expecting no k8ssandra-cluster on a data-plane")
+}
+
+func TestBuildExecutionPlanCleanup(t *testing.T) {
+	meta := model.ProvisionMeta{ProvisionId: "k8c-test"}
+	config := model.ReadinessConfig{Contexts: validContexts()}
+
+	plan, err := BuildExecutionPlan(meta, config, []model.Phase{model.PhaseValidate, model.PhaseCleanup})
+	require.NoError(t, err)
+	require.Equal(t, []string{"cleanup/remove/artifact/" + DefaultArtifactsRootDir("k8c-test")},
+		planActions(plan.Actions))
+	require.Equal(t, []string{
+		"validate/verify rollout/deployment/k8ssandra-operator",
+		"validate/wait/check/k8ssandra-cluster-ready",
+		"validate/wait/check/datacenter-ready",
+		"validate/count/check/rack-pods",
+		"validate/exec/check/nodetool-status",
+		"validate/exec/check/cql-consistency",
+		"cleanup/destroy/terraform-module/dev-central",
+	}, planActions(plan.Contexts[0].Actions))
+	require.Equal(t, []string{
+		"validate/verify rollout/deployment/k8ssandra-operator",
+		"validate/wait/check/datacenter-ready",
+		"validate/count/check/rack-pods",
+		"validate/exec/check/nodetool-status",
+		"validate/exec/check/cql-consistency",
+	}, planActions(plan.Contexts[1].Actions))
+	require.Equal(t, "cassandradatacenter/kind", plan.Contexts[1].Actions[1].Source)
+}
+
+func TestBuildExecutionPlanInvalid(t *testing.T) {
+	config := model.ReadinessConfig{Contexts: validContexts()}
+	_, err := BuildExecutionPlan(model.ProvisionMeta{}, config, []model.Phase{"deploy"})
+	require.Error(t, err)
+
+	contexts := validContexts()
+	central := contexts["central"]
+	central.CloudConfig.Type = "unknown"
+	contexts["central"] = central
+	_, err = BuildExecutionPlan(model.ProvisionMeta{}, model.ReadinessConfig{Contexts: contexts},
+		[]model.Phase{model.PhaseProvision})
+	require.Error(t, err)
+}
+
+func TestWriteExecutionPlan(t *testing.T) {
+	meta := model.ProvisionMeta{ProvisionId: "k8c-test", ArtifactsRootDir: t.TempDir()}
+	config := model.ReadinessConfig{Contexts: validContexts()}
+
+	plan, err := BuildExecutionPlan(meta, config, []model.Phase{model.PhasePreInstall})
+	require.NoError(t, err)
+
+	planPath, err := WriteExecutionPlan(meta, plan)
+	require.NoError(t, err)
+
+	content, err := ioutil.ReadFile(planPath)
+	require.NoError(t, err)
+	var written model.ExecutionPlan
+	require.NoError(t, json.Unmarshal(content, &written))
+	require.Equal(t, plan.Contexts, written.Contexts)
+
+	text, err := ioutil.ReadFile(path.Join(meta.ArtifactsRootDir, defaultPlanTextFileName))
+	require.NoError(t, err)
+	require.Contains(t, string(text), "context: central (gke__us-central1_dev-central) namespace: bootz [control-plane]")
+	require.Contains(t, string(text), "1. [pre-install] add helm-repository: jetstack from https://charts.jetstack.io")
+
+	_, err = WriteExecutionPlan(model.ProvisionMeta{}, plan)
+	require.Error(t, err)
+}
@@ -26,14 +26,15 @@ import (
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	ts "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
-	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/cloud/gcp"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
 	"github.com/stretchr/testify/require"
 	"os"
 	"path"
-	"strconv"
+	"sort"
 	"strings"
-	"testing"
+	"sync"
 )
 
 const (
@@ -43,7 +44,6 @@ const (
 	defaultK8ssandraOperatorReleaseName = "k8ssandra-operator"
 	defaultK8ssandraOperatorChart       = "k8ssandra/k8ssandra-operator"
 	defaultK8ssandraRepositoryURL       = "https://helm.k8ssandra.io/stable"
-	defaultCertManagerFile              = "https://github.com/jetstack/cert-manager/releases/download/v1.5.3/cert-manager.yaml"
 	defaultCertManagerRepositoryName    = "jetstack"
 	defaultCertManagerRepositoryURL     = "https://charts.jetstack.io"
 	defaultTraefikRepositoryName        = "traefik"
@@ -51,25 +51,58 @@ const (
 	defaultTraefikChartName             = "traefik/traefik"
 	defaultRelativeRootFolder           = "../.."
 	prefixFolderName                    = "cloud-k8c-"
+	prefixModulesFolderName             = "k8c-modules-"
+
+	defaultPlanStep  = "plan"
+	defaultApplyStep = "apply"
 
 	DefaultAdminIdentifier = "K8C_ADMIN_ID"
 	DefaultTraefikVersion  = "v10.3.2"
 )
 
-func ProvisionMultiCluster(t *testing.T, readinessConfig model.ReadinessConfig,
-	provisionMeta model.ProvisionMeta) model.ProvisionMeta {
+var timeoutFailurePatterns = []string{
+	"timeout while waiting",
+	"timed out",
+	"context deadline exceeded",
+	"i/o timeout",
+}
+
+var configFailurePatterns = []string{
+	"invalid value for",
+	"invalid reference",
+	"unsupported argument",
+	"unsupported attribute",
+	"missing required argument",
+	"reference to undeclared",
+	"no value for required variable",
+	"failed to load",
+	"module not installed",
+}
+
+// ProvisionMultiCluster provisions the infrastructure of every context in parallel, providing the result of
+// each provisioned context ordered by context name.
+func ProvisionMultiCluster(t testing.TestingT, readinessConfig model.ReadinessConfig,
+	provisionMeta model.ProvisionMeta) (model.ProvisionMeta, []model.ProvisionResult) {
+
+	provisionId := provisionMeta.ProvisionId
+	if provisionId == "" {
+		provisionId = strings.ToLower(random.UniqueId())
+	}
 
-	uniqueProvisionId := strings.ToLower(random.UniqueId())
-	testFolderName := path.Join(os.TempDir(), prefixFolderName+uniqueProvisionId)
+	artifactsRootDir := provisionMeta.ArtifactsRootDir
+	if artifactsRootDir == "" {
+		artifactsRootDir = DefaultArtifactsRootDir(provisionId)
+	}
 
 	var meta = model.ProvisionMeta{
 		KubeConfigs:       map[string]string{},
 		Enable:            provisionMeta.Enable,
-		ProvisionId:       uniqueProvisionId,
-		ArtifactsRootDir:  testFolderName,
+		ProvisionId:       provisionId,
+		ArtifactsRootDir:  artifactsRootDir,
 		DefaultConfigPath: provisionMeta.DefaultConfigPath,
 		DefaultConfigDir:  provisionMeta.DefaultConfigDir,
 		AdminIdentity:     DefaultAdminIdentifier,
+		Cassette:          provisionMeta.Cassette,
 	}
 
 	provConfig := readinessConfig.ProvisionConfig
@@ -77,15 +110,33 @@ func ProvisionMultiCluster(t *testing.T, readinessConfig model.ReadinessConfig,
 
 	initTempArtifacts(t, meta)
 
+	ledger, ledgerErr := LoadLedger(meta)
+	require.NoError(t, ledgerErr, fmt.Sprintf("unable to load the run ledger in: %s", meta.ArtifactsRootDir))
+
+	var contextOptions = map[string]*terraform.Options{}
 	for name, ctx := range readinessConfig.Contexts {
 
-		testPath := ts.FormatTestDataPath(testFolderName, ctx.Name)
+		if IsExistingCluster(ctx) {
+			logger.Log(t, fmt.Sprintf("existing cluster referenced for: %s, provisioning not required", name))
+			UpdateLedger(t, meta, func(ledger *model.RunLedger) {
+				ledger.Contexts[name] = model.LedgerContext{KubeConfigPath: ExistingKubeConfigPath(meta, ctx)}
+			})
+			continue
+		}
+
+		testPath := ts.FormatTestDataPath(meta.ArtifactsRootDir, ctx.Name)
 		logger.Log(t, fmt.Sprintf("test path formatted as: %s", testPath))
 
-		modulesFolder := ts.CopyTerraformFolderToTemp(t, defaultRelativeRootFolder, tfConfig.ModuleFolder)
+		modulesFolder := ledger.Contexts[name].ModulesFolder
+		if modulesFolder != "" && files.IsExistingDir(modulesFolder) {
+			logger.Log(t, fmt.Sprintf("resuming provisioning of: %s with modules folder: %s", name, modulesFolder))
+		} else {
+			modulesFolder = copyModulesToTemp(t, defaultRelativeRootFolder, tfConfig.ModuleFolder)
+		}
 
-		options := CreateTerraformOptions(meta, readinessConfig, name, ctx,
+		options, optionsErr := CreateTerraformOptions(meta, readinessConfig, name, ctx,
 			meta.DefaultConfigPath, path.Join(modulesFolder, defaultTestSubFolder))
+		require.NoError(t, optionsErr, fmt.Sprintf("expecting terraform options for context: %s", name))
 
 		testData := model.ContextTestManifest{
 			Name:            ctx.Name,
@@ -94,17 +145,52 @@ func ProvisionMultiCluster(t *testing.T, readinessConfig model.ReadinessConfig,
 		}
 
 		identity := FetchEnv(t, meta.AdminIdentity)
-		env := CreateIdentityEnv(meta.DefaultConfigPath, identity, ctx.CloudConfig.CredPath)
+		env := CreateIdentityEnv(t, meta.DefaultConfigPath, identity, ctx)
 
-		gcp.Switch(t, identity, env)
+		FetchCloudProvider(t, ctx).Switch(t, identity, env)
 		ts.SaveTestData(t, testPath, testData)
+		UpdateLedger(t, meta, func(ledger *model.RunLedger) {
+			ledger.Contexts[name] = model.LedgerContext{ModulesFolder: modulesFolder,
+				KubeConfigPath: meta.DefaultConfigPath}
+		})
+
+		contextOptions[name] = &options
+	}
+
+	var mutex sync.Mutex
+	var results []model.ProvisionResult
+	record := func(result model.ProvisionResult) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		results = append(results, result)
+	}
+
+	if len(contextOptions) > 0 && files.FileExists(meta.DefaultConfigPath) {
+		logger.Log(t, fmt.Sprintf("backing up existing kube config file: %s", meta.DefaultConfigPath))
+		cpErr := files.CopyFile(meta.DefaultConfigPath, meta.DefaultConfigPath+"-backup")
+		require.NoError(t, cpErr, "expecting backup of default config file")
+	}
 
-		provisionCluster(t, name, &options, meta, readinessConfig)
+	var names []string
+	for name := range contextOptions {
+		names = append(names, name)
 	}
-	return meta
+	sort.Strings(names)
+
+	// The parallel provisioning of each context completes before the group returns.
+	runSubtest(t, "provision", func(t testing.TestingT) {
+		runParallelSubtests(t, names, func(t testing.TestingT, name string) {
+			provisionCluster(t, name, contextOptions[name], meta, record)
+		})
+	})
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Context < results[j].Context
+	})
+	return meta, results
 }
 
-func Cleanup(t *testing.T, meta model.ProvisionMeta, name string, options *terraform.Options) bool {
+func Cleanup(t testing.TestingT, meta model.ProvisionMeta, name string, options *terraform.Options) bool {
 
 	logger.Log(t, fmt.Sprintf("cleanup started for resources in: %s", name))
 
@@ -113,15 +199,15 @@ func Cleanup(t *testing.T, meta model.ProvisionMeta, name string, options *terra
 		return true
 	}
 
-	initOut, initErr := terraform.InitE(t, options)
-	// initPlanOut, initPlanErr := terraform.InitAndPlanE(t, options)
+	initOut, initErr := executor.TerraformInit(t, options)
+	// initPlanOut, initPlanErr := executor.TerraformInitAndPlan(t, options)
 	if initErr != nil {
 		logger.Log(t, fmt.Sprintf("failed cleanup on init, error: %s", initErr.Error()))
 		return false
 	}
 	logger.Log(t, fmt.Sprintf("successful cleanup init-plan, output: %s", initOut))
 
-	destroyOut, destroyErr := terraform.DestroyE(t, options)
+	destroyOut, destroyErr := executor.TerraformDestroy(t, options)
 	if destroyErr != nil {
 		logger.Log(t, fmt.Sprintf("failed cleanup destroy, error: %s", destroyErr.Error()))
 		return false
@@ -131,63 +217,52 @@ func Cleanup(t *testing.T, meta model.ProvisionMeta, name string, options *terra
 	return true
 }
 
-func initTempArtifacts(t *testing.T, meta model.ProvisionMeta) {
+func initTempArtifacts(t testing.TestingT, meta model.ProvisionMeta) {
 	var rootTempDir = meta.ArtifactsRootDir
 	if files.IsExistingDir(rootTempDir) {
-		err := os.Remove(rootTempDir)
-		require.NoError(t, err, fmt.Sprintf("failed to remove a tmp root dir: %s", rootTempDir))
+		logger.Log(t, fmt.Sprintf("existing artifacts referenced in: %s", rootTempDir))
 	}
 
 	mkdirErr := os.MkdirAll(rootTempDir, defaultTempFilePerm)
 	require.NoError(t, mkdirErr, fmt.Sprintf("failed to init folder: %s", rootTempDir))
 }
 
-func provisionCluster(t *testing.T, name string, tfOptions *terraform.Options,
-	meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) {
-
-	if files.FileExists(meta.DefaultConfigPath) {
-		logger.Log(t, fmt.Sprintf("backing up existing kube config file: %s", meta.DefaultConfigPath))
-		cpErr := files.CopyFile(meta.DefaultConfigPath, meta.DefaultConfigPath+"-backup")
-		require.NoError(t, cpErr, "expecting backup of default config file")
-	}
-
-	timeout, _ := t.Deadline()
-	logger.Log(t, fmt.Sprintf("executing test:"+
-		"%s with timeout: %d(m)",
-		t.Name(), timeout.UnixMilli()))
-
-	testRun := t.Run(name, func(t *testing.T) {
-		t.Parallel()
-		if meta.Enable.Simulate {
-			timeout, _ := t.Deadline()
-			logger.Log(t, fmt.Sprintf("SIMULATION, init, plan, and apply being invoked for:"+
-				"%s with timeout: %d(m)", t.Name(), timeout.UnixMilli()))
-
-		} else {
-			logger.Log(t, fmt.Sprintf("init, plan and apply being invoked for: %s", name))
+// copyModulesToTemp copies the root folder of the modules to a temp folder named after the modules prefix,
+// whichever test or command provisions, providing the path of the module folder in the copy.
+func copyModulesToTemp(t testing.TestingT, rootFolder string, moduleFolder string) string {
+	tempRootFolder, err := files.CopyTerraformFolderToDest(rootFolder, os.TempDir(), prefixModulesFolderName)
+	require.NoError(t, err, fmt.Sprintf("expecting a copy of the modules in: %s", rootFolder))
 
-			planErr, applyErr := apply(t, tfOptions)
+	modulesFolder := path.Join(tempRootFolder, moduleFolder)
+	require.True(t, files.IsExistingDir(modulesFolder), fmt.Sprintf("expecting module folder: %s", modulesFolder))
+	logger.Log(t, fmt.Sprintf("copied modules folder: %s to: %s", path.Join(rootFolder, moduleFolder), modulesFolder))
+	return modulesFolder
+}
 
-			if planErr != nil || applyErr != nil {
-				logger.Log(t, fmt.Sprintf("provision: %s, failure discovered. plan err reported: %s apply "+
-					"err reported: %s", name, planErr, applyErr))
+// provisionCluster applies the Terraform modules of a context, run as one of the parallel provisioning subtests.
+func provisionCluster(t testing.TestingT, name string, tfOptions *terraform.Options, meta model.ProvisionMeta,
+	record func(result model.ProvisionResult)) {
 
-				// TODO indicate to the test client a failure overall, IF we can determine that there is an actual
-				// issue with the TF activities or it was simply a timeout on that side.
-			}
-		}
+	timeout, _ := deadline(t)
+	if meta.Enable.Simulate {
+		logger.Log(t, fmt.Sprintf("SIMULATION, init, plan, and apply being invoked for:"+
+			"%s with timeout: %d(m)", t.Name(), timeout.UnixMilli()))
+		record(model.ProvisionResult{Success: true, Context: name, Phase: model.PhaseProvision})
+		return
+	}
 
-		if meta.Enable.PreInstallSetup {
-			PreInstallSetup(t, meta, readinessConfig)
-		} else {
-			logger.Log(t, fmt.Sprintf("No pre-install setup requested for: %s", name))
-		}
-	})
-	logger.Log(t, fmt.Sprintf("test run: %s reported success as: %s", name, strconv.FormatBool(testRun)))
+	logger.Log(t, fmt.Sprintf("init, plan and apply being invoked for: %s with timeout: %d(m)", name,
+		timeout.UnixMilli()))
+	result := apply(t, name, tfOptions)
+	record(result)
 
+	if !result.Success {
+		t.Errorf("provision: %s, %s failure discovered in step: %s, error: %s", name,
+			result.Classification, result.Step, result.Error)
+	}
 }
 
-func PreInstallSetup(t *testing.T, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) {
+func PreInstallSetup(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) {
 	if meta.Enable.Simulate {
 		logger.Log(t, fmt.Sprintf("SIMULATION, pre-install setup requested"))
 	} else {
@@ -214,13 +289,54 @@ func createHelmOptions(kubeConfig *k8s.KubectlOptions, values map[string]string,
 	return helmOptions
 }
 
-func apply(t *testing.T, options *terraform.Options) (error, error) {
+// apply plans and applies the Terraform modules of a context, stopping at the first failing step.
+func apply(t testing.TestingT, name string, options *terraform.Options) model.ProvisionResult {
+
+	var result = model.ProvisionResult{Context: name, Phase: model.PhaseProvision, Step: defaultPlanStep}
 
-	_, initPlanErr := terraform.InitAndPlanE(t, options)
+	initPlanOut, initPlanErr := executor.TerraformInitAndPlan(t, options)
+	if initPlanErr != nil {
+		return failedResult(result, initPlanOut, initPlanErr)
+	}
 	logger.Log(t, fmt.Sprintf("initialized and planned: %s", t.Name()))
 
-	_, applyErr := terraform.ApplyE(t, options)
+	result.Step = defaultApplyStep
+	applyOut, applyErr := executor.TerraformApply(t, options)
+	if applyErr != nil {
+		return failedResult(result, applyOut, applyErr)
+	}
 	logger.Log(t, fmt.Sprintf("applied: %s", t.Name()))
 
-	return initPlanErr, applyErr
+	result.Success = true
+	result.Output = applyOut
+	return result
+}
+
+func failedResult(result model.ProvisionResult, output string, err error) model.ProvisionResult {
+	result.Success = false
+	result.Output = output
+	result.Error = err.Error()
+	result.Classification = ClassifyFailure(output, err)
+	return result
+}
+
+// ClassifyFailure classifies a Terraform failure as a timeout, a configuration error, or otherwise an error
+// reported by the cloud.
+func ClassifyFailure(output string, err error) model.FailureClass {
+	var message = strings.ToLower(output)
+	if err != nil {
+		message += "\n" + strings.ToLower(err.Error())
+	}
+
+	for _, pattern := range timeoutFailurePatterns {
+		if strings.Contains(message, pattern) {
+			return model.FailureTimeout
+		}
+	}
+	for _, pattern := range configFailurePatterns {
+		if strings.Contains(message, pattern) {
+			return model.FailureConfig
+		}
+	}
+	return model.FailureCloud
 }
@@ -0,0 +1,112 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"errors"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const fakeTerraformScript = `#!/bin/sh
+case "$1" in
+  plan)
+    if [ -n "$FAKE_PLAN_ERROR" ]; then
+      echo "$FAKE_PLAN_ERROR" >&2
+      exit 1
+    fi
+    ;;
+  apply)
+    if [ -n "$FAKE_APPLY_ERROR" ]; then
+      echo "$FAKE_APPLY_ERROR" >&2
+      exit 1
+    fi
+    echo "Apply complete! Resources: 3 added, 0 changed, 0 destroyed."
+    ;;
+esac
+`
+
+// fakeTerraformOptions provides options invoking a fake terraform executable with the provided env.
+func fakeTerraformOptions(t *testing.T, env map[string]string) *terraform.Options {
+	binDir := t.TempDir()
+	binary := filepath.Join(binDir, "terraform")
+	require.NoError(t, os.WriteFile(binary, []byte(fakeTerraformScript), 0755))
+
+	return &terraform.Options{
+		TerraformBinary: binary,
+		TerraformDir:    t.TempDir(),
+		EnvVars:         env,
+		NoColor:         true,
+	}
+}
+
+func TestApplySuccess(t *testing.T) {
+	result := apply(t, "central", fakeTerraformOptions(t, map[string]string{}))
+
+	require.True(t, result.Success)
+	require.Equal(t, "central", result.Context)
+	require.Equal(t, model.PhaseProvision, result.Phase)
+	require.Equal(t, defaultApplyStep, result.Step)
+	require.Contains(t, result.Output, "Apply complete!")
+	require.Empty(t, result.Classification)
+}
+
+func TestApplyPlanFailure(t *testing.T) {
+	result := apply(t, "central", fakeTerraformOptions(t, map[string]string{
+		"FAKE_PLAN_ERROR":  "Error: Invalid value for variable",
+		"FAKE_APPLY_ERROR": "Error: apply is not expected",
+	}))
+
+	require.False(t, result.Success)
+	require.Equal(t, defaultPlanStep, result.Step)
+	require.NotEmpty(t, result.Error)
+	require.Equal(t, model.FailureConfig, result.Classification)
+}
+
+func TestApplyFailure(t *testing.T) {
+	result := apply(t, "central", fakeTerraformOptions(t, map[string]string{
+		"FAKE_APPLY_ERROR": "Error: googleapi: Error 403: Insufficient regional quota, forbidden",
+	}))
+
+	require.False(t, result.Success)
+	require.Equal(t, defaultApplyStep, result.Step)
+	require.Equal(t, model.FailureCloud, result.Classification)
+}
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		output   string
+		err      error
+		expected model.FailureClass
+	}{
+		{"Error: timeout while waiting for state to become 'RUNNING'", errors.New("exit status 1"),
+			model.FailureTimeout},
+		{"", errors.New("context deadline exceeded"), model.FailureTimeout},
+		{"Error: Unsupported argument", errors.New("exit status 1"), model.FailureConfig},
+		{"Error: No value for required variable", errors.New("exit status 1"), model.FailureConfig},
+		{"Error: Error creating Network: googleapi: Error 409: already exists", errors.New("exit status 1"),
+			model.FailureCloud},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.expected, ClassifyFailure(test.output, test.err), test.output)
+	}
+}
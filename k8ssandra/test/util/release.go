@@ -0,0 +1,292 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/goccy/go-yaml"
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"k8s.io/utils/strings/slices"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// releaseAction is the helm action bringing a release to its desired chart version and values.
+type releaseAction string
+
+const (
+	releaseInstall   releaseAction = "install"
+	releaseUpgrade   releaseAction = "upgrade"
+	releaseUnchanged releaseAction = "unchanged"
+
+	releaseStatusDeployed = "deployed"
+)
+
+// helmRelease is the desired state of a release. An empty version accepts any deployed chart version.
+type helmRelease struct {
+	Name       string
+	Chart      string
+	Version    string
+	Namespace  string
+	ValuesFile string
+	Values     map[string]string
+}
+
+// deployedRelease is a release as listed by helm.
+type deployedRelease struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	Revision   string `json:"revision"`
+	Status     string `json:"status"`
+	Chart      string `json:"chart"`
+	AppVersion string `json:"app_version"`
+}
+
+// applyRelease installs the release when absent, upgrades it when its chart version, values or status
+// differ from the desired ones, and otherwise leaves it alone.
+func applyRelease(t testing.TestingT, options *helm.Options, release helmRelease) (releaseAction, string, error) {
+
+	desired, err := releaseValues(release.ValuesFile, release.Values)
+	if err != nil {
+		return "", "", err
+	}
+
+	if isDryRun(options) {
+		logger.Log(t, fmt.Sprintf("SIMULATE release: %s, skipping lookup of the deployed release", release.Name))
+		output, err := runRelease(t, options, releaseUpgrade, release)
+		return releaseUpgrade, output, err
+	}
+
+	deployed, err := fetchRelease(t, options, release.Name, release.Namespace)
+	if err != nil {
+		return "", "", err
+	}
+
+	var deployedValues map[string]interface{}
+	if deployed != nil {
+		if deployedValues, err = fetchReleaseValues(t, options, release.Name, release.Namespace); err != nil {
+			return "", "", err
+		}
+	}
+
+	action := decideReleaseAction(deployed, deployedValues, release, desired)
+	logger.Log(t, fmt.Sprintf("release: %s in namespace: %s, action: %s", release.Name,
+		release.Namespace, action))
+
+	if action == releaseUnchanged {
+		return action, "", nil
+	}
+	output, err := runRelease(t, options, action, release)
+	return action, output, err
+}
+
+// decideReleaseAction compares a deployed release, nil when absent, and its user supplied values
+// to the desired release.
+func decideReleaseAction(deployed *deployedRelease, deployedValues map[string]interface{}, release helmRelease,
+	desired map[string]interface{}) releaseAction {
+
+	if deployed == nil {
+		return releaseInstall
+	}
+	if deployed.Status != releaseStatusDeployed {
+		return releaseUpgrade
+	}
+	if release.Version != "" && !isDeployedVersion(deployed, release) {
+		return releaseUpgrade
+	}
+	if !reflect.DeepEqual(flattenValues("", deployedValues), flattenValues("", desired)) {
+		return releaseUpgrade
+	}
+	return releaseUnchanged
+}
+
+func runRelease(t testing.TestingT, options *helm.Options, action releaseAction, release helmRelease) (string, error) {
+	var args []string
+	if action == releaseInstall {
+		args = []string{"install", release.Name, release.Chart}
+	} else {
+		args = []string{"upgrade", "--install", release.Name, release.Chart}
+	}
+
+	if release.Namespace != "" {
+		args = append(args, "-n", release.Namespace, "--create-namespace")
+	}
+	if release.Version != "" {
+		args = append(args, "--version", release.Version)
+	}
+	if release.ValuesFile != "" {
+		args = append(args, "-f", release.ValuesFile)
+	}
+	for _, key := range sortedKeys(release.Values) {
+		args = append(args, "--set", key+"="+release.Values[key])
+	}
+	if isDryRun(options) {
+		args = append(args, options.ExtraArgs["install"]...)
+	}
+	return executor.RunHelm(t, options, args...)
+}
+
+// fetchRelease provides the release of the namespace, or nil when not installed.
+func fetchRelease(t testing.TestingT, options *helm.Options, name string, namespace string) (*deployedRelease, error) {
+
+	args := []string{"list", "--all", "--filter", "^" + name + "$", "-o", "json"}
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+
+	output, err := executor.RunHelm(t, options, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list helm release: %s, error: %w", name, err)
+	}
+
+	var releases []deployedRelease
+	if err := json.Unmarshal([]byte(jsonOutput(output)), &releases); err != nil {
+		return nil, fmt.Errorf("unable to parse helm release: %s, error: %w", name, err)
+	}
+	for _, release := range releases {
+		if release.Name == name {
+			return &release, nil
+		}
+	}
+	return nil, nil
+}
+
+// fetchReleaseValues provides the user supplied values of a release.
+func fetchReleaseValues(t testing.TestingT, options *helm.Options, name string, namespace string) (map[string]interface{}, error) {
+
+	args := []string{"get", "values", name, "-o", "json"}
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+
+	output, err := executor.RunHelm(t, options, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch values of helm release: %s, error: %w", name, err)
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonOutput(output)), &values); err != nil {
+		return nil, fmt.Errorf("unable to parse values of helm release: %s, error: %w", name, err)
+	}
+	return values, nil
+}
+
+// releaseValues provides the values of the values file, overridden by the dotted keys of the set values.
+func releaseValues(valuesFile string, setValues map[string]string) (map[string]interface{}, error) {
+	var values map[string]interface{}
+	if valuesFile != "" {
+		content, err := os.ReadFile(valuesFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read helm values file: %s, error: %w", valuesFile, err)
+		}
+		if err := yaml.Unmarshal(content, &values); err != nil {
+			return nil, fmt.Errorf("unable to parse helm values file: %s, error: %w", valuesFile, err)
+		}
+	}
+
+	for key, value := range setValues {
+		if values == nil {
+			values = map[string]interface{}{}
+		}
+		nested := values
+		keys := strings.Split(key, ".")
+		for _, parent := range keys[:len(keys)-1] {
+			child, isMap := nested[parent].(map[string]interface{})
+			if !isMap {
+				child = map[string]interface{}{}
+				nested[parent] = child
+			}
+			nested = child
+		}
+		nested[keys[len(keys)-1]] = value
+	}
+	return values, nil
+}
+
+// flattenValues keys the leaf values by their dotted path, formatting them so that values parsed from
+// YAML and JSON, or set as strings, compare equal.
+func flattenValues(prefix string, values map[string]interface{}) map[string]string {
+	var flattened = map[string]string{}
+	for key, value := range values {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+
+		if nested, isMap := value.(map[string]interface{}); isMap {
+			for nestedKey, nestedValue := range flattenValues(name, nested) {
+				flattened[nestedKey] = nestedValue
+			}
+			continue
+		}
+
+		if value == nil {
+			continue
+		}
+		if text, isString := value.(string); isString {
+			flattened[name] = text
+			continue
+		}
+		formatted, _ := json.Marshal(value)
+		flattened[name] = string(formatted)
+	}
+	return flattened
+}
+
+// jsonOutput provides the last line of the output, as helm prints its warnings ahead of the JSON document.
+func jsonOutput(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return last
+	}
+	return "null"
+}
+
+// isDeployedVersion indicates the deployed chart is of the release version, e.g. the traefik-10.3.2 chart of
+// version v10.3.2, a leading v being ignored on both sides.
+func isDeployedVersion(deployed *deployedRelease, release helmRelease) bool {
+	prefix := chartName(release.Chart) + "-"
+	if !strings.HasPrefix(deployed.Chart, prefix) {
+		return false
+	}
+	deployedVersion := strings.TrimPrefix(deployed.Chart, prefix)
+	return strings.TrimPrefix(deployedVersion, "v") == strings.TrimPrefix(release.Version, "v")
+}
+
+// chartName removes the repository of a chart reference, e.g. k8ssandra/k8ssandra-operator.
+func chartName(chart string) string {
+	return chart[strings.LastIndex(chart, "/")+1:]
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isDryRun(options *helm.Options) bool {
+	return slices.Contains(options.ExtraArgs["install"], helmInstallDryRun)
+}
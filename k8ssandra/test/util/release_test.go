@@ -0,0 +1,176 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"context"
+	"errors"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"path"
+	"testing"
+)
+
+const deployedOperator = `[{"name":"k8ssandra-operator","namespace":"bootz","revision":"1","status":"deployed",` +
+	`"chart":"k8ssandra-operator-0.38.0","app_version":"1.2.0"}]`
+
+func TestDecideReleaseAction(t *testing.T) {
+	release := helmRelease{Name: "traefik", Chart: "traefik/traefik", Version: "10.3.2"}
+	deployed := &deployedRelease{Name: "traefik", Status: "deployed", Chart: "traefik-10.3.2"}
+	desired := map[string]interface{}{"ports": map[string]interface{}{"web": map[string]interface{}{"nodePort": uint64(30080)}},
+		"service": map[string]interface{}{"type": "NodePort"}}
+	current := map[string]interface{}{"ports": map[string]interface{}{"web": map[string]interface{}{"nodePort": float64(30080)}},
+		"service": map[string]interface{}{"type": "NodePort"}}
+
+	require.Equal(t, releaseInstall, decideReleaseAction(nil, nil, release, desired))
+	require.Equal(t, releaseUnchanged, decideReleaseAction(deployed, current, release, desired))
+	require.Equal(t, releaseUnchanged, decideReleaseAction(deployed, nil, helmRelease{Chart: "traefik/traefik"}, nil))
+
+	require.Equal(t, releaseUpgrade, decideReleaseAction(deployed, current,
+		helmRelease{Chart: "traefik/traefik", Version: "10.4.0"}, desired))
+	require.Equal(t, releaseUpgrade, decideReleaseAction(deployed, nil, release, desired))
+	require.Equal(t, releaseUpgrade, decideReleaseAction(&deployedRelease{Status: "failed", Chart: "traefik-10.3.2"},
+		current, release, desired))
+
+	// A v-prefixed version, e.g. the default traefik version, matches the chart listed by helm without it.
+	require.Equal(t, releaseUnchanged, decideReleaseAction(deployed, current,
+		helmRelease{Chart: "traefik/traefik", Version: "v10.3.2"}, desired))
+	require.Equal(t, releaseUnchanged, decideReleaseAction(&deployedRelease{Status: "deployed",
+		Chart: "cert-manager-v1.7.1"}, nil, helmRelease{Chart: "jetstack/cert-manager", Version: "1.7.1"}, nil))
+	require.Equal(t, releaseUpgrade, decideReleaseAction(deployed, current,
+		helmRelease{Chart: "traefik/traefik", Version: "v10.4.0"}, desired))
+}
+
+func TestApplyRelease(t *testing.T) {
+	valuesFile := path.Join(t.TempDir(), "values.yaml")
+	require.NoError(t, os.WriteFile(valuesFile, []byte("service:\n  type: NodePort\n"), defaultTempFilePerm))
+	options := createHelmOptions(k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz"),
+		map[string]string{}, map[string]string{}, false)
+	release := helmRelease{Name: defaultK8ssandraOperatorReleaseName, Chart: defaultK8ssandraOperatorChart,
+		Namespace: "bootz", ValuesFile: valuesFile}
+
+	fake := executor.NewFake().Respond(executor.Helm, []string{"list"}, "[]", nil)
+	action, _, err := applyRelease(executor.With(t, fake), options, release)
+	require.NoError(t, err)
+	require.Equal(t, releaseInstall, action)
+	installs := fake.CommandsOf(executor.Helm, "install")
+	require.Len(t, installs, 1)
+	require.Equal(t, []string{"install", defaultK8ssandraOperatorReleaseName, defaultK8ssandraOperatorChart,
+		"-n", "bootz", "--create-namespace", "-f", valuesFile}, installs[0].Args)
+
+	fake = executor.NewFake().
+		Respond(executor.Helm, []string{"list"}, "WARNING: kube config is group-readable\n"+deployedOperator, nil).
+		Respond(executor.Helm, []string{"get", "values"}, `{"service":{"type":"NodePort"}}`, nil)
+	action, _, err = applyRelease(executor.With(t, fake), options, release)
+	require.NoError(t, err)
+	require.Equal(t, releaseUnchanged, action)
+	require.Empty(t, fake.CommandsOf(executor.Helm, "install"))
+	require.Empty(t, fake.CommandsOf(executor.Helm, "upgrade"))
+	require.Empty(t, fake.CommandsOf(executor.Helm, "uninstall"))
+
+	fake = executor.NewFake().
+		Respond(executor.Helm, []string{"list"}, deployedOperator, nil).
+		Respond(executor.Helm, []string{"get", "values"}, `{"service":{"type":"LoadBalancer"}}`, nil)
+	action, _, err = applyRelease(executor.With(t, fake), options, release)
+	require.NoError(t, err)
+	require.Equal(t, releaseUpgrade, action)
+	upgrades := fake.CommandsOf(executor.Helm, "upgrade")
+	require.Len(t, upgrades, 1)
+	require.Equal(t, []string{"upgrade", "--install", defaultK8ssandraOperatorReleaseName,
+		defaultK8ssandraOperatorChart, "-n", "bootz", "--create-namespace", "-f", valuesFile}, upgrades[0].Args)
+	require.Empty(t, fake.CommandsOf(executor.Helm, "uninstall"))
+
+	fake = executor.NewFake().Respond(executor.Helm, []string{"list"}, "", errors.New("cluster unreachable"))
+	_, _, err = applyRelease(executor.With(t, fake), options, release)
+	require.Error(t, err)
+}
+
+func TestApplyReleaseSimulated(t *testing.T) {
+	fake := executor.NewFake()
+	options := createHelmOptions(k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz"),
+		map[string]string{}, map[string]string{}, true)
+
+	action, _, err := applyRelease(executor.With(t, fake), options, helmRelease{Name: defaultK8ssandraOperatorReleaseName,
+		Chart: defaultK8ssandraOperatorChart, Namespace: "bootz"})
+	require.NoError(t, err)
+	require.Equal(t, releaseUpgrade, action)
+	require.Empty(t, fake.CommandsOf(executor.Helm, "list"), "expecting no release lookup when simulated")
+	upgrades := fake.CommandsOf(executor.Helm, "upgrade")
+	require.Len(t, upgrades, 1)
+	require.Contains(t, upgrades[0].Args, helmInstallDryRun)
+}
+
+func TestInstallK8ssandraOperatorDataPlane(t *testing.T) {
+	deployment := operatorDeployment()
+	deployment.Spec.Template.Spec.Containers[0].Env = append(deployment.Spec.Template.Spec.Containers[0].Env,
+		corev1.EnvVar{Name: defaultControlPlaneKey, Value: "false"})
+	client := useFakeClient(t, deployment, operatorPod("k8ssandra-operator-7f9c", corev1.PodRunning))
+	fake := executor.NewFake().
+		Respond(executor.Helm, []string{"list"}, deployedOperator, nil).
+		Respond(executor.Helm, []string{"get", "values"}, "null", nil)
+	options := createHelmOptions(k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz"),
+		map[string]string{}, map[string]string{}, false)
+
+	installK8ssandraOperator(executor.With(t, fake), options, "kind", "bootz", model.ComponentVersion{Chart: defaultK8ssandraOperatorChart},
+		false, false)
+
+	require.Empty(t, fake.CommandsOf(executor.Helm, "install"))
+	require.Empty(t, fake.CommandsOf(executor.Helm, "upgrade"))
+	pods, err := client.CoreV1().Pods("bootz").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, pods.Items, 1, "expecting the running operator pod not to be restarted")
+}
+
+func TestInstallK8ssandraOperatorPatchesDataPlane(t *testing.T) {
+	client := useFakeClient(t, operatorDeployment(), operatorPod("k8ssandra-operator-7f9c", corev1.PodRunning))
+	fake := executor.NewFake().Respond(executor.Helm, []string{"list"}, "[]", nil)
+	options := createHelmOptions(k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz"),
+		map[string]string{}, map[string]string{}, false)
+
+	installK8ssandraOperator(executor.With(t, fake), options, "kind", "bootz", model.ComponentVersion{Chart: defaultK8ssandraOperatorChart},
+		false, false)
+
+	require.Len(t, fake.CommandsOf(executor.Helm, "install"), 1)
+	require.True(t, isDataPlaneOperator(t, options.KubectlOptions, "bootz"))
+	pods, err := client.CoreV1().Pods("bootz").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, pods.Items, "expecting the operator pod to be restarted")
+}
+
+func TestReleaseValues(t *testing.T) {
+	valuesFile := path.Join(t.TempDir(), "values.yaml")
+	require.NoError(t, os.WriteFile(valuesFile, []byte("global:\n  clusterScoped: false\nimage:\n  tag: v1.2.0\n"),
+		defaultTempFilePerm))
+
+	values, err := releaseValues(valuesFile, map[string]string{"global.clusterScoped": "true", "replicaCount": "2"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"global.clusterScoped": "true", "image.tag": "v1.2.0", "replicaCount": "2"},
+		flattenValues("", values))
+
+	deployed := map[string]interface{}{"global": map[string]interface{}{"clusterScoped": true},
+		"image": map[string]interface{}{"tag": "v1.2.0"}, "replicaCount": float64(2)}
+	require.Equal(t, flattenValues("", deployed), flattenValues("", values),
+		"expecting the values set as strings to match the values parsed by helm")
+
+	_, err = releaseValues(path.Join(t.TempDir(), "missing.yaml"), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"sync"
+	gotesting "testing"
+	"time"
+)
+
+// Runner is a testing.TestingT able to run nested activities, driving the phases outside of the go test
+// framework, e.g. from the cloud-readiness command.  A *testing.T is used as is.
+type Runner interface {
+	testing.TestingT
+
+	// Run runs the activity as a nested activity named after the runner, providing whether it succeeded.  Run is
+	// called concurrently by parallel activities.
+	Run(name string, activity func(t testing.TestingT)) bool
+
+	// Failed indicates the runner or any of its nested activities failed.
+	Failed() bool
+}
+
+// runSubtest runs the activity as a subtest of t, or in place when t is unable to run subtests.  The subtest
+// carries the executor of t.
+func runSubtest(t testing.TestingT, name string, activity func(t testing.TestingT)) bool {
+	switch runner := executor.Unwrap(t).(type) {
+	case *gotesting.T:
+		return runner.Run(name, func(subtest *gotesting.T) {
+			activity(executor.Inherit(t, subtest))
+		})
+	case Runner:
+		return runner.Run(name, func(subtest testing.TestingT) {
+			activity(executor.Inherit(t, subtest))
+		})
+	}
+	activity(t)
+	return !isFailed(t)
+}
+
+// runParallelSubtests runs the activity for each name as parallel subtests of t.  With a *testing.T the
+// subtests complete once the calling test returns, other runners complete them before returning.
+func runParallelSubtests(t testing.TestingT, names []string, activity func(t testing.TestingT, name string)) {
+	if goT, ok := executor.Unwrap(t).(*gotesting.T); ok {
+		for _, name := range names {
+			name := name
+			goT.Run(name, func(subtest *gotesting.T) {
+				subtest.Parallel()
+				activity(executor.Inherit(t, subtest), name)
+			})
+		}
+		return
+	}
+
+	var group sync.WaitGroup
+	for _, name := range names {
+		group.Add(1)
+		go func(name string) {
+			defer group.Done()
+			runSubtest(t, name, func(t testing.TestingT) {
+				activity(t, name)
+			})
+		}(name)
+	}
+	group.Wait()
+}
+
+// isFailed indicates t reports a failure, false when t does not track failures.
+func isFailed(t testing.TestingT) bool {
+	if failing, ok := executor.Unwrap(t).(interface{ Failed() bool }); ok {
+		return failing.Failed()
+	}
+	return false
+}
+
+// deadline provides the time t times out, when known.
+func deadline(t testing.TestingT) (time.Time, bool) {
+	if limited, ok := executor.Unwrap(t).(interface{ Deadline() (time.Time, bool) }); ok {
+		return limited.Deadline()
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,87 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"github.com/stretchr/testify/require"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// recordingRunner runs the nested activities in place with itself, recording their names.
+type recordingRunner struct {
+	*testing.T
+	mutex sync.Mutex
+	names []string
+}
+
+func (r *recordingRunner) Run(name string, activity func(t terratesting.TestingT)) bool {
+	r.mutex.Lock()
+	r.names = append(r.names, name)
+	r.mutex.Unlock()
+	activity(r)
+	return true
+}
+
+func TestRunSubtest(t *testing.T) {
+	var name string
+	require.True(t, runSubtest(t, "provision", func(t terratesting.TestingT) {
+		name = t.Name()
+	}))
+	require.Equal(t, "TestRunSubtest/provision", name)
+
+	runner := &recordingRunner{T: t}
+	require.True(t, runSubtest(runner, "provision", func(t terratesting.TestingT) {}))
+	require.Equal(t, []string{"provision"}, runner.names)
+}
+
+func TestRunParallelSubtestsWithRunner(t *testing.T) {
+	runner := &recordingRunner{T: t}
+	var applied []string
+	var mutex sync.Mutex
+	runParallelSubtests(runner, []string{"central", "east"}, func(t terratesting.TestingT, name string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		applied = append(applied, name)
+	})
+
+	sort.Strings(runner.names)
+	sort.Strings(applied)
+	require.Equal(t, []string{"central", "east"}, runner.names)
+	require.Equal(t, []string{"central", "east"}, applied, "expecting every subtest completed on return")
+}
+
+func TestParallelSubtestsKeepTheirExecutor(t *testing.T) {
+	fakes := map[string]*executor.Fake{"central": executor.NewFake(), "east": executor.NewFake()}
+	runParallelSubtests(t, []string{"central", "east"}, func(t terratesting.TestingT, name string) {
+		runSubtest(executor.With(t, fakes[name]), "install", func(t terratesting.TestingT) {
+			_, err := executor.RunKubectl(t, nil, "get", "pods", name)
+			require.NoError(t, err)
+		})
+	})
+
+	t.Cleanup(func() {
+		for name, fake := range fakes {
+			commands := fake.Commands()
+			require.Len(t, commands, 1, "expecting the commands of context: %s only", name)
+			require.Equal(t, name, commands[0].Args[2])
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"context"
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+	"time"
+)
+
+const (
+	CheckClientToken = "client-token"
+
+	defaultTokenExpirationSecs = int64(24 * 60 * 60)
+	minimumTokenExpirationSecs = int64(10 * 60)
+	defaultTokenSecretSuffix   = "-token"
+	defaultTokenWaitTimeout    = time.Second * 30
+)
+
+// tokenRequestVersion is the first Kubernetes version no longer creating a token secret for a service account.
+var tokenRequestVersion = version.MustParseGeneric("1.24.0")
+
+// ResolveTokenMode provides the token mode applied to a context, resolving the auto mode from the server version.
+func ResolveTokenMode(t testing.TestingT, options *k8s.KubectlOptions, mode model.TokenMode) model.TokenMode {
+	if mode != "" && mode != model.TokenModeAuto {
+		return mode
+	}
+
+	info, err := KubeClient(t, options).Discovery().ServerVersion()
+	if err != nil {
+		logger.Log(t, fmt.Sprintf("WARNING: unable to obtain the server version, requesting a bound token: %s", err))
+		return model.TokenModeRequest
+	}
+
+	serverVersion, parseErr := version.ParseGeneric(info.GitVersion)
+	if parseErr != nil || serverVersion.AtLeast(tokenRequestVersion) {
+		return model.TokenModeRequest
+	}
+	return model.TokenModeLegacy
+}
+
+// RequestToken mints a bound token for the service account through the TokenRequest API, providing the token
+// along with its expiry.
+func RequestToken(t testing.TestingT, options *k8s.KubectlOptions, serviceAccount string, namespace string,
+	expirationSecs int64) (string, time.Time) {
+
+	if expirationSecs <= 0 {
+		expirationSecs = defaultTokenExpirationSecs
+	}
+
+	requestedAt := time.Now()
+	request := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &expirationSecs},
+	}
+	response, err := KubeClient(t, options).CoreV1().ServiceAccounts(namespace).CreateToken(context.Background(),
+		serviceAccount, request, metav1.CreateOptions{})
+	require.NoError(t, err, fmt.Sprintf("expecting a token to be requested for service account: %s", serviceAccount))
+	require.NotEmpty(t, response.Status.Token, fmt.Sprintf("expecting a token for service account: %s", serviceAccount))
+
+	expiresAt := response.Status.ExpirationTimestamp.Time
+	if expiresAt.IsZero() {
+		expiresAt = requestedAt.Add(time.Duration(expirationSecs) * time.Second)
+	}
+	logger.Log(t, fmt.Sprintf("token requested for service account: %s expiring at: %s", serviceAccount,
+		expiresAt.UTC().Format(time.RFC3339)))
+	return response.Status.Token, expiresAt.UTC()
+}
+
+// CreateTokenSecret creates a kubernetes.io/service-account-token secret for the service account, providing the
+// secret name once populated by the token controller.
+func CreateTokenSecret(t testing.TestingT, options *k8s.KubectlOptions, serviceAccount string, namespace string) string {
+
+	name := serviceAccount + defaultTokenSecretSuffix
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Annotations: map[string]string{corev1.ServiceAccountNameKey: serviceAccount},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}
+
+	secrets := KubeClient(t, options).CoreV1().Secrets(namespace)
+	_, err := secrets.Create(context.Background(), secret, metav1.CreateOptions{})
+	if err != nil {
+		_, getErr := secrets.Get(context.Background(), name, metav1.GetOptions{})
+		require.NoError(t, getErr, fmt.Sprintf("expecting token secret: %s to be created: %v", name, err))
+	}
+
+	require.Eventually(t, func() bool {
+		found, getErr := secrets.Get(context.Background(), name, metav1.GetOptions{})
+		return getErr == nil && len(found.Data[corev1.ServiceAccountTokenKey]) > 0
+	}, defaultTokenWaitTimeout, defaultInterval, fmt.Sprintf("timeout waiting for token secret: %s", name))
+	return name
+}
+
+// serviceAccountToken is the token of a service account, along with the secret and certificate it was read
+// from, or the expiry of a requested token.
+type serviceAccountToken struct {
+	secret    string
+	token     string
+	cert      []byte
+	expiresAt *time.Time
+}
+
+// fetchServiceAccountToken provides the token of the service account using the resolved token mode.
+func fetchServiceAccountToken(t testing.TestingT, options *k8s.KubectlOptions, serviceAccount string, namespace string,
+	mode model.TokenMode, expirationSecs int64) serviceAccountToken {
+
+	logger.Log(t, fmt.Sprintf("service account token mode: %s for: %s", mode, options.ContextName))
+
+	var secret string
+	switch mode {
+	case model.TokenModeRequest:
+		token, expiresAt := RequestToken(t, options, serviceAccount, namespace, expirationSecs)
+		return serviceAccountToken{token: token, expiresAt: &expiresAt}
+	case model.TokenModeSecret:
+		secret = CreateTokenSecret(t, options, serviceAccount, namespace)
+	case model.TokenModeLegacy:
+		// The token controller references the secret of a service account shortly after its creation.
+		require.Eventually(t, func() bool {
+			found, err := KubeClient(t, options).CoreV1().ServiceAccounts(namespace).Get(context.Background(),
+				serviceAccount, metav1.GetOptions{})
+			return err == nil && len(found.Secrets) > 0
+		}, defaultTokenWaitTimeout, defaultInterval, fmt.Sprintf("timeout waiting for the token secret of: %s",
+			serviceAccount))
+		secret = FetchSecret(t, options, serviceAccount, namespace)
+	default:
+		require.FailNow(t, fmt.Sprintf("unknown service account token mode: %s", mode))
+	}
+
+	cert, _ := FetchCertificate(t, options, secret, namespace)
+	return serviceAccountToken{secret: secret, token: FetchToken(t, options, secret, namespace), cert: cert}
+}
+
+// checkClientTokens expects the requested tokens of the client access recorded by the install phase to outlast
+// the timeout of the checks, as the k8s-contexts secret holds them without refreshing them.
+func checkClientTokens(meta model.ProvisionMeta, timeoutSecs int) []model.CheckResult {
+	accesses, err := ReadClientAccess(meta)
+	if err != nil {
+		return nil
+	}
+
+	var results []model.CheckResult
+	deadline := time.Now().Add(time.Duration(timeoutSecs) * time.Second)
+	for _, access := range accesses {
+		if access.TokenExpiresAt == nil {
+			continue
+		}
+		expiresAt := access.TokenExpiresAt.UTC().Format(time.RFC3339)
+		if access.TokenExpiresAt.Before(deadline) {
+			results = append(results, checkResult(access.Context, CheckClientToken, access.ServiceAccount, false,
+				fmt.Sprintf("token expires at: %s within the timeout of %ds, apply the install phase again to "+
+					"request a new token", expiresAt, timeoutSecs)))
+			continue
+		}
+		results = append(results, checkResult(access.Context, CheckClientToken, access.ServiceAccount, true,
+			"token expires at: "+expiresAt))
+	}
+	return results
+}
+
+// validateServiceAccountToken expects a known token mode, and an expiry accepted by the TokenRequest API.
+func validateServiceAccountToken(readinessConfig model.ReadinessConfig) []ValidationError {
+	tokenConfig := readinessConfig.ProvisionConfig.K8cConfig.ServiceAccountToken
+
+	var validationErrors []ValidationError
+	switch tokenConfig.Mode {
+	case "", model.TokenModeAuto, model.TokenModeRequest, model.TokenModeSecret, model.TokenModeLegacy:
+	default:
+		validationErrors = append(validationErrors, ValidationError{Field: "k8c_config.service_account_token.mode",
+			Message: fmt.Sprintf("unknown token mode: %s, expecting one of: %s, %s, %s or %s", tokenConfig.Mode,
+				model.TokenModeAuto, model.TokenModeRequest, model.TokenModeSecret, model.TokenModeLegacy)})
+	}
+
+	if tokenConfig.ExpirationSeconds < 0 ||
+		(tokenConfig.ExpirationSeconds > 0 && tokenConfig.ExpirationSeconds < minimumTokenExpirationSecs) {
+		validationErrors = append(validationErrors, ValidationError{
+			Field: "k8c_config.service_account_token.expiration_seconds",
+			Message: fmt.Sprintf("expiration of %ds is below the minimum of %ds of a requested token",
+				tokenConfig.ExpirationSeconds, minimumTokenExpirationSecs)})
+	}
+	return validationErrors
+}
@@ -0,0 +1,192 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"testing"
+	"time"
+)
+
+func useFakeServerVersion(client *fake.Clientset, gitVersion string) {
+	client.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: gitVersion}
+}
+
+// respondTokenRequest mints the token of every TokenRequest, recording the requested expiry.
+func respondTokenRequest(client *fake.Clientset, expirationSecs *int64) {
+	client.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		request := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenRequest)
+		*expirationSecs = *request.Spec.ExpirationSeconds
+		return true, &authenticationv1.TokenRequest{Status: authenticationv1.TokenRequestStatus{Token: "bound-token"}}, nil
+	})
+}
+
+func clientServiceAccount(secrets ...string) *corev1.ServiceAccount {
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: defaultClientServiceAccountName, Namespace: "bootz"}}
+	for _, secret := range secrets {
+		sa.Secrets = append(sa.Secrets, corev1.ObjectReference{Name: secret})
+	}
+	return sa
+}
+
+func contextOption() model.ContextOption {
+	return model.ContextOption{ShortName: "kind", FullName: "kind-k8ssandra-0",
+		ServiceAccount: &model.ContextServiceAccount{Cert: []byte("cluster-ca")}}
+}
+
+func TestResolveTokenMode(t *testing.T) {
+	client := useFakeClient(t)
+	options := k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz")
+
+	require.Equal(t, model.TokenModeSecret, ResolveTokenMode(t, options, model.TokenModeSecret))
+
+	useFakeServerVersion(client, "v1.23.14-gke.1800")
+	require.Equal(t, model.TokenModeLegacy, ResolveTokenMode(t, options, ""))
+
+	useFakeServerVersion(client, "v1.24.0")
+	require.Equal(t, model.TokenModeRequest, ResolveTokenMode(t, options, model.TokenModeAuto))
+
+	useFakeServerVersion(client, "v1.27.3-gke.100")
+	require.Equal(t, model.TokenModeRequest, ResolveTokenMode(t, options, ""))
+}
+
+func TestAddServiceAccountRequestedToken(t *testing.T) {
+	client := useFakeClient(t, clientServiceAccount())
+	useFakeServerVersion(client, "v1.25.1")
+	var expirationSecs int64
+	respondTokenRequest(client, &expirationSecs)
+	ctxOption := contextOption()
+
+	AddServiceAccount(t, ctxOption, "bootz", k8s.NewKubectlOptions("kind-k8ssandra-0", "", ""),
+		model.K8cConfig{})
+
+	require.Equal(t, "bound-token", ctxOption.ServiceAccount.Token)
+	require.Empty(t, ctxOption.ServiceAccount.Secret)
+	require.Equal(t, []byte("cluster-ca"), ctxOption.ServiceAccount.Cert)
+	require.Equal(t, defaultTokenExpirationSecs, expirationSecs)
+
+	requestedAt := time.Now()
+	_, expiresAt := RequestToken(t, k8s.NewKubectlOptions("kind-k8ssandra-0", "", ""),
+		defaultK8ssandraOperatorReleaseName, "bootz", 3600)
+	require.Equal(t, int64(3600), expirationSecs)
+	require.WithinDuration(t, requestedAt.Add(time.Hour), expiresAt, time.Minute)
+}
+
+func TestAddServiceAccountResolvesModeOnce(t *testing.T) {
+	client := useFakeClient(t, clientServiceAccount())
+	useFakeServerVersion(client, "v1.25.1")
+	var expirationSecs int64
+	respondTokenRequest(client, &expirationSecs)
+
+	access := AddServiceAccount(t, contextOption(), "bootz", k8s.NewKubectlOptions("kind-k8ssandra-0", "", ""),
+		model.K8cConfig{ServiceAccountToken: model.ServiceAccountTokenConfig{ExpirationSeconds: 7200}})
+	require.Equal(t, model.TokenModeRequest, access.TokenMode)
+	require.NotNil(t, access.TokenExpiresAt)
+	require.WithinDuration(t, time.Now().Add(2*time.Hour), *access.TokenExpiresAt, time.Minute)
+
+	var versionLookups int
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "get" && action.GetResource().Resource == "version" {
+			versionLookups++
+		}
+	}
+	require.Equal(t, 1, versionLookups, "expecting the server version to be looked up once")
+}
+
+func TestCheckClientTokens(t *testing.T) {
+	meta := model.ProvisionMeta{ArtifactsRootDir: t.TempDir()}
+	require.Empty(t, checkClientTokens(meta, 600), "expecting no check without a recorded client access")
+
+	expiring := time.Now().Add(5 * time.Minute)
+	lasting := time.Now().Add(24 * time.Hour)
+	_, err := WriteClientAccess(meta, []model.ClientAccess{
+		{Context: "kind-k8ssandra-0", ServiceAccount: defaultClientServiceAccountName, TokenExpiresAt: &expiring},
+		{Context: "kind-k8ssandra-1", ServiceAccount: defaultClientServiceAccountName, TokenExpiresAt: &lasting},
+		{Context: "kind-k8ssandra-2", ServiceAccount: defaultClientServiceAccountName},
+	})
+	require.NoError(t, err)
+
+	results := checkClientTokens(meta, 600)
+	require.Equal(t, []string{"client-token/k8ssandra-client/failed", "client-token/k8ssandra-client/passed"},
+		checkNames(results))
+	require.Contains(t, results[0].Message, "apply the install phase again")
+}
+
+func TestValidateServiceAccountToken(t *testing.T) {
+	config := model.ReadinessConfig{Contexts: validContexts()}
+	config.ProvisionConfig.K8cConfig.ServiceAccountToken = model.ServiceAccountTokenConfig{
+		Mode: model.TokenModeRequest, ExpirationSeconds: 3600}
+	require.Empty(t, Validate(config))
+
+	config.ProvisionConfig.K8cConfig.ServiceAccountToken = model.ServiceAccountTokenConfig{Mode: "bound",
+		ExpirationSeconds: 60}
+	require.Equal(t, []string{"/k8c_config.service_account_token.mode",
+		"/k8c_config.service_account_token.expiration_seconds"}, fields(Validate(config)))
+}
+
+func TestAddServiceAccountTokenSecret(t *testing.T) {
+	client := useFakeClient(t, clientServiceAccount())
+
+	// Stands in for the token controller populating the secret.
+	client.PrependReactor("create", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		secret := action.(k8stesting.CreateAction).GetObject().(*corev1.Secret)
+		if secret.Type == corev1.SecretTypeServiceAccountToken {
+			secret.Data = map[string][]byte{corev1.ServiceAccountTokenKey: []byte("secret-token"),
+				corev1.ServiceAccountRootCAKey: []byte("secret-ca")}
+		}
+		return false, nil, nil
+	})
+	ctxOption := contextOption()
+
+	AddServiceAccount(t, ctxOption, "bootz", k8s.NewKubectlOptions("kind-k8ssandra-0", "", ""),
+		model.K8cConfig{ServiceAccountToken: model.ServiceAccountTokenConfig{Mode: model.TokenModeSecret}})
+
+	require.Equal(t, defaultClientServiceAccountName+defaultTokenSecretSuffix, ctxOption.ServiceAccount.Secret)
+	require.Equal(t, "secret-token", ctxOption.ServiceAccount.Token)
+	require.Equal(t, []byte("secret-ca"), ctxOption.ServiceAccount.Cert)
+}
+
+func TestAddServiceAccountLegacy(t *testing.T) {
+	client := useFakeClient(t, clientServiceAccount("k8ssandra-client-token-x7z"),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "k8ssandra-client-token-x7z", Namespace: "bootz"},
+			Data:       map[string][]byte{corev1.ServiceAccountTokenKey: []byte("legacy-token")},
+		})
+	useFakeServerVersion(client, "v1.22.8")
+	ctxOption := contextOption()
+
+	AddServiceAccount(t, ctxOption, "bootz", k8s.NewKubectlOptions("kind-k8ssandra-0", "", ""),
+		model.K8cConfig{})
+
+	require.Equal(t, "k8ssandra-client-token-x7z", ctxOption.ServiceAccount.Secret)
+	require.Equal(t, "legacy-token", ctxOption.ServiceAccount.Token)
+	require.Equal(t, []byte("cluster-ca"), ctxOption.ServiceAccount.Cert,
+		"expecting the cluster certificate when the secret has none")
+}
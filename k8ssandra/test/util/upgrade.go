@@ -0,0 +1,329 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultUpgradeReportFileName = "upgrade-report.json"
+	defaultQuorumIntervalSecs    = 5
+	cassandraDatacenterLabel     = "cassandra.datastax.com/datacenter"
+)
+
+// UpgradeOperators verifies the installation, then upgrades the k8ssandra-operator of every context to the
+// configured version, in the upgrade order, while the quorum of the Cassandra datacenters is sampled. The
+// installation is verified again once every context is upgraded, and no datacenter is expected to have lost
+// quorum.
+func UpgradeOperators(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) model.UpgradeReport {
+
+	upgrade := readinessConfig.ProvisionConfig.Upgrade
+	require.NotEmpty(t, upgrade.ToVersion, "expecting a version to upgrade the k8ssandra-operator to")
+
+	component := ResolveVersions(readinessConfig.ProvisionConfig).K8ssandraOperator
+	report := model.UpgradeReport{FromVersion: component.Version, ToVersion: upgrade.ToVersion,
+		Contexts: ResolveUpgradeOrder(readinessConfig), StartedAt: time.Now()}
+	component.Version = upgrade.ToVersion
+
+	if meta.Enable.Simulate {
+		for _, name := range report.Contexts {
+			logger.Log(t, fmt.Sprintf("SIMULATE upgrade of k8ssandra-operator for: %s from: %s to: %s",
+				name, stringOrDefault(report.FromVersion, "latest"), report.ToVersion))
+		}
+		return report
+	}
+
+	logger.Log(t, fmt.Sprintf("verifying the installation before upgrading k8ssandra-operator from: %s",
+		stringOrDefault(report.FromVersion, "latest")))
+	VerifyInstallation(t, meta, readinessConfig)
+
+	identity := FetchEnv(t, meta.AdminIdentity)
+	timeoutSecs := valueOrDefault(readinessConfig.ProvisionConfig.DefaultTimeoutSecs, defaultTimeoutSecs)
+	isClusterScoped := readinessConfig.ProvisionConfig.K8cConfig.ClusterScoped
+
+	var kubeConfigs = map[string]*k8s.KubectlOptions{}
+	var clients = map[string]kubernetes.Interface{}
+	var namespaces = map[string]string{}
+	for _, name := range report.Contexts {
+		ctx := readinessConfig.Contexts[name]
+		kubeConfigs[name] = ConnectContext(t, meta, identity, name, ctx)
+		clients[name] = KubeClient(t, kubeConfigs[name])
+		namespaces[name] = ctx.Namespace
+	}
+
+	interval := time.Duration(valueOrDefault(upgrade.QuorumIntervalSecs, defaultQuorumIntervalSecs)) * time.Second
+	monitor := startQuorumMonitor(t, clients, namespaces, interval)
+	defer monitor.halt()
+
+	for _, name := range report.Contexts {
+		ctx := readinessConfig.Contexts[name]
+		kubeConfig := kubeConfigs[name]
+		SetCurrentContext(t, kubeConfig.ContextName, kubeConfig)
+
+		isControlPlane := IsControlPlane(ctx)
+		helmOptions := createHelmOptions(kubeConfig, map[string]string{
+			defaultControlPlaneKey: strconv.FormatBool(isControlPlane)}, kubeConfig.Env, false)
+
+		logger.Log(t, fmt.Sprintf("upgrading k8ssandra-operator for: %s to: %s", name, report.ToVersion))
+		installK8ssandraOperator(t, helmOptions, ctx.Name, ctx.Namespace, component, isClusterScoped, isControlPlane)
+		verifyOperatorRollout(t, kubeConfig, name, ctx.Namespace, timeoutSecs)
+	}
+
+	report.Samples, report.QuorumLosses = monitor.Stop(t)
+	report.CompletedAt = time.Now()
+
+	reportPath, err := WriteUpgradeReport(meta, report)
+	if err != nil {
+		logger.Log(t, fmt.Sprintf("WARNING: unable to write the upgrade report: %s", err.Error()))
+	} else {
+		logger.Log(t, fmt.Sprintf("upgrade report written to: %s", reportPath))
+	}
+
+	VerifyInstallation(t, meta, readinessConfig)
+	require.Empty(t, report.QuorumLosses, "expecting no Cassandra datacenter to lose quorum during the upgrade")
+	return report
+}
+
+// ResolveUpgradeOrder provides the contexts in the order upgraded, the explicit context order when provided,
+// otherwise the control-plane before or after the data-planes, sorted by name.
+func ResolveUpgradeOrder(readinessConfig model.ReadinessConfig) []string {
+	upgrade := readinessConfig.ProvisionConfig.Upgrade
+	if len(upgrade.ContextOrder) > 0 {
+		return upgrade.ContextOrder
+	}
+
+	var controlPlanes []string
+	var dataPlanes []string
+	for _, name := range sortedContextNames(readinessConfig) {
+		if IsControlPlane(readinessConfig.Contexts[name]) {
+			controlPlanes = append(controlPlanes, name)
+		} else {
+			dataPlanes = append(dataPlanes, name)
+		}
+	}
+
+	if upgrade.Order == model.UpgradeDataPlanesFirst {
+		return append(dataPlanes, controlPlanes...)
+	}
+	return append(controlPlanes, dataPlanes...)
+}
+
+// WriteUpgradeReport writes the upgrade report to the artifacts root, providing its path.
+func WriteUpgradeReport(meta model.ProvisionMeta, report model.UpgradeReport) (string, error) {
+	if meta.ArtifactsRootDir == "" {
+		return "", errors.New("an artifacts root directory is required to write the upgrade report")
+	}
+	if err := os.MkdirAll(meta.ArtifactsRootDir, defaultTempFilePerm); err != nil {
+		return "", err
+	}
+
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	reportPath := path.Join(meta.ArtifactsRootDir, defaultUpgradeReportFileName)
+	return reportPath, ioutil.WriteFile(reportPath, content, defaultTempFilePerm)
+}
+
+// quorumMonitor samples the ready Cassandra nodes of every datacenter in the background, recording the
+// samples of the datacenters without quorum.
+type quorumMonitor struct {
+	clients    map[string]kubernetes.Interface
+	namespaces map[string]string
+	stop       chan struct{}
+	done       chan struct{}
+	halted     sync.Once
+	samples    int
+	losses     []model.QuorumSample
+}
+
+// startQuorumMonitor takes a first sample before returning, then samples at every interval until stopped.
+func startQuorumMonitor(t testing.TestingT, clients map[string]kubernetes.Interface, namespaces map[string]string,
+	interval time.Duration) *quorumMonitor {
+
+	monitor := &quorumMonitor{clients: clients, namespaces: namespaces,
+		stop: make(chan struct{}), done: make(chan struct{})}
+	monitor.sample(t)
+
+	go func() {
+		defer close(monitor.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-monitor.stop:
+				return
+			case <-ticker.C:
+				monitor.sample(t)
+			}
+		}
+	}()
+	return monitor
+}
+
+// Stop ends the background sampling, takes a last sample, and provides the number of samples taken and the
+// samples without quorum.
+func (m *quorumMonitor) Stop(t testing.TestingT) (int, []model.QuorumSample) {
+	m.halt()
+	m.sample(t)
+	return m.samples, m.losses
+}
+
+// halt ends the background sampling, once, e.g. when the test fails before the monitor is stopped.
+func (m *quorumMonitor) halt() {
+	m.halted.Do(func() {
+		close(m.stop)
+		<-m.done
+	})
+}
+
+// sample records the datacenters of every context without quorum. Errors are only logged, as the monitor runs
+// outside the test goroutine.
+func (m *quorumMonitor) sample(t testing.TestingT) {
+	var names []string
+	for name := range m.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		samples, err := sampleQuorum(m.clients[name], name, m.namespaces[name], time.Now())
+		if err != nil {
+			logger.Log(t, fmt.Sprintf("WARNING: unable to sample quorum for: %s, error: %s", name, err.Error()))
+			continue
+		}
+		for _, sample := range samples {
+			if !hasQuorum(sample) {
+				logger.Log(t, fmt.Sprintf("WARNING: datacenter: %s of: %s lost quorum, ready: %d of: %d",
+					sample.Datacenter, name, sample.Ready, sample.Size))
+				m.losses = append(m.losses, sample)
+			}
+		}
+	}
+	m.samples++
+}
+
+// sampleQuorum sums the desired and ready replicas of the Cassandra statefulsets of the namespace, per
+// datacenter, sorted by datacenter name.
+func sampleQuorum(client kubernetes.Interface, contextName string, namespace string,
+	sampledAt time.Time) ([]model.QuorumSample, error) {
+
+	statefulSets, err := client.AppsV1().StatefulSets(namespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: cassandraDatacenterLabel})
+	if err != nil {
+		return nil, err
+	}
+
+	var datacenters = map[string]*model.QuorumSample{}
+	var names []string
+	for _, statefulSet := range statefulSets.Items {
+		name := statefulSet.Labels[cassandraDatacenterLabel]
+		sample, found := datacenters[name]
+		if !found {
+			sample = &model.QuorumSample{Context: contextName, Datacenter: name, SampledAt: sampledAt}
+			datacenters[name] = sample
+			names = append(names, name)
+		}
+		if statefulSet.Spec.Replicas != nil {
+			sample.Size += int(*statefulSet.Spec.Replicas)
+		}
+		sample.Ready += int(statefulSet.Status.ReadyReplicas)
+	}
+
+	sort.Strings(names)
+	var samples []model.QuorumSample
+	for _, name := range names {
+		samples = append(samples, *datacenters[name])
+	}
+	return samples, nil
+}
+
+// hasQuorum expects a majority of the nodes of the datacenter to be ready.
+func hasQuorum(sample model.QuorumSample) bool {
+	return sample.Size == 0 || sample.Ready >= sample.Size/2+1
+}
+
+// validateUpgrade expects a version to upgrade to, a from version not conflicting with the pinned or matrix
+// k8ssandra-operator versions, a known order, and a context order listing every context once.
+func validateUpgrade(readinessConfig model.ReadinessConfig) []ValidationError {
+
+	var validationErrors []ValidationError
+	provisionConfig := readinessConfig.ProvisionConfig
+	upgrade := provisionConfig.Upgrade
+
+	if upgrade.FromVersion != "" && upgrade.ToVersion == "" {
+		validationErrors = append(validationErrors, ValidationError{Field: "upgrade.to_version",
+			Message: fmt.Sprintf("expecting a version to upgrade to from version: %s", upgrade.FromVersion)})
+	}
+	pinned := provisionConfig.Versions.K8ssandraOperator.Version
+	if upgrade.FromVersion != "" && pinned != "" && pinned != upgrade.FromVersion {
+		validationErrors = append(validationErrors, ValidationError{Field: "upgrade.from_version",
+			Message: fmt.Sprintf("from version: %s conflicts with the k8ssandra-operator version: %s",
+				upgrade.FromVersion, pinned)})
+	}
+	operatorVersions := readinessConfig.Matrix.OperatorVersions
+	if upgrade.FromVersion != "" && len(operatorVersions) > 0 {
+		validationErrors = append(validationErrors, ValidationError{Field: "upgrade.from_version",
+			Message: fmt.Sprintf("from version: %s conflicts with the matrix k8ssandra-operator versions: [%s]",
+				upgrade.FromVersion, strings.Join(operatorVersions, ", "))})
+	}
+
+	switch upgrade.Order {
+	case "", model.UpgradeControlPlaneFirst, model.UpgradeDataPlanesFirst:
+	default:
+		validationErrors = append(validationErrors, ValidationError{Field: "upgrade.order",
+			Message: fmt.Sprintf("unknown order: %s, expected one of: [%s, %s]", upgrade.Order,
+				model.UpgradeControlPlaneFirst, model.UpgradeDataPlanesFirst)})
+	}
+
+	if len(upgrade.ContextOrder) == 0 {
+		return validationErrors
+	}
+	var seen = map[string]bool{}
+	for _, name := range upgrade.ContextOrder {
+		if _, found := readinessConfig.Contexts[name]; !found || seen[name] {
+			validationErrors = append(validationErrors, ValidationError{Field: "upgrade.context_order",
+				Message: fmt.Sprintf("expecting each known context once, found: %s", name)})
+		}
+		seen[name] = true
+	}
+	if len(seen) != len(readinessConfig.Contexts) {
+		validationErrors = append(validationErrors, ValidationError{Field: "upgrade.context_order",
+			Message: fmt.Sprintf("expecting every context to be upgraded, found: %d of: %d",
+				len(seen), len(readinessConfig.Contexts))})
+	}
+	return validationErrors
+}
@@ -0,0 +1,193 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"encoding/json"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"path"
+	"testing"
+	"time"
+)
+
+func cassandraStatefulSet(name string, datacenter string, replicas int32, ready int32) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "bootz",
+			Labels: map[string]string{cassandraDatacenterLabel: datacenter}},
+		Spec:   appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status: appsv1.StatefulSetStatus{ReadyReplicas: ready},
+	}
+}
+
+func upgradeConfig(upgrade model.UpgradeConfig) model.ReadinessConfig {
+	config := model.ReadinessConfig{Contexts: validContexts()}
+	config.Contexts["east"] = model.ContextConfig{Name: "east", Namespace: "bootz", ClusterLabels: []string{"data-plane"},
+		ExistingCluster: &model.ExistingClusterConfig{ContextName: "kind-k8ssandra-1"}}
+	config.ProvisionConfig.Upgrade = upgrade
+	return config
+}
+
+func TestResolveUpgradeOrder(t *testing.T) {
+	require.Equal(t, []string{"central", "east", "kind"}, ResolveUpgradeOrder(upgradeConfig(model.UpgradeConfig{})))
+	require.Equal(t, []string{"east", "kind", "central"},
+		ResolveUpgradeOrder(upgradeConfig(model.UpgradeConfig{Order: model.UpgradeDataPlanesFirst})))
+	require.Equal(t, []string{"kind", "central", "east"}, ResolveUpgradeOrder(upgradeConfig(model.UpgradeConfig{
+		Order: model.UpgradeDataPlanesFirst, ContextOrder: []string{"kind", "central", "east"}})))
+}
+
+func TestValidateUpgrade(t *testing.T) {
+	require.Empty(t, Validate(upgradeConfig(model.UpgradeConfig{FromVersion: "1.0.0", ToVersion: "1.1.0",
+		Order: model.UpgradeDataPlanesFirst, ContextOrder: []string{"kind", "central", "east"}})))
+
+	config := upgradeConfig(model.UpgradeConfig{FromVersion: "1.0.0", Order: "random",
+		ContextOrder: []string{"kind", "kind", "west"}})
+	config.ProvisionConfig.Versions.K8ssandraOperator.Version = "0.9.0"
+	require.Equal(t, []string{
+		"/upgrade.to_version",
+		"/upgrade.from_version",
+		"/upgrade.order",
+		"/upgrade.context_order",
+		"/upgrade.context_order",
+		"/upgrade.context_order",
+	}, fields(Validate(config)))
+}
+
+func TestValidateUpgradeMatrix(t *testing.T) {
+	config := upgradeConfig(model.UpgradeConfig{FromVersion: "1.0.0", ToVersion: "1.1.0"})
+	config.Matrix.OperatorVersions = []string{"0.38.0", "1.0.0"}
+	require.Equal(t, []string{"/upgrade.from_version"}, fields(Validate(config)),
+		"expecting the from version not to override the matrix operator versions")
+
+	config.ProvisionConfig.Upgrade.FromVersion = ""
+	require.Empty(t, Validate(config))
+}
+
+func TestResolveVersionsUpgrade(t *testing.T) {
+	config := model.ProvisionConfig{Upgrade: model.UpgradeConfig{FromVersion: "1.0.0", ToVersion: "1.1.0"}}
+	config.K8cConfig.Version = "0.9.0"
+	require.Equal(t, "1.0.0", ResolveVersions(config).K8ssandraOperator.Version,
+		"expecting the from version to be installed")
+
+	cell := ApplyMatrixCell(model.ReadinessConfig{ProvisionConfig: config}, model.MatrixCell{OperatorVersion: "0.38.0"})
+	require.Equal(t, "0.38.0", ResolveVersions(cell.ProvisionConfig).K8ssandraOperator.Version,
+		"expecting a pinned version not to be overridden by the from version")
+}
+
+func TestSampleQuorum(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		cassandraStatefulSet("k8c-dc2-rack1-sts", "dc2", 1, 0),
+		cassandraStatefulSet("k8c-dc1-rack1-sts", "dc1", 1, 1),
+		cassandraStatefulSet("k8c-dc1-rack2-sts", "dc1", 1, 1),
+		cassandraStatefulSet("k8c-dc1-rack3-sts", "dc1", 1, 0),
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "bootz"}})
+	sampledAt := time.Now()
+
+	samples, err := sampleQuorum(client, "kind", "bootz", sampledAt)
+	require.NoError(t, err)
+	require.Equal(t, []model.QuorumSample{
+		{Context: "kind", Datacenter: "dc1", Size: 3, Ready: 2, SampledAt: sampledAt},
+		{Context: "kind", Datacenter: "dc2", Size: 1, Ready: 0, SampledAt: sampledAt},
+	}, samples)
+	require.True(t, hasQuorum(samples[0]))
+	require.False(t, hasQuorum(samples[1]))
+}
+
+func TestQuorumMonitor(t *testing.T) {
+	client := fake.NewSimpleClientset(cassandraStatefulSet("k8c-dc1-rack1-sts", "dc1", 3, 3))
+	monitor := startQuorumMonitor(t, map[string]kubernetes.Interface{"kind": client},
+		map[string]string{"kind": "bootz"}, time.Hour)
+
+	require.NoError(t, client.Tracker().Update(appsv1.SchemeGroupVersion.WithResource("statefulsets"),
+		cassandraStatefulSet("k8c-dc1-rack1-sts", "dc1", 3, 1), "bootz"))
+
+	samples, losses := monitor.Stop(t)
+	require.Equal(t, 2, samples, "expecting a first and a last sample")
+	require.Len(t, losses, 1)
+	require.Equal(t, "dc1", losses[0].Datacenter)
+}
+
+func TestQuorumMonitorHalt(t *testing.T) {
+	client := fake.NewSimpleClientset(cassandraStatefulSet("k8c-dc1-rack1-sts", "dc1", 3, 3))
+	monitor := startQuorumMonitor(t, map[string]kubernetes.Interface{"kind": client},
+		map[string]string{"kind": "bootz"}, time.Millisecond)
+
+	samples, _ := monitor.Stop(t)
+	monitor.halt()
+	require.Equal(t, samples, monitor.samples, "expecting no sample once the monitor is stopped")
+
+	halted := startQuorumMonitor(t, map[string]kubernetes.Interface{"kind": client},
+		map[string]string{"kind": "bootz"}, time.Millisecond)
+	halted.halt()
+	sampled := halted.samples
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(t, sampled, halted.samples, "expecting no sample once the monitor is halted")
+}
+
+func TestUpgradeOperatorsSimulated(t *testing.T) {
+	meta := model.ProvisionMeta{Enable: model.EnableConfig{Simulate: true}, ArtifactsRootDir: t.TempDir()}
+	config := upgradeConfig(model.UpgradeConfig{FromVersion: "1.0.0", ToVersion: "1.1.0",
+		Order: model.UpgradeDataPlanesFirst})
+
+	report := UpgradeOperators(t, meta, config)
+	require.Equal(t, "1.0.0", report.FromVersion)
+	require.Equal(t, []string{"east", "kind", "central"}, report.Contexts)
+	require.Zero(t, report.Samples)
+}
+
+func TestWriteUpgradeReport(t *testing.T) {
+	meta := model.ProvisionMeta{ArtifactsRootDir: t.TempDir()}
+	report := model.UpgradeReport{FromVersion: "1.0.0", ToVersion: "1.1.0", Contexts: []string{"central"}, Samples: 4}
+
+	reportPath, err := WriteUpgradeReport(meta, report)
+	require.NoError(t, err)
+	require.Equal(t, path.Join(meta.ArtifactsRootDir, defaultUpgradeReportFileName), reportPath)
+
+	content, err := ioutil.ReadFile(reportPath)
+	require.NoError(t, err)
+	var written model.UpgradeReport
+	require.NoError(t, json.Unmarshal(content, &written))
+	require.Equal(t, report.Contexts, written.Contexts)
+	require.Equal(t, 4, written.Samples)
+
+	_, err = WriteUpgradeReport(model.ProvisionMeta{}, report)
+	require.Error(t, err)
+}
+
+func TestBuildExecutionPlanUpgrade(t *testing.T) {
+	config := upgradeConfig(model.UpgradeConfig{ToVersion: "1.1.0", Order: model.UpgradeDataPlanesFirst})
+
+	plan, err := BuildExecutionPlan(model.ProvisionMeta{ProvisionId: "k8c-test"}, config,
+		[]model.Phase{model.PhaseUpgrade})
+	require.NoError(t, err)
+	require.Equal(t, "upgrade/write/artifact/"+path.Join(DefaultArtifactsRootDir("k8c-test"),
+		defaultUpgradeReportFileName), planActions(plan.Actions)[len(plan.Actions)-1])
+
+	central := plan.Contexts[0]
+	require.Equal(t, []string{
+		"upgrade/upgrade/helm-release/k8ssandra-operator",
+		"upgrade/verify rollout/deployment/k8ssandra-operator",
+	}, planActions(central.Actions))
+	require.Equal(t, "1.1.0", central.Actions[0].Version)
+	require.Equal(t, "3", central.Actions[0].Values["upgrade_order"])
+	require.Equal(t, "true", central.Actions[0].Values[defaultControlPlaneKey])
+}
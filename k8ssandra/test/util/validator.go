@@ -0,0 +1,194 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/cloud"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/strings/slices"
+	"sort"
+	"strings"
+)
+
+// ValidationError identifies a readiness configuration issue detected before any cloud activity.
+type ValidationError struct {
+	Context string `json:"context,omitempty"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Context == "" {
+		return fmt.Sprintf("%s: %s", e.Field, e.Message)
+	}
+	return fmt.Sprintf("context %s, %s: %s", e.Context, e.Field, e.Message)
+}
+
+// RequireValid logs every validation error and fails when the readiness configuration is not valid.
+func RequireValid(t testing.TestingT, readinessConfig model.ReadinessConfig) {
+	validationErrors := Validate(readinessConfig)
+	for _, validationError := range validationErrors {
+		logger.Log(t, fmt.Sprintf("INVALID configuration, %s", validationError.Error()))
+	}
+	require.Empty(t, validationErrors, "expecting a valid readiness configuration before any cloud activity")
+}
+
+// Validate statically checks the readiness configuration, no cloud or cluster calls are made.
+func Validate(readinessConfig model.ReadinessConfig) []ValidationError {
+
+	var validationErrors []ValidationError
+	if len(readinessConfig.Contexts) == 0 {
+		return append(validationErrors, ValidationError{Field: "contexts", Message: "at least one context is required"})
+	}
+
+	var controlPlanes []string
+	for _, name := range sortedContextNames(readinessConfig) {
+		ctx := readinessConfig.Contexts[name]
+		if IsControlPlane(ctx) {
+			controlPlanes = append(controlPlanes, name)
+		}
+		validationErrors = append(validationErrors, validateContext(name, ctx)...)
+	}
+
+	if len(controlPlanes) != 1 {
+		validationErrors = append(validationErrors, ValidationError{
+			Field: "cluster_labels",
+			Message: fmt.Sprintf("expecting exactly one context labeled %s, found %d: [%s]",
+				defaultControlPlaneLabel, len(controlPlanes), strings.Join(controlPlanes, ", ")),
+		})
+	}
+
+	validationErrors = append(validationErrors, validateMatrix(readinessConfig)...)
+	validationErrors = append(validationErrors, validateUpgrade(readinessConfig)...)
+	validationErrors = append(validationErrors, validateMedusa(readinessConfig)...)
+	validationErrors = append(validationErrors, validateServiceAccountToken(readinessConfig)...)
+	return append(validationErrors, validateCidrBlocks(readinessConfig)...)
+}
+
+func validateContext(name string, ctx model.ContextConfig) []ValidationError {
+
+	var validationErrors []ValidationError
+	for _, message := range validation.IsDNS1123Label(ctx.Namespace) {
+		validationErrors = append(validationErrors, ValidationError{Context: name, Field: "namespace",
+			Message: fmt.Sprintf("%s is not a valid DNS label, %s", ctx.Namespace, message)})
+	}
+
+	if IsExistingCluster(ctx) {
+		return validationErrors
+	}
+
+	provider, err := cloud.Lookup(ctx.CloudConfig.Type)
+	if err != nil {
+		return append(validationErrors, ValidationError{Context: name, Field: "cloud_config.type", Message: err.Error()})
+	}
+
+	cloudConfig := ctx.CloudConfig
+	var rackLocations []string
+	for _, prc := range cloudConfig.PoolRackConfigs {
+		rackLocations = append(rackLocations, prc.Location)
+		if !provider.IsLocationInRegion(prc.Location, cloudConfig.Region) {
+			validationErrors = append(validationErrors, ValidationError{Context: name,
+				Field:   "cloud_config.poolRackConfigs",
+				Message: fmt.Sprintf("rack %s location %s is not within region %s", prc.Name, prc.Location, cloudConfig.Region)})
+		}
+		if prc.Label != "" {
+			if _, _, err := splitRackLabel(prc.Label); err != nil {
+				validationErrors = append(validationErrors, ValidationError{Context: name,
+					Field: "cloud_config.poolRackConfigs", Message: fmt.Sprintf("rack %s %s", prc.Name, err)})
+			}
+		}
+	}
+
+	if zones := distinctLocations(rackLocations); provider.MaxRackLocations() > 0 &&
+		len(zones) > provider.MaxRackLocations() {
+		validationErrors = append(validationErrors, ValidationError{Context: name, Field: "cloud_config.poolRackConfigs",
+			Message: fmt.Sprintf("racks span %d locations: [%s], exceeding the provider limit of %d", len(zones),
+				strings.Join(zones, ", "), provider.MaxRackLocations())})
+	}
+
+	if ctx.NetworkConfig.MasterIpv4CidrBlock != "" && !provider.HasMasterCidrBlock() {
+		validationErrors = append(validationErrors, ValidationError{Context: name, Field: masterCidrField,
+			Message: fmt.Sprintf("%s is not used, the %s control plane network is managed by the provider",
+				ctx.NetworkConfig.MasterIpv4CidrBlock, cloudConfig.Type)})
+	}
+
+	for _, location := range cloudConfig.Locations {
+		if !slices.Contains(rackLocations, location) {
+			validationErrors = append(validationErrors, ValidationError{Context: name, Field: "cloud_config.locations",
+				Message: fmt.Sprintf("location %s is not a rack zone, expecting one of: [%s]",
+					location, strings.Join(rackLocations, ", "))})
+		}
+	}
+
+	clusterName := provider.ConstructCloudClusterName(name, cloudConfig)
+	if len(clusterName) > provider.MaxClusterNameLength() {
+		validationErrors = append(validationErrors, ValidationError{Context: name, Field: "name",
+			Message: fmt.Sprintf("cluster name %s has %d characters, exceeding the provider limit of %d",
+				clusterName, len(clusterName), provider.MaxClusterNameLength())})
+	}
+	return validationErrors
+}
+
+// distinctLocations provides the locations without duplicates, in their original order.
+func distinctLocations(locations []string) []string {
+	var distinct []string
+	for _, location := range locations {
+		if !slices.Contains(distinct, location) {
+			distinct = append(distinct, location)
+		}
+	}
+	return distinct
+}
+
+// splitRackLabel provides the key and value of a rack label, expected as a key=value node label.
+func splitRackLabel(label string) (string, string, error) {
+	key, value, found := strings.Cut(label, "=")
+	if !found || key == "" {
+		return "", "", fmt.Errorf("label: %s expected as key=value", label)
+	}
+	messages := append(validation.IsQualifiedName(key), validation.IsValidLabelValue(value)...)
+	if len(messages) > 0 {
+		return "", "", fmt.Errorf("label: %s is not a valid node label, %s", label, strings.Join(messages, ", "))
+	}
+	return key, value, nil
+}
+
+// validateCidrBlocks expects every configured block to parse and not overlap any other block across all contexts.
+func validateCidrBlocks(readinessConfig model.ReadinessConfig) []ValidationError {
+
+	_, validationErrors := CollectCidrBlocks(readinessConfig)
+	for _, overlap := range DetectCidrOverlaps(readinessConfig) {
+		validationErrors = append(validationErrors, ValidationError{Context: overlap.Second.Context,
+			Field: overlap.Second.Field, Message: fmt.Sprintf("%s overlaps %s %s of context %s", overlap.Second.Block,
+				overlap.First.Field, overlap.First.Block, overlap.First.Context)})
+	}
+	return validationErrors
+}
+
+func sortedContextNames(readinessConfig model.ReadinessConfig) []string {
+	var names []string
+	for name := range readinessConfig.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
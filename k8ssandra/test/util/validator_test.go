@@ -0,0 +1,183 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+)
+
+func validContexts() map[string]model.ContextConfig {
+	cloudConfig := model.CloudConfig{
+		Type:        "gcp",
+		Region:      "us-central1",
+		Environment: "dev",
+		Locations:   []string{"us-central1-a"},
+		PoolRackConfigs: []model.PoolRackConfig{
+			{Name: "rack1", Location: "us-central1-a"},
+			{Name: "rack2", Location: "us-central1-b"},
+		},
+	}
+	return map[string]model.ContextConfig{
+		"central": {Name: "central", Namespace: "bootz", ClusterLabels: []string{"control-plane"}, CloudConfig: cloudConfig,
+			NetworkConfig: model.NetworkConfig{SubnetCidrBlock: "10.1.0.0/16", MasterIpv4CidrBlock: "10.0.0.0/28"}},
+		"kind": {Name: "kind", Namespace: "bootz", ClusterLabels: []string{"data-plane"},
+			ExistingCluster: &model.ExistingClusterConfig{ContextName: "kind-k8ssandra-0"}},
+	}
+}
+
+func fields(validationErrors []ValidationError) []string {
+	var result []string
+	for _, validationError := range validationErrors {
+		result = append(result, validationError.Context+"/"+validationError.Field)
+	}
+	return result
+}
+
+func TestValidate(t *testing.T) {
+	require.Empty(t, Validate(model.ReadinessConfig{Contexts: validContexts()}))
+}
+
+func TestValidateScenarioFile(t *testing.T) {
+	t.Setenv("HOME", "/home/tester")
+	_, config, err := ParseReadinessConfig(filepath.Join("..", "testdata", "scenario_1", "readiness-config.yaml"))
+	require.NoError(t, err)
+	require.Empty(t, Validate(config))
+}
+
+func TestValidateNoContexts(t *testing.T) {
+	require.Equal(t, []string{"/contexts"}, fields(Validate(model.ReadinessConfig{})))
+}
+
+func TestValidateControlPlane(t *testing.T) {
+	contexts := validContexts()
+	kind := contexts["kind"]
+	kind.ClusterLabels = []string{"control-plane"}
+	contexts["kind"] = kind
+
+	require.Equal(t, []string{"/cluster_labels"}, fields(Validate(model.ReadinessConfig{Contexts: contexts})))
+}
+
+func TestValidateCloudConfig(t *testing.T) {
+	contexts := validContexts()
+	central := contexts["central"]
+	central.Namespace = "Bootz_NS"
+	central.CloudConfig.Locations = []string{"us-central1-f"}
+	central.CloudConfig.PoolRackConfigs = append(central.CloudConfig.PoolRackConfigs,
+		model.PoolRackConfig{Name: "rack3", Location: "us-east1-b"})
+	central.CloudConfig.Environment = "a-very-long-environment-name-for-gke"
+	contexts["central"] = central
+
+	require.Equal(t, []string{
+		"central/namespace",
+		"central/cloud_config.poolRackConfigs",
+		"central/cloud_config.locations",
+		"central/name",
+	}, fields(Validate(model.ReadinessConfig{Contexts: contexts})))
+}
+
+func TestValidateRackLabels(t *testing.T) {
+	contexts := validContexts()
+	central := contexts["central"]
+	central.CloudConfig.PoolRackConfigs = []model.PoolRackConfig{
+		{Name: "rack1", Label: "k8ssandra.io/rack=rack1", Location: "us-central1-a"},
+		{Name: "rack2", Label: "rack2", Location: "us-central1-b"},
+		{Name: "rack3", Label: "k8ssandra.io/rack=rack=3", Location: "us-central1-c"},
+	}
+	central.CloudConfig.Locations = nil
+	contexts["central"] = central
+
+	validationErrors := Validate(model.ReadinessConfig{Contexts: contexts})
+	require.Equal(t, []string{"central/cloud_config.poolRackConfigs", "central/cloud_config.poolRackConfigs"},
+		fields(validationErrors))
+	require.Equal(t, "rack rack2 label: rack2 expected as key=value", validationErrors[0].Message)
+	require.Contains(t, validationErrors[1].Message, "rack rack3 label: k8ssandra.io/rack=rack=3 is not a valid node label")
+}
+
+func TestValidateRackLabelsAzure(t *testing.T) {
+	contexts := validContexts()
+	central := contexts["central"]
+	central.CloudConfig = model.CloudConfig{Type: "azure", Region: "eastus", Environment: "dev",
+		PoolRackConfigs: []model.PoolRackConfig{
+			{Name: "rack1", Label: "k8ssandra.io/rack=rack1", Location: "1"},
+			{Name: "rack2", Location: "2"},
+			{Name: "rack3", Label: "=rack3", Location: "3"},
+		}}
+	central.NetworkConfig.MasterIpv4CidrBlock = ""
+	contexts["central"] = central
+
+	validationErrors := Validate(model.ReadinessConfig{Contexts: contexts})
+	require.Equal(t, []string{"central/cloud_config.poolRackConfigs"}, fields(validationErrors))
+	require.Equal(t, "rack rack3 label: =rack3 expected as key=value", validationErrors[0].Message)
+}
+
+func TestValidateRackLocationsAws(t *testing.T) {
+	contexts := validContexts()
+	central := contexts["central"]
+	central.CloudConfig = model.CloudConfig{Type: "aws", Region: "us-east-2", Environment: "dev",
+		PoolRackConfigs: []model.PoolRackConfig{
+			{Name: "rack1", Location: "us-east-2a"},
+			{Name: "rack2", Location: "us-east-2b"},
+			{Name: "rack3", Location: "us-east-2c"},
+			{Name: "rack4", Location: "us-east-2a"},
+		}}
+	central.NetworkConfig.MasterIpv4CidrBlock = ""
+	contexts["central"] = central
+	require.Empty(t, Validate(model.ReadinessConfig{Contexts: contexts}))
+
+	central.CloudConfig.PoolRackConfigs[3].Location = "us-east-2d"
+	validationErrors := Validate(model.ReadinessConfig{Contexts: contexts})
+	require.Equal(t, []string{"central/cloud_config.poolRackConfigs"}, fields(validationErrors))
+	require.Equal(t, "racks span 4 locations: [us-east-2a, us-east-2b, us-east-2c, us-east-2d], exceeding the "+
+		"provider limit of 3", validationErrors[0].Message)
+}
+
+func TestValidateMasterCidrBlockAzure(t *testing.T) {
+	contexts := validContexts()
+	central := contexts["central"]
+	central.CloudConfig = model.CloudConfig{Type: "azure", Region: "eastus", Environment: "dev",
+		PoolRackConfigs: []model.PoolRackConfig{{Name: "rack1", Location: "1"}}}
+	contexts["central"] = central
+
+	validationErrors := Validate(model.ReadinessConfig{Contexts: contexts})
+	require.Equal(t, []string{"central/network_config.master_ipv_4_cidr_block"}, fields(validationErrors))
+	require.Equal(t, "10.0.0.0/28 is not used, the azure control plane network is managed by the provider",
+		validationErrors[0].Message)
+}
+
+func TestValidateUnknownCloudType(t *testing.T) {
+	contexts := validContexts()
+	central := contexts["central"]
+	central.CloudConfig.Type = "on-prem"
+	contexts["central"] = central
+
+	require.Equal(t, []string{"central/cloud_config.type"}, fields(Validate(model.ReadinessConfig{Contexts: contexts})))
+}
+
+func TestValidateCidrBlocks(t *testing.T) {
+	contexts := validContexts()
+	kind := contexts["kind"]
+	kind.NetworkConfig = model.NetworkConfig{SubnetCidrBlock: "10.1.32.0/20", SecondaryCidrBlock: "10.300.0.0/20"}
+	contexts["kind"] = kind
+
+	require.Equal(t, []string{
+		"kind/network_config.secondary_cidr_block",
+		"kind/network_config.subnetCidrBlock",
+	}, fields(Validate(model.ReadinessConfig{Contexts: contexts})))
+}
@@ -0,0 +1,109 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"fmt"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+const defaultDiagnosticsFolder = "diagnostics"
+
+// VerifyInstallation requires the k8ssandra-operator deployment to be rolled out in every context, and every
+// post-install check of the K8ssandraCluster to pass, recording the check results in the run ledger.
+func VerifyInstallation(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) {
+
+	identity := FetchEnv(t, meta.AdminIdentity)
+	timeoutSecs := valueOrDefault(readinessConfig.ProvisionConfig.DefaultTimeoutSecs, defaultTimeoutSecs)
+
+	for _, name := range sortedContextNames(readinessConfig) {
+		ctx := readinessConfig.Contexts[name]
+		if meta.Enable.Simulate {
+			logger.Log(t, fmt.Sprintf("SIMULATE verification of the k8ssandra-operator rollout for: %s", name))
+			continue
+		}
+
+		verifyOperatorRollout(t, ConnectContext(t, meta, identity, name, ctx), name, ctx.Namespace, timeoutSecs)
+	}
+
+	results := ValidateK8ssandraCluster(t, meta, readinessConfig)
+	RecordCheckResults(t, meta, results)
+	RequireChecksPassed(t, results)
+}
+
+// verifyOperatorRollout waits for the k8ssandra-operator deployment of the context to be rolled out.
+func verifyOperatorRollout(t testing.TestingT, kubeConfig *k8s.KubectlOptions, name string, namespace string,
+	timeoutSecs int) {
+
+	out, err := executor.RunKubectl(t, kubeConfig, "rollout", "status", "deployment",
+		defaultK8ssandraOperatorReleaseName, "-n", namespace, fmt.Sprintf("--timeout=%ds", timeoutSecs))
+	require.NoError(t, err, fmt.Sprintf("expecting k8ssandra-operator to be rolled out for context: %s", name))
+	logger.Log(t, fmt.Sprintf("verified k8ssandra-operator rollout for: %s, output: %s", name, out))
+}
+
+// CollectDiagnostics writes the pods, events and K8ssandraCluster resources of every context to the
+// diagnostics folder of the artifacts root.
+func CollectDiagnostics(t testing.TestingT, meta model.ProvisionMeta, readinessConfig model.ReadinessConfig) {
+
+	identity := FetchEnv(t, meta.AdminIdentity)
+	diagnosticsDir := path.Join(meta.ArtifactsRootDir, defaultDiagnosticsFolder)
+
+	for _, name := range sortedContextNames(readinessConfig) {
+		ctx := readinessConfig.Contexts[name]
+		if meta.Enable.Simulate {
+			logger.Log(t, fmt.Sprintf("SIMULATE collection of diagnostics for: %s into: %s", name, diagnosticsDir))
+			continue
+		}
+
+		kubeConfig := ConnectContext(t, meta, identity, name, ctx)
+
+		var report strings.Builder
+		for _, args := range diagnosticCommands(ctx.Namespace) {
+			out, err := executor.RunKubectl(t, kubeConfig, args...)
+			report.WriteString(fmt.Sprintf("$ kubectl %s\n%s\n", strings.Join(args, " "), out))
+			if err != nil {
+				report.WriteString(fmt.Sprintf("error: %s\n", err.Error()))
+			}
+			report.WriteString("\n")
+		}
+
+		mkdirErr := os.MkdirAll(diagnosticsDir, defaultTempFilePerm)
+		require.NoError(t, mkdirErr, fmt.Sprintf("failed to init folder: %s", diagnosticsDir))
+
+		reportPath := path.Join(diagnosticsDir, name+".log")
+		writeErr := ioutil.WriteFile(reportPath, []byte(report.String()), defaultTempFilePerm)
+		require.NoError(t, writeErr, fmt.Sprintf("unable to write diagnostics: %s", reportPath))
+		logger.Log(t, fmt.Sprintf("diagnostics for: %s written to: %s", name, reportPath))
+	}
+}
+
+func diagnosticCommands(namespace string) [][]string {
+	return [][]string{
+		{"get", "pods", "-o", "wide", "-n", namespace},
+		{"get", "events", "--sort-by=.lastTimestamp", "-n", namespace},
+		{"get", "k8ssandraclusters", "-o", "yaml", "-n", namespace},
+	}
+}
@@ -0,0 +1,148 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"fmt"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"path"
+	"strings"
+)
+
+const (
+	defaultCertManagerVersion     = "v1.5.3"
+	defaultCertManagerManifest    = "https://github.com/jetstack/cert-manager/releases/download/%s/cert-manager.yaml"
+	defaultCertManagerReleaseName = "cert-manager"
+	defaultCertManagerNamespace   = "cert-manager"
+)
+
+// ResolveVersions provides the versions of the components installed, completing the configured ones with
+// the defaults. The HelmConfig chart path and K8cConfig version apply to the k8ssandra-operator when the
+// versions do not provide them, and the upgrade from version is the k8ssandra-operator version installed when
+// no version is pinned, e.g. by a matrix cell. MinIO is only resolved for the Medusa stand-in.
+func ResolveVersions(config model.ProvisionConfig) model.VersionsConfig {
+	versions := config.Versions
+
+	operator := &versions.K8ssandraOperator
+	operator.Chart = stringOrDefault(operator.Chart, stringOrDefault(config.HelmConfig.ChartPath,
+		defaultK8ssandraOperatorChart))
+	operator.Version = stringOrDefault(operator.Version, stringOrDefault(config.Upgrade.FromVersion,
+		config.K8cConfig.Version))
+	completeRepository(operator, defaultK8ssandraRepositoryName, defaultK8ssandraRepositoryURL)
+
+	certManager := &versions.CertManager
+	certManager.Version = stringOrDefault(certManager.Version, defaultCertManagerVersion)
+	if certManager.Chart == "" && certManager.Manifest == "" {
+		certManager.Manifest = fmt.Sprintf(defaultCertManagerManifest, certManager.Version)
+	}
+	completeRepository(certManager, defaultCertManagerRepositoryName, defaultCertManagerRepositoryURL)
+
+	traefik := &versions.Traefik
+	traefik.Chart = stringOrDefault(traefik.Chart, defaultTraefikChartName)
+	traefik.Version = stringOrDefault(traefik.Version, DefaultTraefikVersion)
+	completeRepository(traefik, defaultTraefikRepositoryName, defaultTraefikRepositoryURL)
+
+	if config.K8cConfig.MedusaStandIn == nil {
+		versions.MinIO = model.ComponentVersion{}
+		return versions
+	}
+	minio := &versions.MinIO
+	minio.Chart = stringOrDefault(minio.Chart, defaultMinIOChart)
+	minio.Version = stringOrDefault(minio.Version, defaultMinIOVersion)
+	completeRepository(minio, defaultMinIORepositoryName, defaultMinIORepositoryURL)
+
+	return versions
+}
+
+// completeRepository names the repository after the chart reference, and provides the default URL of the
+// default repository. A repository without URL is expected to be already known to helm.
+func completeRepository(component *model.ComponentVersion, defaultName string, defaultURL string) {
+	if component.RepositoryName == "" {
+		component.RepositoryName = defaultName
+		if index := strings.Index(component.Chart, "/"); index > 0 {
+			component.RepositoryName = component.Chart[:index]
+		}
+	}
+	if component.RepositoryURL == "" && component.RepositoryName == defaultName {
+		component.RepositoryURL = defaultURL
+	}
+}
+
+// helmRepositories provides the name and URL of the repositories of the components, once per name.
+func helmRepositories(versions model.VersionsConfig) [][]string {
+	var repositories [][]string
+	var added = map[string]bool{}
+	for _, component := range []model.ComponentVersion{versions.CertManager, versions.K8ssandraOperator,
+		versions.Traefik, versions.MinIO} {
+		if component.RepositoryURL == "" || added[component.RepositoryName] {
+			continue
+		}
+		added[component.RepositoryName] = true
+		repositories = append(repositories, []string{component.RepositoryName, component.RepositoryURL})
+	}
+	return repositories
+}
+
+// componentRelease provides the release of a component installed by helm.
+func componentRelease(name string, namespace string, component model.ComponentVersion) helmRelease {
+	return helmRelease{
+		Name:       name,
+		Chart:      component.Chart,
+		Version:    component.Version,
+		Namespace:  namespace,
+		ValuesFile: configFilePath(component.ValuesFile),
+		Values:     component.Values,
+	}
+}
+
+// traefikRelease provides the Traefik release of a context, whose network configuration takes precedence
+// over the pinned version and values file.
+func traefikRelease(component model.ComponentVersion, networkConfig model.NetworkConfig) helmRelease {
+	release := componentRelease(defaultTraefikRepositoryName, "", component)
+	if networkConfig.TraefikVersion != "" {
+		release.Version = networkConfig.TraefikVersion
+	}
+	if networkConfig.TraefikValuesFile != "" {
+		release.ValuesFile = configFilePath(networkConfig.TraefikValuesFile)
+	}
+	return release
+}
+
+// certManagerRelease provides the cert-manager release of a chart, installing its CRDs unless set otherwise.
+func certManagerRelease(component model.ComponentVersion) helmRelease {
+	release := componentRelease(defaultCertManagerReleaseName, defaultCertManagerNamespace, component)
+	release.Values = map[string]string{"installCRDs": "true"}
+	for key, value := range component.Values {
+		release.Values[key] = value
+	}
+	return release
+}
+
+// configFilePath resolves a relative file from the config folder.
+func configFilePath(file string) string {
+	if file == "" || path.IsAbs(file) {
+		return file
+	}
+	return path.Join(defaultConfigFolder, file)
+}
+
+func stringOrDefault(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,114 @@
+package util
+
+/**
+Copyright 2022 DataStax, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+ https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+**/
+
+import (
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/model"
+	"github.com/k8ssandra/cloud-readiness/k8ssandra/test/util/executor"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestResolveVersionsDefaults(t *testing.T) {
+	versions := ResolveVersions(model.ProvisionConfig{})
+
+	require.Equal(t, model.ComponentVersion{RepositoryName: defaultK8ssandraRepositoryName,
+		RepositoryURL: defaultK8ssandraRepositoryURL, Chart: defaultK8ssandraOperatorChart}, versions.K8ssandraOperator)
+	require.Equal(t, "https://github.com/jetstack/cert-manager/releases/download/v1.5.3/cert-manager.yaml",
+		versions.CertManager.Manifest)
+	require.Equal(t, defaultCertManagerRepositoryURL, versions.CertManager.RepositoryURL)
+	require.Equal(t, DefaultTraefikVersion, versions.Traefik.Version)
+	require.Equal(t, defaultTraefikChartName, versions.Traefik.Chart)
+
+	require.Equal(t, [][]string{
+		{defaultCertManagerRepositoryName, defaultCertManagerRepositoryURL},
+		{defaultK8ssandraRepositoryName, defaultK8ssandraRepositoryURL},
+		{defaultTraefikRepositoryName, defaultTraefikRepositoryURL},
+	}, helmRepositories(versions))
+}
+
+func TestResolveVersionsPinned(t *testing.T) {
+	config := model.ProvisionConfig{
+		HelmConfig: model.HelmConfig{ChartPath: "k8ssandra/k8ssandra-operator"},
+		K8cConfig:  model.K8cConfig{Version: "0.38.0"},
+	}
+	require.Equal(t, "0.38.0", ResolveVersions(config).K8ssandraOperator.Version)
+
+	config.Versions = model.VersionsConfig{
+		K8ssandraOperator: model.ComponentVersion{RepositoryName: "k8ssandra-next",
+			RepositoryURL: "https://helm.k8ssandra.io/next", Chart: "k8ssandra-next/k8ssandra-operator",
+			Version: "1.0.0-rc1", Values: map[string]string{"global.clusterScoped": "true"}},
+		CertManager: model.ComponentVersion{Version: "v1.7.1"},
+		Traefik:     model.ComponentVersion{Chart: "traefik/traefik", Version: "v10.9.1"},
+	}
+	versions := ResolveVersions(config)
+
+	require.Equal(t, "1.0.0-rc1", versions.K8ssandraOperator.Version)
+	require.Equal(t, "https://github.com/jetstack/cert-manager/releases/download/v1.7.1/cert-manager.yaml",
+		versions.CertManager.Manifest)
+	require.Equal(t, [][]string{
+		{defaultCertManagerRepositoryName, defaultCertManagerRepositoryURL},
+		{"k8ssandra-next", "https://helm.k8ssandra.io/next"},
+		{defaultTraefikRepositoryName, defaultTraefikRepositoryURL},
+	}, helmRepositories(versions))
+
+	release := componentRelease(defaultK8ssandraOperatorReleaseName, "bootz", versions.K8ssandraOperator)
+	require.Equal(t, helmRelease{Name: defaultK8ssandraOperatorReleaseName, Chart: "k8ssandra-next/k8ssandra-operator",
+		Version: "1.0.0-rc1", Namespace: "bootz", Values: map[string]string{"global.clusterScoped": "true"}}, release)
+}
+
+func TestTraefikRelease(t *testing.T) {
+	component := model.ComponentVersion{Chart: defaultTraefikChartName, Version: "v10.9.1",
+		ValuesFile: "k8c-traefik.yaml"}
+
+	release := traefikRelease(component, model.NetworkConfig{})
+	require.Equal(t, "v10.9.1", release.Version)
+	require.Equal(t, "../config/k8c-traefik.yaml", release.ValuesFile)
+
+	release = traefikRelease(component, model.NetworkConfig{TraefikVersion: "v10.3.2",
+		TraefikValuesFile: "k8c-traefik-bootz000.yaml"})
+	require.Equal(t, "v10.3.2", release.Version)
+	require.Equal(t, "../config/k8c-traefik-bootz000.yaml", release.ValuesFile)
+}
+
+func TestInstallCertManagerChart(t *testing.T) {
+	fake := executor.NewFake().Respond(executor.Helm, []string{"list"}, "[]", nil)
+	component := ResolveVersions(model.ProvisionConfig{Versions: model.VersionsConfig{
+		CertManager: model.ComponentVersion{Chart: "jetstack/cert-manager", Version: "v1.7.1"}}}).CertManager
+	require.Empty(t, component.Manifest)
+
+	installCertManager(executor.With(t, fake), k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz"), component, false)
+
+	require.Empty(t, fake.CommandsOf(executor.Kubectl, "apply"))
+	installs := fake.CommandsOf(executor.Helm, "install")
+	require.Len(t, installs, 1)
+	require.Equal(t, []string{"install", defaultCertManagerReleaseName, "jetstack/cert-manager",
+		"-n", defaultCertManagerNamespace, "--create-namespace", "--version", "v1.7.1", "--set", "installCRDs=true"},
+		installs[0].Args)
+}
+
+func TestInstallCertManagerManifest(t *testing.T) {
+	fake := executor.NewFake()
+	component := ResolveVersions(model.ProvisionConfig{}).CertManager
+
+	installCertManager(executor.With(t, fake), k8s.NewKubectlOptions("kind-k8ssandra-0", "", "bootz"), component, false)
+
+	applied := fake.CommandsOf(executor.Kubectl, "apply")
+	require.Len(t, applied, 1)
+	require.Equal(t, []string{"apply", "-f", component.Manifest}, applied[0].Args)
+}